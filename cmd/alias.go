@@ -3,8 +3,11 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"sort"
+	"strings"
+	"unicode"
 
 	"github.com/isiidaisuke0926/sleepship/internal/config"
 	"github.com/spf13/cobra"
@@ -17,22 +20,43 @@ var aliasCmd = &cobra.Command{
 
 Aliases allow you to define shortcuts for frequently used commands.
 
+Aliases are merged from ~/.sleepship.toml and every .sleepship.toml from the
+repository root (or the filesystem root outside a repository) down to the
+current directory. A definition closer to the current directory shadows
+definitions of the same alias in other files. With --config or
+SLEEPSHIP_CONFIG, the selected file is used instead of the project files.
+
 Example .sleepship.toml:
   [aliases]
   dev = "sync tasks-dev.txt"
   test = "sync tasks-test.txt --max-retries=5"
   prod = "sync tasks-prod.txt --max-retries=10"
+  run = "sync ${1:-tasks.txt} --max-retries=${2:-3}"
+  here = "sync \"$1\" --dir=\"$PWD\""
+  nightly = ["!test -s nightly.txt", "sync --wait nightly.txt", "history --last 1"]
 
 Then you can use:
   sleepship dev
   sleepship test
-  sleepship prod`,
+  sleepship prod
+  sleepship run "my tasks.txt" 5
+
+Alias commands are split with shell quoting rules. Arguments given after the
+alias are appended, or substituted for placeholders:
+  $1 … $9, ${10}    Argument at that position (required)
+  ${1:-default}     Argument, or default if it is missing or empty
+  $@                All arguments
+  ${NAME:-default}  Environment variable, or default if it is unset or empty
+
+An alias defined as a list runs its steps in order and stops at the first
+step that fails. Arguments are only passed to steps through placeholders.
+Steps starting with ! run in the shell, with the arguments as $1, $2, ...`,
 }
 
 var aliasListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all aliases",
-	Long:  "List all command aliases defined in .sleepship.toml",
+	Long:  "List all command aliases in effect, with the config file that defines each and the definitions it shadows",
 	RunE:  runAliasList,
 }
 
@@ -44,22 +68,75 @@ var aliasGetCmd = &cobra.Command{
 	RunE:  runAliasGet,
 }
 
+var aliasAddCmd = &cobra.Command{
+	Use:   "add <name> <command> [<command>...]",
+	Short: "Add an alias",
+	Long: `Add an alias to the project's .sleepship.toml, or to ~/.sleepship.toml
+with --global. The project file is the nearest .sleepship.toml from the
+current directory up to the repository root, or the file selected with
+--config. If there is none, it is created in the current directory.
+
+Given more than one command, the alias runs them as steps in order and
+stops at the first step that fails. Steps starting with ! run in the shell.
+
+Only the alias's line is changed; comments and other sections of the file
+are kept. Aliases that would refer to themselves are rejected.
+
+Examples:
+  sleepship alias add dev "sync tasks-dev.txt"
+  sleepship alias add run 'sync ${1:-tasks.txt} --max-retries=${2:-3}'
+  sleepship alias add dev "sync tasks-dev.txt --keep-going" --force
+  sleepship alias add nightly "sync nightly.txt --max-cost=10" --global
+  sleepship alias add nightly "sync --wait nightly.txt" "history --last 1"`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runAliasAdd,
+}
+
+var aliasRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove an alias",
+	Long:  "Remove an alias from the project's .sleepship.toml, or from ~/.sleepship.toml with --global",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runAliasRemove,
+}
+
+var aliasRenameCmd = &cobra.Command{
+	Use:   "rename <old> <new>",
+	Short: "Rename an alias",
+	Long:  "Rename an alias in the project's .sleepship.toml, or in ~/.sleepship.toml with --global",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runAliasRename,
+}
+
+var (
+	aliasGlobal bool // Edit ~/.sleepship.toml instead of the project config file
+	aliasForce  bool // Replace an existing alias
+)
+
 func init() {
 	rootCmd.AddCommand(aliasCmd)
 	aliasCmd.AddCommand(aliasListCmd)
 	aliasCmd.AddCommand(aliasGetCmd)
+	aliasCmd.AddCommand(aliasAddCmd)
+	aliasCmd.AddCommand(aliasRemoveCmd)
+	aliasCmd.AddCommand(aliasRenameCmd)
+
+	for _, cmd := range []*cobra.Command{aliasAddCmd, aliasRemoveCmd, aliasRenameCmd} {
+		cmd.Flags().BoolVar(&aliasGlobal, "global", false, "Edit ~/.sleepship.toml instead of the project config file")
+	}
+	aliasAddCmd.Flags().BoolVarP(&aliasForce, "force", "f", false, "Replace the alias if it already exists")
 }
 
 func runAliasList(_ *cobra.Command, _ []string) error {
-	aliases, err := config.LoadAliases()
+	sources, err := config.LoadAliasSources()
 	if err != nil {
 		return fmt.Errorf("failed to load aliases: %w", err)
 	}
+	aliases := config.MergeAliases(sources)
 
 	if len(aliases) == 0 {
 		// Check if config file exists
-		configPath := findConfigPath()
-		if configPath == "" {
+		if len(sources) == 0 {
 			fmt.Println("No .sleepship.toml file found.")
 			fmt.Println("\nCreate a .sleepship.toml file in your project directory or home directory with:")
 			fmt.Println("\n[aliases]")
@@ -89,20 +166,30 @@ func runAliasList(_ *cobra.Command, _ []string) error {
 		}
 	}
 
-	// Display aliases
+	// Display aliases with the file that defines them
+	definitions := config.AliasDefinitions(sources)
 	for _, name := range names {
+		source := displayPath(definitions[name][0].Source)
+		command := aliases[name].String()
 		resolved, err := config.ResolveAlias(name, aliases)
 		if err != nil {
-			fmt.Printf("  %-*s -> %s (error: %v)\n", maxLen, name, aliases[name], err)
-		} else if resolved != aliases[name] {
+			fmt.Printf("  %-*s -> %s (error: %v) [%s]\n", maxLen, name, command, err, source)
+		} else if resolved != command {
 			// Show both original and resolved if different
-			fmt.Printf("  %-*s -> %s (resolves to: %s)\n", maxLen, name, aliases[name], resolved)
+			fmt.Printf("  %-*s -> %s (resolves to: %s) [%s]\n", maxLen, name, command, resolved, source)
 		} else {
-			fmt.Printf("  %-*s -> %s\n", maxLen, name, aliases[name])
+			fmt.Printf("  %-*s -> %s [%s]\n", maxLen, name, command, source)
+		}
+
+		for _, shadowed := range definitions[name][1:] {
+			fmt.Printf("  %-*s    shadows: %s [%s]\n", maxLen, "", shadowed.Alias, displayPath(shadowed.Source))
 		}
 	}
 
-	fmt.Printf("\nConfig file: %s\n", findConfigPath())
+	fmt.Printf("\nConfig files (lowest priority first):\n")
+	for _, source := range sources {
+		fmt.Printf("  %s\n", displayPath(source.Path))
+	}
 
 	return nil
 }
@@ -110,48 +197,263 @@ func runAliasList(_ *cobra.Command, _ []string) error {
 func runAliasGet(_ *cobra.Command, args []string) error {
 	aliasName := args[0]
 
-	aliases, err := config.LoadAliases()
+	sources, err := config.LoadAliasSources()
 	if err != nil {
 		return fmt.Errorf("failed to load aliases: %w", err)
 	}
+	aliases := config.MergeAliases(sources)
 
-	command, exists := aliases[aliasName]
+	alias, exists := aliases[aliasName]
 	if !exists {
 		return fmt.Errorf("alias not found: %s", aliasName)
 	}
+	definitions := config.AliasDefinitions(sources)[aliasName]
 
 	fmt.Printf("Alias: %s\n", aliasName)
-	fmt.Printf("Command: %s\n", command)
+	if alias.IsMultiStep() {
+		fmt.Printf("Steps:\n")
+		for i, step := range alias.Steps {
+			fmt.Printf("  %d. %s\n", i+1, step)
+		}
+	} else {
+		fmt.Printf("Command: %s\n", alias.Command)
+	}
+	fmt.Printf("Source: %s\n", displayPath(definitions[0].Source))
 
 	// Show resolved command if different
 	resolved, err := config.ResolveAlias(aliasName, aliases)
 	if err != nil {
 		fmt.Printf("Error resolving: %v\n", err)
-	} else if resolved != command {
+	} else if resolved != alias.String() {
 		fmt.Printf("Resolves to: %s\n", resolved)
 	}
 
+	for _, shadowed := range definitions[1:] {
+		fmt.Printf("Shadows: %s [%s]\n", shadowed.Alias, displayPath(shadowed.Source))
+	}
+
+	return nil
+}
+
+func runAliasAdd(_ *cobra.Command, args []string) error {
+	name, commands := args[0], args[1:]
+	if err := validateAliasName(name); err != nil {
+		return err
+	}
+	for _, command := range commands {
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("alias command must not be empty")
+		}
+	}
+
+	// More than one command makes a multi-step alias
+	alias := config.Alias{Command: commands[0]}
+	if len(commands) > 1 {
+		alias = config.Alias{Steps: commands}
+	}
+
+	var replaced bool
+	path, err := editAliasFile(func(f *config.AliasFile, aliases map[string]config.Alias) error {
+		if _, exists := aliases[name]; exists {
+			if !aliasForce {
+				return fmt.Errorf("alias already exists: %s (use --force to replace it)", name)
+			}
+			replaced = true
+		}
+		return f.Set(name, alias)
+	})
+	if err != nil {
+		return err
+	}
+
+	action := "Added"
+	if replaced {
+		action = "Updated"
+	}
+	fmt.Printf("✅ %s alias %s -> %s\n", action, name, alias)
+	fmt.Printf("📝 Config file: %s\n", path)
+	return nil
+}
+
+func runAliasRemove(_ *cobra.Command, args []string) error {
+	name := args[0]
+	path, err := editAliasFile(func(f *config.AliasFile, _ map[string]config.Alias) error {
+		return f.Remove(name)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🗑️  Removed alias %s\n", name)
+	fmt.Printf("📝 Config file: %s\n", path)
+	return nil
+}
+
+func runAliasRename(_ *cobra.Command, args []string) error {
+	oldName, newName := args[0], args[1]
+	if err := validateAliasName(newName); err != nil {
+		return err
+	}
+
+	path, err := editAliasFile(func(f *config.AliasFile, _ map[string]config.Alias) error {
+		return f.Rename(oldName, newName)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Renamed alias %s -> %s\n", oldName, newName)
+	fmt.Printf("📝 Config file: %s\n", path)
 	return nil
 }
 
-func findConfigPath() string {
-	// Check current directory
+// editAliasFile applies edit to the config file selected by --global and
+// saves it unless the edited aliases refer to themselves. It returns the
+// path of the file.
+func editAliasFile(edit func(f *config.AliasFile, aliases map[string]config.Alias) error) (string, error) {
+	path, err := aliasFilePath()
+	if err != nil {
+		return "", err
+	}
+
+	f, err := config.OpenAliasFile(path)
+	if err != nil {
+		return "", err
+	}
+	aliases, err := f.Aliases()
+	if err != nil {
+		return "", err
+	}
+	if err := edit(f, aliases); err != nil {
+		return "", err
+	}
+
+	edited, err := f.Aliases()
+	if err != nil {
+		return "", fmt.Errorf("edit would produce an invalid config file: %w", err)
+	}
+
+	// Check the aliases that will be in effect, with the edited file in place
+	// of the saved one
+	sources, err := config.LoadAliasSources()
+	if err != nil {
+		return "", err
+	}
+	found := false
+	for i := range sources {
+		if sources[i].Path == path {
+			sources[i].Aliases = edited
+			found = true
+		}
+	}
+	if !found {
+		source := config.AliasSource{Path: path, Aliases: edited}
+		if aliasGlobal {
+			sources = append([]config.AliasSource{source}, sources...)
+		} else {
+			sources = append(sources, source)
+		}
+	}
+	if err := config.CheckAliases(config.MergeAliases(sources)); err != nil {
+		return "", fmt.Errorf("alias not saved: %w", err)
+	}
+
+	return path, f.Save()
+}
+
+// aliasFilePath returns the config file that alias edits apply to.
+func aliasFilePath() (string, error) {
+	if aliasGlobal {
+		return config.GlobalConfigPath()
+	}
+	return projectConfigPath()
+}
+
+// projectConfigPath returns the project config file that edits apply to.
+func projectConfigPath() (string, error) {
+	if explicit := config.ExplicitConfigPath(); explicit != "" {
+		return explicit, nil
+	}
 	cwd, err := os.Getwd()
-	if err == nil {
-		configPath := filepath.Join(cwd, ".sleepship.toml")
-		if _, err := os.Stat(configPath); err == nil {
-			return configPath
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	// Edit the project config found from a subdirectory instead of creating
+	// a new one next to it
+	if path := config.FindProjectConfigPath(cwd); path != "" {
+		if globalPath, err := config.GlobalConfigPath(); err != nil || path != globalPath {
+			return path, nil
 		}
 	}
+	return filepath.Join(cwd, config.ConfigFileName), nil
+}
 
-	// Check home directory
-	homeDir, err := os.UserHomeDir()
-	if err == nil {
-		configPath := filepath.Join(homeDir, ".sleepship.toml")
-		if _, err := os.Stat(configPath); err == nil {
-			return configPath
+// validateAliasName returns an error if name cannot be used as an alias.
+// Aliases are resolved before commands, so built-in command names are not
+// allowed.
+func validateAliasName(name string) error {
+	if name == "" || strings.HasPrefix(name, "-") || strings.HasPrefix(name, config.ShellStepPrefix) || strings.ContainsFunc(name, unicode.IsSpace) {
+		return fmt.Errorf("invalid alias name %q", name)
+	}
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return fmt.Errorf("alias name %q is a built-in command", name)
 		}
 	}
+	if name == "help" {
+		return fmt.Errorf("alias name %q is a built-in command", name)
+	}
+	return nil
+}
+
+// displayPath shortens a path in the home directory to ~/...
+func displayPath(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(home, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Join("~", rel)
+	}
+	return path
+}
 
-	return ""
+// runAliasSteps runs the steps of a multi-step alias in order and stops at
+// the first step that fails. Each step runs as a separate sleepship or shell
+// process, so that steps start from a clean state.
+func runAliasSteps(name string, alias config.Alias, args []string, aliases map[string]config.Alias) error {
+	if _, err := config.ResolveAlias(name, aliases); err != nil {
+		return fmt.Errorf("error resolving alias '%s': %w", name, err)
+	}
+	steps, err := config.ExpandAliasSteps(alias, args)
+	if err != nil {
+		return fmt.Errorf("error expanding alias '%s': %w", name, err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	for i, step := range steps {
+		var cmd *exec.Cmd
+		var label string
+		if step.Shell != "" {
+			// The alias name and arguments become $0 and $1... of the script
+			cmd = exec.Command("bash", append([]string{"-c", step.Shell, name}, args...)...)
+			label = config.ShellStepPrefix + step.Shell
+		} else {
+			cmd = exec.Command(executable, step.Args...)
+			label = "sleepship " + strings.Join(step.Args, " ")
+		}
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		fmt.Printf("▶️  [%d/%d] %s\n", i+1, len(steps), label)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("step %d/%d of alias %s failed: %w", i+1, len(steps), name, err)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isiidaisuke0926/sleepship/internal/config"
+)
+
+func TestAliasEditCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	oldDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(oldDir) }()
+	_ = os.Chdir(dir)
+	defer func() { aliasGlobal, aliasForce = false, false }()
+
+	path := filepath.Join(dir, config.ConfigFileName)
+	if err := os.WriteFile(path, []byte("# team aliases\n[aliases]\ndev = \"sync tasks-dev.txt\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name    string
+		run     func() error
+		wantErr bool
+	}{
+		{name: "add", run: func() error { return runAliasAdd(nil, []string{"quick", "dev --max-retries=1"}) }},
+		{name: "add existing", run: func() error { return runAliasAdd(nil, []string{"dev", "sync other.txt"}) }, wantErr: true},
+		{name: "add circular", run: func() error { return runAliasAdd(nil, []string{"loop", "loop --keep-going"}) }, wantErr: true},
+		{name: "add built-in name", run: func() error { return runAliasAdd(nil, []string{"sync", "sync tasks.txt"}) }, wantErr: true},
+		{name: "rename", run: func() error { return runAliasRename(nil, []string{"quick", "fast"}) }},
+		{name: "remove", run: func() error { return runAliasRemove(nil, []string{"dev"}) }},
+		{name: "remove missing", run: func() error { return runAliasRemove(nil, []string{"dev"}) }, wantErr: true},
+	}
+	for _, step := range steps {
+		if err := step.run(); (err != nil) != step.wantErr {
+			t.Fatalf("%s: error = %v, wantErr %v", step.name, err, step.wantErr)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# team aliases\n[aliases]\nfast = \"dev --max-retries=1\"\n"; string(data) != want {
+		t.Errorf("config file = %q, want %q", data, want)
+	}
+
+	// Aliases that form a cycle through an existing alias are rejected too
+	aliasForce = true
+	if err := runAliasAdd(nil, []string{"dev", "fast"}); err == nil || !strings.Contains(err.Error(), "circular") {
+		t.Errorf("runAliasAdd() forming a cycle = %v, want circular reference error", err)
+	}
+}
+
+func TestAliasGlobal(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	oldDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(oldDir) }()
+	_ = os.Chdir(t.TempDir())
+	defer func() { aliasGlobal = false }()
+
+	aliasGlobal = true
+	if err := runAliasAdd(nil, []string{"nightly", "sync nightly.txt"}); err != nil {
+		t.Fatalf("runAliasAdd() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, config.ConfigFileName)); err != nil {
+		t.Errorf("global config file not written: %v", err)
+	}
+}
+
+func TestRunAliasSteps(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.txt")
+
+	alias := config.Alias{Steps: []string{
+		"!echo \"first $1\" >> " + out,
+		"!exit 3",
+		"!echo second >> " + out,
+	}}
+	err := runAliasSteps("steps", alias, []string{"arg"}, map[string]config.Alias{"steps": alias})
+	if err == nil || !strings.Contains(err.Error(), "step 2/3") {
+		t.Errorf("runAliasSteps() = %v, want failure of step 2", err)
+	}
+
+	// Steps after the failed step are not run
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first arg\n" {
+		t.Errorf("output = %q, want %q", data, "first arg\n")
+	}
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/isiidaisuke0926/sleepship/internal/history"
+)
+
+// Run budgets limit the resources a sync run may use. Zero means unlimited.
+var (
+	maxDuration   time.Duration // Wall-clock time of a run
+	maxAgentCalls int           // Agent invocations of a run
+	maxCost       float64       // Agent cost of a run in USD
+)
+
+// Resources used by the current sync run
+var (
+	runStart      time.Time
+	runAgentCalls int
+	runUsage      history.Usage
+)
+
+// errBudgetExceeded is wrapped by the errors returned instead of calling the
+// agent once the run has used up one of its budgets.
+var errBudgetExceeded = errors.New("budget exceeded")
+
+// budgetError reports which run budget was used up.
+type budgetError struct {
+	Reason  string // history.StopMaxDuration, StopMaxAgentCalls or StopMaxCost
+	Message string
+}
+
+func (e *budgetError) Error() string {
+	return e.Message
+}
+
+func (e *budgetError) Unwrap() error {
+	return errBudgetExceeded
+}
+
+// resetRunBudget starts tracking the resources of a new run.
+func resetRunBudget(start time.Time) {
+	runStart = start
+	runAgentCalls = 0
+	runUsage = history.Usage{}
+}
+
+// checkBudget returns a *budgetError if the run has used up one of its budgets.
+func checkBudget() error {
+	if elapsed := time.Since(runStart); maxDuration > 0 && elapsed >= maxDuration {
+		return timeBudgetError(elapsed)
+	}
+	if maxAgentCalls > 0 && runAgentCalls >= maxAgentCalls {
+		return &budgetError{
+			Reason:  history.StopMaxAgentCalls,
+			Message: fmt.Sprintf("agent call budget exceeded: made %d of %d calls", runAgentCalls, maxAgentCalls),
+		}
+	}
+	if maxCost > 0 && runUsage.CostUSD >= maxCost {
+		return &budgetError{
+			Reason:  history.StopMaxCost,
+			Message: fmt.Sprintf("cost budget exceeded: spent %s of %s", formatCost(runUsage.CostUSD), formatCost(maxCost)),
+		}
+	}
+	return nil
+}
+
+// timeBudgetError returns the *budgetError of a run that has run elapsed
+// and used up its time budget.
+func timeBudgetError(elapsed time.Duration) error {
+	return &budgetError{
+		Reason:  history.StopMaxDuration,
+		Message: fmt.Sprintf("time budget exceeded: ran %s of %s", formatDuration(elapsed), formatDuration(maxDuration)),
+	}
+}
+
+// agentContext returns the context an agent call runs with. It is done once
+// the time budget of the run is used up, so that a long agent call cannot
+// overrun it.
+func agentContext() (context.Context, context.CancelFunc) {
+	if maxDuration == 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithDeadline(context.Background(), runStart.Add(maxDuration))
+}
+
+// budgetStopReason returns the history stop reason of err, or an empty
+// string if err is not a budget error.
+func budgetStopReason(err error) string {
+	var budgetErr *budgetError
+	if errors.As(err, &budgetErr) {
+		return budgetErr.Reason
+	}
+	return ""
+}
+
+// maxDurationValue returns the time budget as recorded in history, or an
+// empty string if the run time is unlimited.
+func maxDurationValue() string {
+	if maxDuration == 0 {
+		return ""
+	}
+	return maxDurationFlag
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/isiidaisuke0926/sleepship/internal/history"
+)
+
+func TestCheckBudget(t *testing.T) {
+	oldDuration, oldCalls, oldCost := maxDuration, maxAgentCalls, maxCost
+	oldStart, oldRunCalls, oldUsage := runStart, runAgentCalls, runUsage
+	defer func() {
+		maxDuration, maxAgentCalls, maxCost = oldDuration, oldCalls, oldCost
+		runStart, runAgentCalls, runUsage = oldStart, oldRunCalls, oldUsage
+	}()
+
+	tests := []struct {
+		name       string
+		duration   time.Duration
+		calls      int
+		cost       float64
+		elapsed    time.Duration
+		agentCalls int
+		spent      float64
+		wantReason string
+	}{
+		{name: "unlimited", elapsed: 100 * time.Hour, agentCalls: 1000, spent: 100},
+		{name: "under budgets", duration: time.Hour, calls: 10, cost: 1, elapsed: 59 * time.Minute, agentCalls: 9, spent: 0.99},
+		{name: "time budget", duration: time.Hour, elapsed: time.Hour, wantReason: history.StopMaxDuration},
+		{name: "agent call budget", calls: 10, agentCalls: 10, wantReason: history.StopMaxAgentCalls},
+		{name: "cost budget", cost: 1, spent: 1, wantReason: history.StopMaxCost},
+		{name: "time checked first", duration: time.Minute, calls: 1, elapsed: time.Hour, agentCalls: 5, wantReason: history.StopMaxDuration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxDuration, maxAgentCalls, maxCost = tt.duration, tt.calls, tt.cost
+			resetRunBudget(time.Now().Add(-tt.elapsed))
+			runAgentCalls = tt.agentCalls
+			runUsage.Add(history.Usage{CostUSD: tt.spent})
+
+			err := checkBudget()
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Errorf("checkBudget() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, errBudgetExceeded) {
+				t.Fatalf("checkBudget() = %v, want errBudgetExceeded", err)
+			}
+			if reason := budgetStopReason(err); reason != tt.wantReason {
+				t.Errorf("budgetStopReason() = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestCallAgentOverBudget(t *testing.T) {
+	oldCalls, oldStart, oldRunCalls := maxAgentCalls, runStart, runAgentCalls
+	defer func() { maxAgentCalls, runStart, runAgentCalls = oldCalls, oldStart, oldRunCalls }()
+
+	maxAgentCalls = 2
+	resetRunBudget(time.Now())
+	runAgentCalls = 2
+
+	// No agent call is made once a budget is used up
+	result := &taskResult{}
+	if err := callAgent("prompt", result, nil); !errors.Is(err, errBudgetExceeded) || result.Attempts != 0 {
+		t.Errorf("callAgent() over budget = %v with %d attempts", err, result.Attempts)
+	}
+	if runAgentCalls != 2 {
+		t.Errorf("runAgentCalls = %d, want 2", runAgentCalls)
+	}
+}
+
+func TestBudgetStopReason(t *testing.T) {
+	err := &budgetError{Reason: history.StopMaxCost, Message: "cost budget exceeded"}
+	if reason := budgetStopReason(errors.Join(errors.New("task failed"), err)); reason != history.StopMaxCost {
+		t.Errorf("budgetStopReason() of wrapped error = %q", reason)
+	}
+	if reason := budgetStopReason(errors.New("verification failed")); reason != "" {
+		t.Errorf("budgetStopReason() of other error = %q, want empty", reason)
+	}
+}
+
+func TestAgentStoppedAtTimeBudget(t *testing.T) {
+	oldDuration, oldStart, oldRunCalls, oldProjectDir := maxDuration, runStart, runAgentCalls, projectDir
+	defer func() {
+		maxDuration, runStart, runAgentCalls, projectDir = oldDuration, oldStart, oldRunCalls, oldProjectDir
+	}()
+
+	// An agent that runs far longer than the time budget
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "claude"), []byte("#!/bin/sh\nexec sleep 60\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	projectDir = t.TempDir()
+	logFile, err := os.CreateTemp(t.TempDir(), "sync-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = logFile.Close() }()
+
+	maxDuration = 300 * time.Millisecond
+	resetRunBudget(time.Now())
+	result := &taskResult{}
+	start := time.Now()
+	err = callAgent("prompt", result, logFile)
+
+	if reason := budgetStopReason(err); reason != history.StopMaxDuration {
+		t.Errorf("callAgent() = %v, want a %s stop", err, history.StopMaxDuration)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("agent stopped after %s, want it stopped at the time budget", elapsed)
+	}
+	if result.Attempts != 1 {
+		t.Errorf("attempts = %d, want the stopped call recorded", result.Attempts)
+	}
+}
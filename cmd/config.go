@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/isiidaisuke0926/sleepship/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// strictConfig makes configuration problems fail the command (--strict-config)
+var strictConfig bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check config files and environment variables",
+	Long: `Check the SLEEPSHIP_* environment variables and every config file that
+applies to the current directory, and report:
+
+  - environment variables with invalid values, which are otherwise ignored
+  - unknown environment variables and TOML keys, with suggestions for typos
+  - values of the wrong type
+  - numbers out of range and invalid choices
+
+Every command runs the same checks before it starts and prints warnings for
+problems that it would otherwise ignore. With --strict-config or
+SLEEPSHIP_STRICT_CONFIG=true, any problem fails the command instead.
+
+validate exits with an error if a problem would stop a command, or if any
+problem is found in strict mode.
+
+Examples:
+  sleepship config validate
+  sleepship config validate --strict-config
+  sleepship --config ci.sleepship.toml config validate`,
+	Args:         cobra.NoArgs,
+	RunE:         runConfigValidate,
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+
+	rootCmd.PersistentFlags().BoolVar(&strictConfig, "strict-config", false, "Fail on any configuration problem instead of warning")
+}
+
+func runConfigValidate(_ *cobra.Command, _ []string) error {
+	paths, problems := config.Validate()
+
+	fmt.Println("🔍 Checked:")
+	fmt.Println("  environment variables")
+	for _, path := range paths {
+		fmt.Printf("  %s\n", displayPath(path))
+	}
+
+	if len(problems) == 0 {
+		fmt.Println("\n✅ Configuration is valid")
+		return nil
+	}
+
+	errorCount := 0
+	fmt.Printf("\nProblems (%d):\n", len(problems))
+	for _, problem := range problems {
+		mark := "⚠️ "
+		if !problem.IsWarning() {
+			mark = "❌"
+			errorCount++
+		}
+		fmt.Printf("  %s %s\n", mark, formatProblem(problem))
+	}
+
+	strict, err := isStrictConfig()
+	if err != nil {
+		return err
+	}
+	if errorCount > 0 || strict {
+		return fmt.Errorf("found %d configuration problem(s)", len(problems))
+	}
+	return nil
+}
+
+// checkConfig validates the configuration before a command runs. Problems
+// that the command would ignore are printed as warnings; in strict mode any
+// problem is an error.
+func checkConfig() error {
+	strict, err := isStrictConfig()
+	if err != nil {
+		return err
+	}
+
+	_, problems := config.Validate()
+	if len(problems) == 0 {
+		return nil
+	}
+	for _, problem := range problems {
+		if strict || problem.IsWarning() {
+			log.Printf("⚠️ Warning: %s\n", formatProblem(problem))
+		}
+	}
+	if strict {
+		return fmt.Errorf("found %d configuration problem(s) in strict mode; run \"sleepship config validate\" for details", len(problems))
+	}
+	return nil
+}
+
+// isStrictConfig reports whether strict mode is on, from --strict-config or
+// SLEEPSHIP_STRICT_CONFIG.
+func isStrictConfig() (bool, error) {
+	if strictConfig {
+		return true, nil
+	}
+	strict, err := config.ParseStrict(os.Getenv(config.StrictEnvVar))
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", config.StrictEnvVar, err)
+	}
+	return strict, nil
+}
+
+// formatProblem formats a configuration problem with the config file path
+// abbreviated.
+func formatProblem(problem *config.ValidationError) string {
+	p := *problem
+	if p.Source != config.EnvSource {
+		p.Source = displayPath(p.Source)
+	}
+	return p.Error()
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isiidaisuke0926/sleepship/internal/config"
+)
+
+func TestCheckConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, config.ConfigFileName), []byte("[sync]\nmax_retry = 5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.ConfigEnvVar, filepath.Join(dir, config.ConfigFileName))
+
+	tests := []struct {
+		name    string
+		flag    bool
+		env     string
+		wantErr bool
+	}{
+		{name: "warnings only", wantErr: false},
+		{name: "strict flag", flag: true, wantErr: true},
+		{name: "strict environment variable", env: "true", wantErr: true},
+		{name: "invalid strict environment variable", env: "sometimes", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strictConfig = tt.flag
+			defer func() { strictConfig = false }()
+			t.Setenv(config.StrictEnvVar, tt.env)
+
+			if err := checkConfig(); (err != nil) != tt.wantErr {
+				t.Errorf("checkConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Values that stop commands fail validate without strict mode
+	if err := os.WriteFile(filepath.Join(dir, config.ConfigFileName), []byte("[sync]\nmax_retries = \"5\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runConfigValidate(nil, nil); err == nil {
+		t.Error("runConfigValidate() expected error for a type mismatch")
+	}
+}
@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/isiidaisuke0926/sleepship/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage named contexts for switching projects and settings",
+	Long: `Manage contexts: named sets of settings for switching between projects.
+
+A context sets the project directory, default task file, agent flags, retry
+policy and the ref new branches are created from. Contexts are defined in
+[contexts.<name>] tables of ~/.sleepship.toml or a project's .sleepship.toml:
+
+  [contexts.api]
+  project_dir = "~/src/api"
+  default_task_file = "tasks.txt"   # relative to project_dir
+  claude_flags = ["--model", "opus"]
+  max_retries = 5
+  rollback = "preserve"
+  branch_base = "origin/main"
+
+The context selected with "sleepship context use" applies to every command
+until another one is selected. --context or SLEEPSHIP_CONTEXT selects a
+context for one command. Context settings take priority over config file
+settings, and environment variables and flags take priority over contexts.
+
+Examples:
+  sleepship context create api --dir ~/src/api --branch-base origin/main
+  sleepship context use api
+  sleepship sync                       # Runs the api project's default task file
+  sleepship --context web history
+  sleepship context use --unset`,
+}
+
+var contextListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List contexts",
+	Args:  cobra.NoArgs,
+	RunE:  runContextList,
+}
+
+var contextUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Select the context used by later commands",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if contextUnset {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: runContextUse,
+}
+
+var contextShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show the settings of a context (default: the current one)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runContextShow,
+}
+
+var contextCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a context",
+	Long: `Create a context in ~/.sleepship.toml, or in the project's .sleepship.toml
+with --project. Only the new [contexts.<name>] table is added; the rest of
+the file is kept.
+
+Examples:
+  sleepship context create api --dir ~/src/api --task-file tasks.txt
+  sleepship context create careful --max-retries 10 --rollback preserve --project
+  sleepship context create review --claude-flag=--model --claude-flag=opus --use`,
+	Args: cobra.ExactArgs(1),
+	RunE: runContextCreate,
+}
+
+var (
+	contextUnset bool // Stop using a context
+
+	contextProject     bool // Create the context in the project config file
+	contextUse         bool // Select the context after creating it
+	contextDir         string
+	contextTaskFile    string
+	contextClaudeFlags []string
+	contextMaxRetries  int
+	contextRollback    string
+	contextBranchBase  string
+)
+
+func init() {
+	rootCmd.AddCommand(contextCmd)
+	contextCmd.AddCommand(contextListCmd)
+	contextCmd.AddCommand(contextUseCmd)
+	contextCmd.AddCommand(contextShowCmd)
+	contextCmd.AddCommand(contextCreateCmd)
+
+	contextUseCmd.Flags().BoolVar(&contextUnset, "unset", false, "Stop using a context")
+
+	contextCreateCmd.Flags().BoolVar(&contextProject, "project", false, "Create the context in the project's .sleepship.toml instead of ~/.sleepship.toml")
+	contextCreateCmd.Flags().BoolVar(&contextUse, "use", false, "Use the context after creating it")
+	contextCreateCmd.Flags().StringVar(&contextDir, "dir", "", "Project directory")
+	contextCreateCmd.Flags().StringVar(&contextTaskFile, "task-file", "", "Default task file, relative to the project directory")
+	contextCreateCmd.Flags().StringArrayVar(&contextClaudeFlags, "claude-flag", nil, "Flag passed to the agent CLI (repeatable)")
+	contextCreateCmd.Flags().IntVar(&contextMaxRetries, "max-retries", 0, "Maximum number of retries for failed verifications")
+	contextCreateCmd.Flags().StringVar(&contextRollback, "rollback", "", "What happens to a failed task's changes: leave, reset, preserve")
+	contextCreateCmd.Flags().StringVar(&contextBranchBase, "branch-base", "", "Ref to create new branches from, e.g. origin/main")
+}
+
+func runContextList(_ *cobra.Command, _ []string) error {
+	contexts, err := config.LoadContexts()
+	if err != nil {
+		return fmt.Errorf("failed to load contexts: %w", err)
+	}
+	current, err := config.CurrentContext()
+	if err != nil {
+		return err
+	}
+
+	if len(contexts) == 0 {
+		fmt.Println("No contexts defined.")
+		fmt.Println("\nCreate one with: sleepship context create <name> --dir <project directory>")
+		return nil
+	}
+
+	names := make([]string, 0, len(contexts))
+	maxLen := 0
+	for name := range contexts {
+		names = append(names, name)
+		maxLen = max(maxLen, len(name))
+	}
+	sort.Strings(names)
+
+	fmt.Printf("Contexts (%d):\n\n", len(contexts))
+	for _, name := range names {
+		marker := " "
+		if name == current {
+			marker = "*"
+		}
+		definition := contexts[name]
+		dir := definition.Context.ProjectDir
+		if dir == "" {
+			dir = "-"
+		}
+		fmt.Printf("%s %-*s  %s [%s]\n", marker, maxLen, name, displayPath(dir), displayPath(definition.Source))
+	}
+
+	if current != "" {
+		if _, ok := contexts[current]; !ok {
+			fmt.Printf("\n⚠️  The current context %q is not defined\n", current)
+		}
+	}
+	return nil
+}
+
+func runContextUse(_ *cobra.Command, args []string) error {
+	if contextUnset {
+		if err := config.SetCurrentContext(""); err != nil {
+			return err
+		}
+		fmt.Println("✅ No context in use")
+		return nil
+	}
+
+	name := args[0]
+	contexts, err := config.LoadContexts()
+	if err != nil {
+		return fmt.Errorf("failed to load contexts: %w", err)
+	}
+	if _, ok := contexts[name]; !ok {
+		return fmt.Errorf("context %q is not defined", name)
+	}
+	if err := config.SetCurrentContext(name); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Switched to context %s\n", name)
+	return nil
+}
+
+func runContextShow(_ *cobra.Command, args []string) error {
+	name, err := config.CurrentContext()
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		name = args[0]
+	}
+	if name == "" {
+		fmt.Println("No context in use. Select one with: sleepship context use <name>")
+		return nil
+	}
+
+	contexts, err := config.LoadContexts()
+	if err != nil {
+		return fmt.Errorf("failed to load contexts: %w", err)
+	}
+	definition, ok := contexts[name]
+	if !ok {
+		return fmt.Errorf("context %q is not defined", name)
+	}
+
+	fmt.Printf("Context: %s\n", name)
+	fmt.Printf("Source: %s\n", displayPath(definition.Source))
+	settings := definition.Context.Settings()
+	if len(settings) == 0 {
+		fmt.Println("Settings: none")
+		return nil
+	}
+	fmt.Println("Settings:")
+	for _, setting := range settings {
+		fmt.Printf("  %s = %s\n", setting[0], setting[1])
+	}
+	return nil
+}
+
+func runContextCreate(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := validateContextName(name); err != nil {
+		return err
+	}
+
+	ctx := config.Context{
+		DefaultTaskFile: contextTaskFile,
+		ClaudeFlags:     contextClaudeFlags,
+		Rollback:        contextRollback,
+		BranchBase:      contextBranchBase,
+	}
+	if contextDir != "" {
+		// Stored as an absolute path, since relative paths in a config file
+		// are relative to the file
+		dir, err := filepath.Abs(contextDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve project directory: %w", err)
+		}
+		ctx.ProjectDir = dir
+	}
+	if cmd.Flags().Changed("max-retries") {
+		ctx.MaxRetries = &contextMaxRetries
+	}
+
+	path, err := contextFilePath()
+	if err != nil {
+		return err
+	}
+	if err := config.AddContext(path, name, ctx); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Created context %s\n", name)
+	fmt.Printf("📝 Config file: %s\n", displayPath(path))
+
+	if contextUse {
+		if err := config.SetCurrentContext(name); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Switched to context %s\n", name)
+	}
+	return nil
+}
+
+// contextFilePath returns the config file new contexts are added to.
+func contextFilePath() (string, error) {
+	if contextProject {
+		return projectConfigPath()
+	}
+	if explicit := config.ExplicitConfigPath(); explicit != "" {
+		return explicit, nil
+	}
+	return config.GlobalConfigPath()
+}
+
+// validateContextName returns an error if name cannot be used as a context.
+func validateContextName(name string) error {
+	if name == "" || name[0] == '-' {
+		return fmt.Errorf("invalid context name %q", name)
+	}
+	for _, r := range name {
+		if r == '.' || r <= ' ' {
+			return fmt.Errorf("invalid context name %q", name)
+		}
+	}
+	return nil
+}
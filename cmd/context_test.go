@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isiidaisuke0926/sleepship/internal/config"
+)
+
+func TestContextCommands(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv(config.ConfigEnvVar, "")
+	t.Setenv(config.ContextEnvVar, "")
+
+	project := t.TempDir()
+	contextDir, contextUse = project, true
+	defer func() { contextDir, contextUse = "", false }()
+	if err := runContextCreate(contextCreateCmd, []string{"api"}); err != nil {
+		t.Fatalf("runContextCreate() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, config.ConfigFileName)); err != nil {
+		t.Errorf("context was not created in the global config file: %v", err)
+	}
+	if err := runContextCreate(contextCreateCmd, []string{"api"}); err == nil {
+		t.Error("runContextCreate() expected error for an existing context")
+	}
+	if err := runContextCreate(contextCreateCmd, []string{"a.b"}); err == nil {
+		t.Error("runContextCreate() expected error for an invalid name")
+	}
+
+	// History commands use the project directory of the current context
+	if current, _ := config.CurrentContext(); current != "api" {
+		t.Errorf("current context = %q, want api", current)
+	}
+	if dir, err := historyProjectDir(); err != nil || dir != project {
+		t.Errorf("historyProjectDir() = %q, %v, want %q", dir, err, project)
+	}
+
+	if err := runContextUse(contextUseCmd, []string{"missing"}); err == nil {
+		t.Error("runContextUse() expected error for an undefined context")
+	}
+	contextUnset = true
+	defer func() { contextUnset = false }()
+	if err := runContextUse(contextUseCmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := config.CurrentContext(); current != "" {
+		t.Errorf("current context after --unset = %q", current)
+	}
+}
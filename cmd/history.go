@@ -2,19 +2,39 @@ package cmd
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/isiidaisuke0926/sleepship/internal/config"
 	"github.com/isiidaisuke0926/sleepship/internal/history"
 	"github.com/spf13/cobra"
 )
 
+// historyFormatTable is the default, human-readable output format of history
+const historyFormatTable = "table"
+
 var (
-	historyLast   int
-	historyFailed bool
+	historyLast        int
+	historyFailed      bool
+	historySince       string
+	historyUntil       string
+	historyTaskFile    string
+	historyBranch      string
+	historyStatus      string
+	historyMinDuration time.Duration
+	historySearch      string
+	historySort        string
+	historyDesc        bool
+	historyFormat      string
+	historyAllProjects bool
+
+	pruneDryRun     bool
+	pruneMaxEntries int
+	pruneMaxAge     string
 )
 
 var historyCmd = &cobra.Command{
@@ -22,49 +42,147 @@ var historyCmd = &cobra.Command{
 	Short: "Show task execution history",
 	Long: `Show the history of task executions.
 
+Filters can be combined; --last is applied to the matching executions.
+--since and --until accept a date (2025-01-31), a time (2025-01-31 15:04
+or RFC 3339) or an age relative to now (7d, 2w, 12h). A date given to
+--until includes that whole day.
+
 Examples:
   sleepship history                 # Show all history
   sleepship history --last 5        # Show last 5 executions
   sleepship history --last 1        # Show last execution
-  sleepship history --failed        # Show only failed executions`,
+  sleepship history --failed        # Show only failed executions
+  sleepship history --failed --last 3 --since 7d
+  sleepship history --task-file 'tasks-*.txt' --sort duration --desc
+  sleepship history --search "timeout" --format csv > history.csv
+  sleepship history --dir ../api    # Show the history of another project
+  sleepship history --all-projects  # Show executions of every project
+  sleepship history show <id>       # Show the task breakdown of one execution
+  sleepship history prune --dry-run # Show executions beyond the retention limits`,
 	RunE: runHistory,
 }
 
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show the task breakdown of one execution",
+	Long: `Show the per-task results of one execution.
+
+The execution is identified by its run ID (shown in the ID column of
+"sleepship history"). Executions recorded before run IDs existed can be
+identified by their position in the history, starting at 1 for the oldest.
+
+Examples:
+  sleepship history show 20250101-020000.123
+  sleepship history show 3`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHistoryShow,
+}
+
+var historyDiffCmd = &cobra.Command{
+	Use:   "diff <id> <id>",
+	Short: "Compare what two executions were started with",
+	Long: `Compare the run archives of two executions: the sleepship and agent CLI
+versions, the commit checked out at start, the merged configuration and the
+task file content.
+
+Executions are identified as in "sleepship history show". Only executions
+recorded with a run archive can be compared.
+
+Examples:
+  sleepship history diff 20250101-020000.123 20250102-020000.456
+  sleepship history diff 3 4`,
+	Args: cobra.ExactArgs(2),
+	RunE: runHistoryDiff,
+}
+
+var historyPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old executions and their log files",
+	Long: `Remove executions beyond the retention limits, together with their log files.
+
+The limits are read from the [history] section of .sleepship.toml or from
+SLEEPSHIP_HISTORY_MAX_ENTRIES / SLEEPSHIP_HISTORY_MAX_AGE, and can be
+overridden with flags. sync prunes automatically after recording each run.
+
+Examples:
+  sleepship history prune --dry-run              # Show what would be removed
+  sleepship history prune --max-entries 100      # Keep the latest 100 executions
+  sleepship history prune --max-age 30d          # Remove executions older than 30 days`,
+	Args: cobra.NoArgs,
+	RunE: runHistoryPrune,
+}
+
 func init() {
 	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyDiffCmd)
+	historyCmd.AddCommand(historyPruneCmd)
+
+	historyCmd.PersistentFlags().StringVar(&projectDir, "dir", "", "Project directory (default: current directory)")
+	historyCmd.Flags().BoolVar(&historyAllProjects, "all-projects", false, "Show executions of every project sleepship has run in")
+	historyShowCmd.Flags().BoolVar(&historyAllProjects, "all-projects", false, "Search executions of every project sleepship has run in")
+	historyDiffCmd.Flags().BoolVar(&historyAllProjects, "all-projects", false, "Search executions of every project sleepship has run in")
 
 	historyCmd.Flags().IntVar(&historyLast, "last", 0, "Show last N executions (0 = all)")
-	historyCmd.Flags().BoolVar(&historyFailed, "failed", false, "Show only failed executions")
+	historyCmd.Flags().BoolVar(&historyFailed, "failed", false, "Show only failed executions (same as --status failed)")
+	historyCmd.Flags().StringVar(&historySince, "since", "", "Show executions at or after a date, time or age (e.g. 2025-01-31, 7d)")
+	historyCmd.Flags().StringVar(&historyUntil, "until", "", "Show executions before a date, time or age")
+	historyCmd.Flags().StringVar(&historyTaskFile, "task-file", "", "Show executions of task files matching a glob or path substring")
+	historyCmd.Flags().StringVar(&historyBranch, "branch", "", "Show executions on branches containing the text")
+	historyCmd.Flags().StringVar(&historyStatus, "status", "", "Show executions with the status: success, failed")
+	historyCmd.Flags().DurationVar(&historyMinDuration, "min-duration", 0, "Show executions that took at least the duration (e.g. 10m)")
+	historyCmd.Flags().StringVar(&historySearch, "search", "", "Show executions whose error messages contain the text (case-insensitive)")
+	historyCmd.Flags().StringVar(&historySort, "sort", history.SortExecutedAt, "Sort by: "+strings.Join(history.SortKeys, ", "))
+	historyCmd.Flags().BoolVar(&historyDesc, "desc", false, "Sort in descending order")
+	historyCmd.Flags().StringVar(&historyFormat, "format", historyFormatTable, "Output format: table, json, csv, markdown")
+
+	historyPruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Show what would be removed without removing anything")
+	historyPruneCmd.Flags().IntVar(&pruneMaxEntries, "max-entries", 0, "Maximum number of executions to keep (0 = unlimited)")
+	historyPruneCmd.Flags().StringVar(&pruneMaxAge, "max-age", "", "Maximum age of executions to keep, e.g. 30d, 2w, 12h (0 = unlimited)")
 }
 
 func runHistory(_ *cobra.Command, _ []string) error {
-	// Get project directory
-	dir, err := os.Getwd()
+	switch historyFormat {
+	case historyFormatTable, history.FormatJSON, history.FormatCSV, history.FormatMarkdown:
+	default:
+		return fmt.Errorf("invalid --format %q (valid: table, json, csv, markdown)", historyFormat)
+	}
+
+	filter, err := buildHistoryFilter(time.Now())
 	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
+		return err
 	}
 
 	// Load history
-	hist, err := history.Load(dir)
+	hist, err := loadHistory()
 	if err != nil {
-		return fmt.Errorf("failed to load history: %w", err)
+		return err
+	}
+
+	// Filter, limit and sort entries
+	entries := hist.Query(filter)
+	if historyLast > 0 && len(entries) > historyLast {
+		entries = entries[len(entries)-historyLast:]
+	}
+	if err := history.SortEntries(entries, historySort, historyDesc); err != nil {
+		return err
+	}
+
+	if historyFormat != historyFormatTable {
+		return history.Export(os.Stdout, entries, historyFormat)
 	}
 
 	if len(hist.Entries) == 0 {
 		fmt.Println("📋 No execution history found")
 		return nil
 	}
-
-	// Filter entries
-	entries := hist.Entries
-	if historyFailed {
-		entries = hist.GetFailed()
-		if len(entries) == 0 {
+	if len(entries) == 0 {
+		if filter == (history.Filter{Status: history.StatusFailed}) {
 			fmt.Println("✅ No failed executions found")
-			return nil
+		} else {
+			fmt.Println("📋 No matching executions found")
 		}
-	} else if historyLast > 0 {
-		entries = hist.GetLast(historyLast)
+		return nil
 	}
 
 	// Display entries
@@ -73,6 +191,124 @@ func runHistory(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
+// historyProjectDir returns the absolute project directory given by --dir,
+// or the current directory.
+func historyProjectDir() (string, error) {
+	dir := projectDir
+	if dir == "" {
+		contextConfig, _, err := config.LoadContextConfig()
+		if err != nil {
+			return "", err
+		}
+		dir = contextConfig.ProjectDir
+	}
+	if dir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get current directory: %w", err)
+		}
+		dir = cwd
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve project directory: %w", err)
+	}
+	return absDir, nil
+}
+
+// entryProjectDir returns the project directory of an entry loaded with
+// loadHistory.
+func entryProjectDir(entry *history.Entry) (string, error) {
+	if entry.ProjectDir != "" {
+		return entry.ProjectDir, nil
+	}
+	return historyProjectDir()
+}
+
+// loadHistory loads the history of the project directory, or of every
+// project in the global index with --all-projects.
+func loadHistory() (*history.History, error) {
+	if historyAllProjects {
+		indexDir, err := config.UserConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		entries, err := history.LoadAll(indexDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load history: %w", err)
+		}
+		return &history.History{Entries: entries}, nil
+	}
+
+	dir, err := historyProjectDir()
+	if err != nil {
+		return nil, err
+	}
+	hist, err := history.Load(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load history: %w", err)
+	}
+	return hist, nil
+}
+
+// buildHistoryFilter builds the history filter from the command flags.
+func buildHistoryFilter(now time.Time) (history.Filter, error) {
+	filter := history.Filter{
+		TaskFile:    historyTaskFile,
+		Branch:      historyBranch,
+		Status:      historyStatus,
+		MinDuration: historyMinDuration,
+		Search:      historySearch,
+	}
+
+	switch historyStatus {
+	case "", history.StatusSuccess, history.StatusFailed:
+	default:
+		return filter, fmt.Errorf("invalid --status %q (valid: success, failed)", historyStatus)
+	}
+	if historyFailed {
+		if historyStatus == history.StatusSuccess {
+			return filter, fmt.Errorf("--failed cannot be combined with --status success")
+		}
+		filter.Status = history.StatusFailed
+	}
+
+	var err error
+	if historySince != "" {
+		if filter.Since, err = parseTimeBound(historySince, now, false); err != nil {
+			return filter, fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if historyUntil != "" {
+		if filter.Until, err = parseTimeBound(historyUntil, now, true); err != nil {
+			return filter, fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+
+	return filter, nil
+}
+
+// parseTimeBound parses a date, a time or an age relative to now. With
+// endOfDay, a date without a time refers to the end of that day.
+func parseTimeBound(value string, now time.Time, endOfDay bool) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if endOfDay {
+			return t.AddDate(0, 0, 1), nil
+		}
+		return t, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if age, err := config.ParseAge(value); err == nil {
+		return now.Add(-age), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date, time or age (e.g. 2025-01-31, \"2025-01-31 15:04\", 7d)", value)
+}
+
 func displayHistory(entries []history.Entry) {
 	// Create color printers
 	green := color.New(color.FgGreen).SprintFunc()
@@ -97,13 +333,32 @@ func displayHistory(entries []history.Entry) {
 		maxTaskFileLen = 50
 	}
 
+	// Show the project column when entries come from several projects
+	showProject := false
+	for _, entry := range entries {
+		if entry.ProjectDir != "" {
+			showProject = true
+			break
+		}
+	}
+	projectColumn := func(entry history.Entry) string {
+		if !showProject {
+			return ""
+		}
+		return fmt.Sprintf("%-20s ", shorten(filepath.Base(entry.ProjectDir), 20))
+	}
+
 	// Header
-	headerFormat := "%-6s %-" + fmt.Sprintf("%d", maxTaskFileLen) + "s %-20s %-10s %-6s %-8s %s\n"
-	fmt.Printf(headerFormat, "Status", "Task File", "Executed At", "Duration", "Tasks", "Retries", "Branch")
-	fmt.Println(strings.Repeat("-", maxTaskFileLen+80))
+	headerFormat := "%-6s %s%-15s %-" + fmt.Sprintf("%d", maxTaskFileLen) + "s %-20s %-10s %-6s %-8s %-8s %s\n"
+	projectHeader := ""
+	if showProject {
+		projectHeader = fmt.Sprintf("%-20s ", "Project")
+	}
+	fmt.Printf(headerFormat, "Status", projectHeader, "ID", "Task File", "Executed At", "Duration", "Tasks", "Retries", "Cost", "Branch")
+	fmt.Println(strings.Repeat("-", maxTaskFileLen+105+len(projectHeader)))
 
 	// Entries
-	entryFormat := "%-6s %-" + fmt.Sprintf("%d", maxTaskFileLen) + "s %-20s %-10s %-6d %-8d %s\n"
+	entryFormat := "%-6s %s%-15s %-" + fmt.Sprintf("%d", maxTaskFileLen) + "s %-20s %-10s %-6d %-8d %-8s %s\n"
 	for _, entry := range entries {
 		// Status
 		var status string
@@ -137,7 +392,13 @@ func displayHistory(entries []history.Entry) {
 			}
 		}
 
-		fmt.Printf(entryFormat, status, taskFile, executedAt, duration, entry.TaskCount, entry.MaxRetries, branch)
+		// Cost reported by the agent
+		cost := "-"
+		if entry.Usage != nil {
+			cost = formatCost(entry.Usage.CostUSD)
+		}
+
+		fmt.Printf(entryFormat, status, projectColumn(entry), valueOrDash(entry.ID), taskFile, executedAt, duration, entry.TaskCount, entry.MaxRetries, cost, branch)
 
 		// Show error message if failed
 		if !entry.Success && entry.ErrorMessage != "" {
@@ -155,6 +416,7 @@ func displayHistory(entries []history.Entry) {
 	successCount := 0
 	failedCount := 0
 	totalDuration := time.Duration(0)
+	var totalUsage history.Usage
 
 	for _, entry := range entries {
 		if entry.Success {
@@ -163,13 +425,316 @@ func displayHistory(entries []history.Entry) {
 			failedCount++
 		}
 		totalDuration += entry.Duration
+		if entry.Usage != nil {
+			totalUsage.Add(*entry.Usage)
+		}
 	}
 
 	fmt.Printf("📊 Summary:\n")
 	fmt.Printf("   Total: %d | ", len(entries))
 	fmt.Printf("%s: %d | ", green("Success"), successCount)
 	fmt.Printf("%s: %d | ", red("Failed"), failedCount)
-	fmt.Printf("%s: %s\n", blue("Total Duration"), formatDuration(totalDuration))
+	fmt.Printf("%s: %s", blue("Total Duration"), formatDuration(totalDuration))
+	if !totalUsage.IsZero() {
+		fmt.Printf(" | %s: %s", blue("Total Cost"), formatCost(totalUsage.CostUSD))
+	}
+	fmt.Println()
+}
+
+func runHistoryShow(_ *cobra.Command, args []string) error {
+	hist, err := loadHistory()
+	if err != nil {
+		return err
+	}
+
+	entry, err := hist.Find(args[0])
+	if err != nil {
+		return err
+	}
+
+	var archive *history.Archive
+	if entry.RunHash != "" {
+		dir, err := entryProjectDir(entry)
+		if err != nil {
+			return err
+		}
+		if archive, err = history.LoadArchive(dir, *entry); err != nil {
+			log.Printf("⚠️ Warning: %v\n", err)
+		}
+	}
+
+	displayHistoryEntry(entry, archive)
+	return nil
+}
+
+func displayHistoryEntry(entry *history.Entry, archive *history.Archive) {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	status := green("✅ Success")
+	if !entry.Success {
+		status = red("❌ Failed")
+	}
+
+	fmt.Printf("📋 Execution %s\n\n", valueOrDash(entry.ID))
+	if entry.ProjectDir != "" {
+		fmt.Printf("   Project:     %s\n", entry.ProjectDir)
+	}
+	if entry.Member != "" {
+		fmt.Printf("   Member:      %s\n", entry.Member)
+	}
+	fmt.Printf("   Task File:   %s\n", entry.TaskFile)
+	fmt.Printf("   Executed At: %s\n", entry.ExecutedAt.Format("2006-01-02 15:04:05"))
+	fmt.Printf("   Status:      %s\n", status)
+	fmt.Printf("   Duration:    %s\n", formatDuration(entry.Duration))
+	fmt.Printf("   Branch:      %s\n", valueOrDash(entry.BranchName))
+	if entry.PushedRef != "" {
+		fmt.Printf("   Pushed:      %s\n", entry.PushedRef)
+	}
+	if entry.ErrorMessage != "" {
+		fmt.Printf("   %s       %s\n", yellow("Error:"), entry.ErrorMessage)
+	}
+	if entry.StopReason != "" {
+		fmt.Printf("   %s     %s budget exceeded\n", yellow("Stopped:"), entry.StopReason)
+	}
+	if entry.NextTask > 0 {
+		fmt.Printf("   Resume:      sleepship rerun %s --resume (from task %d)\n", valueOrDash(entry.ID), entry.NextTask)
+	}
+	if entry.Usage != nil {
+		fmt.Printf("   Usage:       %s\n", formatUsage(*entry.Usage))
+		if len(entry.Usage.Models) > 0 {
+			fmt.Printf("   Models:      %s\n", strings.Join(entry.Usage.Models, ", "))
+		}
+	}
+	if archive != nil {
+		fmt.Printf("   Run Hash:    %s\n", entry.RunHash)
+		fmt.Printf("   Sleepship:   %s\n", valueOrDash(archive.SleepshipVersion))
+		fmt.Printf("   Agent:       %s\n", valueOrDash(archive.AgentVersion))
+		fmt.Printf("   Git HEAD:    %s\n", valueOrDash(archive.GitHead))
+	}
+	fmt.Println()
+
+	if len(entry.Tasks) == 0 {
+		fmt.Println("ℹ️  No per-task records for this execution (recorded by an older version)")
+		return
+	}
+
+	fmt.Printf("%-4s %-12s %-9s %-10s %-9s %s\n", "#", "Status", "Attempts", "Duration", "Commit", "Task")
+	fmt.Println(strings.Repeat("-", 80))
+
+	for _, task := range entry.Tasks {
+		commit := "-"
+		if len(task.CommitSHA) >= 7 {
+			commit = task.CommitSHA[:7]
+		}
+		fmt.Printf("%-4d %s %-9s %-9d %-10s %-9s %s\n",
+			task.Number, statusIcon(task.Status), task.Status, task.Attempts, formatDuration(task.Duration), commit, strings.TrimSpace(task.Title))
+
+		if len(task.AgentExitCodes) > 0 {
+			codes := make([]string, len(task.AgentExitCodes))
+			for i, code := range task.AgentExitCodes {
+				codes[i] = fmt.Sprintf("%d", code)
+			}
+			fmt.Printf("     Agent exit codes: %s\n", strings.Join(codes, ", "))
+		}
+		if task.Usage != nil {
+			fmt.Printf("     Usage: %s\n", formatUsage(*task.Usage))
+			if len(task.AttemptUsage) > 1 {
+				costs := make([]string, len(task.AttemptUsage))
+				for i, usage := range task.AttemptUsage {
+					costs[i] = formatCost(usage.CostUSD)
+				}
+				fmt.Printf("     Cost per attempt: %s\n", strings.Join(costs, ", "))
+			}
+		}
+		for _, v := range task.Verifications {
+			if v.Passed {
+				fmt.Printf("     %s %s\n", green("✓"), v.Command)
+			} else {
+				fmt.Printf("     %s %s\n", red("✗"), v.Command)
+			}
+		}
+		if task.Error != "" {
+			fmt.Printf("     %s %s\n", yellow("Error:"), task.Error)
+		}
+	}
+}
+
+func runHistoryDiff(_ *cobra.Command, args []string) error {
+	hist, err := loadHistory()
+	if err != nil {
+		return err
+	}
+
+	var archives [2]*history.Archive
+	var taskLines [2][]string
+	for i, id := range args {
+		entry, err := hist.Find(id)
+		if err != nil {
+			return err
+		}
+		dir, err := entryProjectDir(entry)
+		if err != nil {
+			return err
+		}
+		if archives[i], err = history.LoadArchive(dir, *entry); err != nil {
+			return fmt.Errorf("execution %s: %w", id, err)
+		}
+
+		path, err := history.FindSnapshot(dir, *entry)
+		if err != nil {
+			return fmt.Errorf("execution %s: %w", id, err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read task file snapshot: %w", err)
+		}
+		taskLines[i] = strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	}
+
+	changes, err := history.CompareArchives(archives[0], archives[1])
+	if err != nil {
+		return fmt.Errorf("failed to compare configurations: %w", err)
+	}
+
+	fmt.Printf("🔍 Comparing %s → %s\n\n", args[0], args[1])
+	taskFileChanged := archives[0].TaskFileHash != archives[1].TaskFileHash
+	if len(changes) == 0 && !taskFileChanged {
+		fmt.Println("✅ Both executions started from the same task file, configuration and versions")
+		return nil
+	}
+
+	for _, change := range changes {
+		fmt.Printf("   %s: %s → %s\n", change.Field, valueOrDash(change.Old), valueOrDash(change.New))
+	}
+
+	if taskFileChanged {
+		if len(changes) > 0 {
+			fmt.Println()
+		}
+		fmt.Println("📝 Task file changes")
+		displayLineDiff(history.DiffLines(taskLines[0], taskLines[1]), diffContextLines)
+	}
+	return nil
+}
+
+// diffContextLines is the number of unchanged lines shown around changes
+const diffContextLines = 3
+
+// displayLineDiff prints the changed lines of diff with context lines around
+// them, separating distant changes with "...".
+func displayLineDiff(diff []history.DiffLine, context int) {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	// Mark the lines within context of a change
+	show := make([]bool, len(diff))
+	for i, line := range diff {
+		if line.Op == history.DiffEqual {
+			continue
+		}
+		for j := max(0, i-context); j <= min(len(diff)-1, i+context); j++ {
+			show[j] = true
+		}
+	}
+
+	gap := false
+	for i, line := range diff {
+		if !show[i] {
+			gap = true
+			continue
+		}
+		if gap {
+			fmt.Println("   ...")
+			gap = false
+		}
+		switch line.Op {
+		case history.DiffDelete:
+			fmt.Printf("   %s\n", red("- "+line.Text))
+		case history.DiffInsert:
+			fmt.Printf("   %s\n", green("+ "+line.Text))
+		default:
+			fmt.Printf("     %s\n", line.Text)
+		}
+	}
+}
+
+func runHistoryPrune(cmd *cobra.Command, _ []string) error {
+	dir, err := historyProjectDir()
+	if err != nil {
+		return err
+	}
+
+	fileConfig, err := config.LoadFileConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config file: %w", err)
+	}
+
+	cliConfig := &config.Config{MaxRetries: -1, StartFrom: -1, MaxAgentCalls: -1, MaxCost: -1, HistoryMaxEntries: -1}
+	if cmd.Flags().Changed("max-entries") {
+		if pruneMaxEntries < 0 {
+			return fmt.Errorf("invalid --max-entries %d (must be 0 or greater)", pruneMaxEntries)
+		}
+		cliConfig.HistoryMaxEntries = pruneMaxEntries
+	}
+	if cmd.Flags().Changed("max-age") {
+		cliConfig.HistoryMaxAge = pruneMaxAge
+	}
+
+	contextConfig, _, err := config.LoadContextConfig()
+	if err != nil {
+		return err
+	}
+
+	mergedConfig := config.MergeLayers(config.NewDefaultConfig(), cliConfig, config.FromEnv(config.LoadFromEnv()), contextConfig, config.FromFile(fileConfig))
+	retention, err := historyRetention(mergedConfig)
+	if err != nil {
+		return err
+	}
+	if retention.IsUnlimited() {
+		fmt.Println("ℹ️  No retention limits configured (set [history] max_entries / max_age or use --max-entries / --max-age)")
+		return nil
+	}
+
+	pruned, err := history.Prune(dir, retention, pruneDryRun)
+	if err != nil {
+		return fmt.Errorf("failed to prune history: %w", err)
+	}
+	if len(pruned) == 0 {
+		fmt.Println("✅ No executions to prune")
+		return nil
+	}
+
+	if pruneDryRun {
+		fmt.Printf("🔍 %d executions would be removed:\n\n", len(pruned))
+	} else {
+		fmt.Printf("🧹 Removed %d executions:\n\n", len(pruned))
+	}
+	for _, entry := range pruned {
+		fmt.Printf("  %s  %-15s %s\n", entry.ExecutedAt.Format("2006-01-02 15:04:05"), valueOrDash(entry.ID), filepath.Base(entry.TaskFile))
+		if entry.LogFile != "" {
+			fmt.Printf("      log: %s\n", entry.LogFile)
+		}
+	}
+
+	return nil
+}
+
+// historyRetention converts the retention settings of cfg.
+func historyRetention(cfg *config.Config) (history.Retention, error) {
+	maxAge, err := config.ParseAge(cfg.HistoryMaxAge)
+	if err != nil {
+		return history.Retention{}, fmt.Errorf("invalid history max age: %w", err)
+	}
+	return history.Retention{MaxEntries: cfg.HistoryMaxEntries, MaxAge: maxAge}, nil
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
 }
 
 func formatDuration(d time.Duration) string {
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		value    string
+		endOfDay bool
+		want     time.Time
+		wantErr  bool
+	}{
+		{value: "2025-01-15", want: time.Date(2025, 1, 15, 0, 0, 0, 0, time.Local)},
+		{value: "2025-01-15", endOfDay: true, want: time.Date(2025, 1, 16, 0, 0, 0, 0, time.Local)},
+		{value: "2025-01-15 08:30", endOfDay: true, want: time.Date(2025, 1, 15, 8, 30, 0, 0, time.Local)},
+		{value: "2025-01-15T08:30:00Z", want: time.Date(2025, 1, 15, 8, 30, 0, 0, time.UTC)},
+		{value: "7d", want: now.AddDate(0, 0, -7)},
+		{value: "12h", want: now.Add(-12 * time.Hour)},
+		{value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTimeBound(tt.value, now, tt.endOfDay)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTimeBound(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseTimeBound(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBuildHistoryFilter(t *testing.T) {
+	defer func() { historyFailed, historyStatus = false, "" }()
+
+	historyFailed, historyStatus = true, ""
+	filter, err := buildHistoryFilter(time.Now())
+	if err != nil || filter.Status != "failed" {
+		t.Errorf("--failed: filter.Status = %q, err = %v", filter.Status, err)
+	}
+
+	historyFailed, historyStatus = true, "success"
+	if _, err := buildHistoryFilter(time.Now()); err == nil {
+		t.Error("expected error for --failed with --status success")
+	}
+
+	historyFailed, historyStatus = false, "done"
+	if _, err := buildHistoryFilter(time.Now()); err == nil {
+		t.Error("expected error for invalid --status")
+	}
+}
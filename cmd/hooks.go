@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// commitHooks lists the git hooks that can reject a commit.
+var commitHooks = []string{"pre-commit", "prepare-commit-msg", "commit-msg"}
+
+// commitHookError reports a commit that was rejected by a git hook.
+type commitHookError struct {
+	Hooks  []string // Active hooks that may have rejected the commit
+	Output string   // Combined output of git commit, including hook output
+}
+
+func (e *commitHookError) Error() string {
+	return fmt.Sprintf("commit rejected by git hook (%s):\n%s", strings.Join(e.Hooks, ", "), e.Output)
+}
+
+// activeCommitHooks returns the names of the commit hooks installed in the
+// project repository, honoring core.hooksPath.
+func activeCommitHooks() []string {
+	var active []string
+	for _, hook := range commitHooks {
+		hookPath, err := runGit("rev-parse", "--git-path", "hooks/"+hook)
+		if err != nil {
+			continue
+		}
+		if !filepath.IsAbs(hookPath) {
+			hookPath = filepath.Join(projectDir, hookPath)
+		}
+		info, err := os.Stat(hookPath)
+		if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+			continue
+		}
+		active = append(active, hook)
+	}
+	return active
+}
+
+// gitFatalExitCode is the exit code of git when it fails by itself, for
+// example on a locked index or an unknown identity, rather than because a
+// hook failed
+const gitFatalExitCode = 128
+
+// hookRejectedCommit reports whether a failed git commit was rejected by a
+// git hook: git did not fail by itself, and the staged changes can be
+// committed when the hooks are skipped.
+func hookRejectedCommit(commitErr error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(commitErr, &exitErr) || exitErr.ExitCode() == gitFatalExitCode {
+		return false
+	}
+	_, err := runGit("commit", "--dry-run", "--no-verify", "--quiet", "-m", "sleepship hook check")
+	return err == nil
+}
+
+// commitWithHookFixes runs commit and, when a git hook rejects it, asks Claude
+// to fix the reported problems and tries again within the retry budget.
+// verify, if not nil, is re-run after each fix before committing again.
+// Commit failures not caused by hooks are logged as warnings. label names
+// the commit in messages, e.g. "task 3". Agent calls are recorded in result,
+// which may be nil.
+func commitWithHookFixes(commit func() error, verify func() error, label string, result *taskResult, logFile *os.File) error {
+	for retryCount := 0; ; retryCount++ {
+		failure := ""
+		if retryCount > 0 && verify != nil {
+			if err := verify(); err != nil {
+				failure = fmt.Sprintf("フック修正後の検証に失敗しました:\n%v", err)
+			}
+		}
+
+		if failure == "" {
+			err := commit()
+			var hookErr *commitHookError
+			if !errors.As(err, &hookErr) {
+				if err != nil {
+					log.Printf("⚠️ Warning: Failed to commit changes: %v\n", err)
+					// Continue anyway - commit failure is not critical
+				}
+				return nil
+			}
+			failure = fmt.Sprintf("コミット時にGitフック（%s）が失敗しました:\n%s", strings.Join(hookErr.Hooks, ", "), hookErr.Output)
+		}
+
+		if retryCount >= maxRetries {
+			log.Printf("❌ %s のコミットが %d 回の試行後もGitフックに拒否されました\n", label, maxRetries+1)
+			return fmt.Errorf("commit for %s rejected after %d attempts: %s", label, maxRetries+1, failure)
+		}
+
+		log.Printf("❌ コミットがGitフックに拒否されました。修正を試みます（リトライ %d/%d 回目）\n", retryCount+1, maxRetries)
+
+		fixPrompt := fmt.Sprintf(`%s
+
+# 指示
+1. 上記で報告された問題を修正してください
+2. 修正後、検証が通ることを確認してください
+3. git commit は実行しないでください（sleepshipがコミットします）
+
+プロジェクトディレクトリ: %s
+
+修正を開始してください。`, failure, projectDir)
+
+		err := callAgent(fixPrompt, result, logFile)
+		if errors.Is(err, errBudgetExceeded) {
+			return err
+		}
+		if err != nil {
+			log.Printf("❌ 修正の実行に失敗しました: %v\n", err)
+		}
+	}
+}
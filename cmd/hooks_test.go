@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func installTestHook(t *testing.T, dir, name, script string) {
+	t.Helper()
+
+	hookPath := filepath.Join(dir, ".git", "hooks", name)
+	if err := os.WriteFile(hookPath, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCommitHookDetection(t *testing.T) {
+	dir := initTestRepo(t)
+	logFile, err := os.CreateTemp(t.TempDir(), "sync-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = logFile.Close() }()
+
+	if hooks := activeCommitHooks(); len(hooks) != 0 {
+		t.Errorf("activeCommitHooks() = %v, want none", hooks)
+	}
+
+	installTestHook(t, dir, "pre-commit", `echo "lint: trailing whitespace in main.go"; exit 1`)
+	if hooks := activeCommitHooks(); len(hooks) != 1 || hooks[0] != "pre-commit" {
+		t.Errorf("activeCommitHooks() = %v, want [pre-commit]", hooks)
+	}
+
+	writeTestFile(t, dir, "main.go", "package main ")
+	err = commitChanges("タスク1: test", logFile)
+
+	var hookErr *commitHookError
+	if !errors.As(err, &hookErr) {
+		t.Fatalf("commitChanges() error = %v, want commitHookError", err)
+	}
+	if hookErr.Output == "" || !containsSubstring(hookErr.Output, "trailing whitespace") {
+		t.Errorf("hook output = %q, want hook message", hookErr.Output)
+	}
+
+	// Without retries left the rejection fails the task instead of being ignored
+	oldMaxRetries := maxRetries
+	maxRetries = 0
+	defer func() { maxRetries = oldMaxRetries }()
+
+	err = commitWithHookFixes(func() error { return commitChanges("タスク1: test", logFile) }, nil, "task 1", nil, logFile)
+	if err == nil {
+		t.Error("commitWithHookFixes() expected error when the hook keeps rejecting the commit")
+	}
+
+	// Non-hook failures are only warnings
+	err = commitWithHookFixes(func() error { return errors.New("disk full") }, nil, "task 1", nil, logFile)
+	if err != nil {
+		t.Errorf("commitWithHookFixes() error = %v, want nil for non-hook failures", err)
+	}
+}
+
+func TestCommitFailureWithoutHookRejection(t *testing.T) {
+	dir := initTestRepo(t)
+	logFile, err := os.CreateTemp(t.TempDir(), "sync-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = logFile.Close() }()
+
+	// A hook is installed, but the commit fails because the identity is unknown
+	installTestHook(t, dir, "pre-commit", "exit 0")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("EMAIL", "")
+	for _, args := range [][]string{{"config", "--unset", "user.email"}, {"config", "--unset", "user.name"}, {"config", "user.useConfigOnly", "true"}} {
+		if _, err := runGit(args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeTestFile(t, dir, "main.go", "package main")
+	err = commitChanges("タスク1: test", logFile)
+	var hookErr *commitHookError
+	if err == nil || errors.As(err, &hookErr) {
+		t.Fatalf("commitChanges() error = %v, want a failure not caused by a hook", err)
+	}
+
+	// It is a warning, not a rejection to fix
+	oldMaxRetries := maxRetries
+	maxRetries = 0
+	defer func() { maxRetries = oldMaxRetries }()
+
+	err = commitWithHookFixes(func() error { return commitChanges("タスク1: test", logFile) }, nil, "task 1", nil, logFile)
+	if err != nil {
+		t.Errorf("commitWithHookFixes() error = %v, want nil", err)
+	}
+}
+
+func TestVerificationCommands(t *testing.T) {
+	oldChecks := preCommitChecks
+	preCommitChecks = []string{"gofmt -l .", "go vet ./..."}
+	defer func() { preCommitChecks = oldChecks }()
+
+	commands := verificationCommands(Task{Commands: []string{"go build", "go test ./..."}})
+	if want := []string{"go build", "go test ./...", "gofmt -l .", "go vet ./..."}; !slices.Equal(commands, want) {
+		t.Errorf("verificationCommands() = %v, want %v", commands, want)
+	}
+
+	commands = verificationCommands(Task{})
+	if len(commands) != 2 {
+		t.Errorf("verificationCommands() = %v, want only the pre-commit checks", commands)
+	}
+
+	dir := initTestRepo(t)
+	logFile, err := os.CreateTemp(t.TempDir(), "sync-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = logFile.Close() }()
+
+	writeTestFile(t, dir, "ok.txt", "ok")
+	failed, err := runVerification([]string{"test -f ok.txt", "test -f missing.txt", "true"}, nil, logFile)
+	if err == nil || failed != "test -f missing.txt" {
+		t.Errorf("runVerification() = %q, %v; want failure of the second command", failed, err)
+	}
+}
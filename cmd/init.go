@@ -2,8 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"log"
 	"os"
+	"strings"
 
+	"github.com/isiidaisuke0926/sleepship/internal/config"
+	"github.com/isiidaisuke0926/sleepship/internal/tasktemplate"
 	"github.com/spf13/cobra"
 )
 
@@ -13,30 +17,124 @@ var initCmd = &cobra.Command{
 	Long: "Create a new task file with a template to help you get started.\n\n" +
 		"The template includes example tasks that demonstrate the proper format\n" +
 		"for defining tasks, implementation instructions, and verification commands.\n\n" +
-		"Example:\n" +
-		"  sleepship init tasks.txt",
-	Args: cobra.ExactArgs(1),
+		"Without a task file, default_task_file from .sleepship.toml is created,\n" +
+		"or tasks.txt if none is configured.\n\n" +
+		"--template selects a built-in template or one of your own templates in\n" +
+		".sleepship/templates or ~/.sleepship/templates (see sleepship template list).\n" +
+		"Templates may contain variables, written ${name} or ${name:-default},\n" +
+		"which are set with --var name=value. Write $$ for a literal $. A ${NAME}\n" +
+		"without a default in a verification command is left for the shell, unless\n" +
+		"the template uses it elsewhere.\n\n" +
+		"--interactive asks for the goal, tasks, verification command and scope,\n" +
+		"shows a preview and the problems found in it, and writes the task file once\n" +
+		"confirmed. When stdin is not a terminal, the answers are taken from --goal,\n" +
+		"--task, --scope and --verify instead; giving those flags without\n" +
+		"--interactive also creates the task file from them.\n\n" +
+		"Examples:\n" +
+		"  sleepship init tasks.txt\n" +
+		"  sleepship init\n" +
+		"  sleepship init --template go-library --var module=example.com/mylib\n" +
+		"  sleepship init fix-login.txt --template bug-fix --var bug=\"login fails after logout\"\n" +
+		"  sleepship init --interactive\n" +
+		"  sleepship init --goal \"Add rate limiting\" --task \"Add middleware: limit requests per IP\" --verify \"go test ./...\"",
+	Args: cobra.MaximumNArgs(1),
 	RunE: runInit,
 }
 
+var (
+	initTemplate string   // Template the task file is created from
+	initVars     []string // Template variables as name=value
+
+	initInteractive bool     // Author the task file with the wizard
+	initGoal        string   // Goal of the work, for the wizard
+	initScope       string   // Files or directories to change, for the wizard
+	initTasks       []string // Tasks as "title" or "title: description", for the wizard
+	initVerify      []string // Verification commands of every task, for the wizard
+)
+
 func init() {
 	rootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().StringVarP(&initTemplate, "template", "t", tasktemplate.DefaultName, "Template to create the task file from (see sleepship template list)")
+	initCmd.Flags().StringArrayVar(&initVars, "var", nil, "Template variable as name=value (repeatable)")
+	initCmd.Flags().BoolVarP(&initInteractive, "interactive", "i", false, "Author the task file by answering questions")
+	initCmd.Flags().StringVar(&initGoal, "goal", "", "Goal of the work (wizard)")
+	initCmd.Flags().StringVar(&initScope, "scope", "", "Files or directories the tasks may change (wizard)")
+	initCmd.Flags().StringArrayVar(&initTasks, "task", nil, `Task as "title" or "title: description" (wizard, repeatable)`)
+	initCmd.Flags().StringArrayVar(&initVerify, "verify", nil, "Verification command run after every task (wizard, repeatable)")
 }
 
-func runInit(_ *cobra.Command, args []string) error {
-	taskFile := args[0]
+func runInit(cmd *cobra.Command, args []string) error {
+	useWizard := initInteractive || initGoal != "" || len(initTasks) > 0
+	if useWizard && (cmd.Flags().Changed("template") || len(initVars) > 0) {
+		return fmt.Errorf("--template and --var cannot be used with --interactive, --goal or --task")
+	}
+
+	envConfig := config.LoadFromEnv()
+	contextConfig, _, err := config.LoadContextConfig()
+	if err != nil {
+		return err
+	}
+	// The config file layer is the one of the context's project, if set
+	runDir := config.MergeLayers(config.NewDefaultConfig(), config.FromEnv(envConfig), contextConfig).ProjectDir
+	fileConfig, err := config.LoadFileConfigIn(runDir)
+	if err != nil {
+		return fmt.Errorf("failed to load config file: %w", err)
+	}
+	mergedConfig := config.MergeLayers(config.NewDefaultConfig(), config.FromEnv(envConfig), contextConfig, config.FromFile(fileConfig))
+
+	taskFile, err := taskFileArg(args, envConfig, mergedConfig)
+	if err != nil {
+		return err
+	}
+	if taskFile == "" {
+		taskFile = "tasks.txt"
+	}
 
 	// Check if file already exists
 	if _, err := os.Stat(taskFile); err == nil {
 		return fmt.Errorf("file already exists: %s", taskFile)
 	}
 
+	var content, source string
+	if useWizard {
+		var p *prompter
+		if initInteractive && isTerminal(os.Stdin) {
+			p = newPrompter(os.Stdin, os.Stdout)
+		} else if initInteractive {
+			log.Printf("ℹ️  stdin is not a terminal, creating the task file from --goal, --task, --scope and --verify\n")
+		}
+		var write bool
+		content, write, err = authorTaskFile(p, os.Stdout, taskFile)
+		if err != nil {
+			return err
+		}
+		if !write {
+			fmt.Println("Cancelled, no task file written")
+			return nil
+		}
+		source = "wizard"
+	} else {
+		vars, err := parseTemplateVars(initVars)
+		if err != nil {
+			return err
+		}
+		tmpl, err := tasktemplate.Load(initTemplate, config.TemplateDirs())
+		if err != nil {
+			return err
+		}
+		if content, err = tmpl.Render(vars); err != nil {
+			return err
+		}
+		source = "template: " + tmpl.Name
+	}
+
 	// Write template to file
-	if err := os.WriteFile(taskFile, []byte(template), 0600); err != nil {
+	if err := os.WriteFile(taskFile, []byte(content), 0600); err != nil {
 		return fmt.Errorf("failed to create task file: %w", err)
 	}
 
-	fmt.Printf("✅ Task file created: %s\n", taskFile)
+	fmt.Printf("✅ Task file created: %s (%s)\n", taskFile, source)
 	fmt.Printf("\n📝 Next steps:\n")
 	fmt.Printf("  1. Edit the task file: %s\n", taskFile)
 	fmt.Printf("  2. Run: sleepship sync %s\n", taskFile)
@@ -44,71 +142,15 @@ func runInit(_ *cobra.Command, args []string) error {
 	return nil
 }
 
-const template = `# タスクファイル
-
-このファイルに実装したいタスクを記述します。
-Claude Codeが各タスクを順次実行します。
-
----
-
-## タスク1: プロジェクト初期化
-
-Go言語プロジェクトを初期化してください。
-
-### 実装
-以下を実行してください：
-- go mod init example-project でプロジェクトを初期化
-- 基本的なディレクトリ構造を作成（cmd/, internal/, pkg/）
-- .gitignore ファイルを作成
-
-### 確認
-- ` + "`go mod tidy`" + `
-- ` + "`ls -la`" + `
-
----
-
-## タスク2: HTTPサーバー実装
-
-基本的なHTTPサーバーを main.go に実装してください。
-
-### 実装
-main.go に以下の機能を実装：
-- ポート8080でHTTPサーバーを起動
-- "/" エンドポイントで "Hello, World!" を返す
-- "/health" エンドポイントでヘルスチェック（JSON形式）
-
-### 確認
-- ` + "`go build`" + `
-
----
-
-## タスク3: テスト追加
-
-HTTPハンドラーのユニットテストを追加してください。
-
-### 実装
-main_test.go を作成：
-- "/" エンドポイントのテスト
-- "/health" エンドポイントのテスト
-- レスポンスコードとボディの検証
-
-### 確認
-- ` + "`go test ./...`" + `
-- ` + "`go test -cover ./...`" + `
-
----
-
-## タスク4: README作成
-
-プロジェクトのREADME.mdを作成してください。
-
-### 実装
-README.md に以下を記載：
-- プロジェクトの説明
-- インストール方法
-- 使い方（実行コマンド）
-- エンドポイント一覧
-
-### 確認
-- ` + "`cat README.md`" + `
-`
+// parseTemplateVars parses template variables given as name=value.
+func parseTemplateVars(args []string) (map[string]string, error) {
+	vars := make(map[string]string, len(args))
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --var %q (expected name=value)", arg)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	inspectTask int
+	inspectList bool
+)
+
+var inspectFailureCmd = &cobra.Command{
+	Use:   "inspect-failure [run-id | ref]",
+	Short: "Show the diff of a preserved failed task attempt",
+	Long: `Show the changes of a failed task attempt preserved by --rollback=preserve.
+
+Failed attempts are stored under refs/sleepship/<run-id>/task-<N>-failed.
+Without arguments, the most recent failed attempt is shown.
+
+Examples:
+  sleepship inspect-failure                         # Show the latest failed attempt
+  sleepship inspect-failure --list                  # List all preserved failures
+  sleepship inspect-failure 20250101-020000.123     # Show a failure of a specific run
+  sleepship inspect-failure 20250101-020000.123 --task 3`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runInspectFailure,
+}
+
+func init() {
+	rootCmd.AddCommand(inspectFailureCmd)
+
+	inspectFailureCmd.Flags().StringVar(&projectDir, "dir", "", "Project directory (default: current directory)")
+	inspectFailureCmd.Flags().IntVar(&inspectTask, "task", 0, "Task number of the failed attempt to show")
+	inspectFailureCmd.Flags().BoolVar(&inspectList, "list", false, "List all preserved failed attempts")
+}
+
+func runInspectFailure(_ *cobra.Command, args []string) error {
+	if projectDir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		projectDir = cwd
+	}
+	absProjectDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve project directory: %w", err)
+	}
+	projectDir = absProjectDir
+
+	refs, err := listFailedRefs()
+	if err != nil {
+		return err
+	}
+
+	if inspectList {
+		if len(refs) == 0 {
+			fmt.Println("✅ No preserved failed attempts found")
+			return nil
+		}
+		fmt.Printf("💾 Preserved failed attempts (%d):\n\n", len(refs))
+		for _, ref := range refs {
+			fmt.Printf("  %s\n", ref)
+		}
+		return nil
+	}
+
+	query := ""
+	if len(args) > 0 {
+		query = args[0]
+	}
+	ref, err := selectFailedRef(refs, query, inspectTask)
+	if err != nil {
+		return err
+	}
+
+	message, err := runGit("log", "-1", "--format=%s%n%ci", ref)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("💾 %s\n%s\n\n", ref, message)
+
+	// Diff against the pre-task state, which is the parent of the preserved commit
+	for _, diffArgs := range [][]string{
+		{"diff", "--stat", ref + "^", ref},
+		{"diff", ref + "^", ref},
+	} {
+		diffCmd := exec.Command("git", diffArgs...)
+		diffCmd.Dir = projectDir
+		diffCmd.Stdout = os.Stdout
+		diffCmd.Stderr = os.Stderr
+		if err := diffCmd.Run(); err != nil {
+			return fmt.Errorf("failed to show diff: %w", err)
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
+
+// listFailedRefs returns all preserved failed attempts, newest first.
+func listFailedRefs() ([]string, error) {
+	output, err := runGit("for-each-ref", "--sort=-committerdate", "--format=%(refname)", failedRefPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list failed attempts: %w", err)
+	}
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+// selectFailedRef picks the ref matching a run ID or full ref name, and
+// optionally a task number. refs must be sorted newest first.
+func selectFailedRef(refs []string, query string, taskNum int) (string, error) {
+	if len(refs) == 0 {
+		return "", fmt.Errorf("no preserved failed attempts found (run sync with --rollback=preserve)")
+	}
+
+	for _, ref := range refs {
+		if query != "" && ref != query && !strings.HasPrefix(ref, failedRefPrefix+query+"/") {
+			continue
+		}
+		if taskNum > 0 && !strings.HasSuffix(ref, fmt.Sprintf("/task-%d-failed", taskNum)) {
+			continue
+		}
+		return ref, nil
+	}
+
+	if taskNum > 0 {
+		return "", fmt.Errorf("no preserved failed attempt found for %q task %d", query, taskNum)
+	}
+	return "", fmt.Errorf("no preserved failed attempt found for %q", query)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// lintProblem is a problem found in a task file by lintTaskFile.
+type lintProblem struct {
+	Task    int  // Task number, 0 for problems with the whole file
+	Error   bool // sync cannot run the task file
+	Message string
+}
+
+func (p lintProblem) String() string {
+	if p.Task == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("Task %d: %s", p.Task, p.Message)
+}
+
+// taskNumberPattern matches the task number that starts a task title
+var taskNumberPattern = regexp.MustCompile(`^\s*\d*\s*[:：]?\s*`)
+
+// lintTaskFile checks a task file for mistakes that make sync run it
+// differently than intended.
+func lintTaskFile(content string) ([]lintProblem, error) {
+	tasks, err := parseTasks(strings.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return []lintProblem{{Error: true, Message: `no tasks found (tasks start with "## タスク" or "## Task")`}}, nil
+	}
+
+	var problems []lintProblem
+	seen := make(map[string]int)
+	for i, task := range tasks {
+		number := i + 1
+		add := func(format string, args ...any) {
+			problems = append(problems, lintProblem{Task: number, Message: fmt.Sprintf(format, args...)})
+		}
+
+		title := strings.TrimSpace(taskNumberPattern.ReplaceAllString(task.Title, ""))
+		if title == "" {
+			add("has no title")
+		} else if first, ok := seen[title]; ok {
+			add("has the same title as task %d", first)
+		} else {
+			seen[title] = number
+		}
+		if strings.TrimSpace(task.Description) == "" {
+			add("has no instructions")
+		}
+		if len(task.Commands) == 0 {
+			add("has no verification command, so its changes are not checked")
+		}
+		for _, dep := range task.Depends {
+			if problem := dependencyProblem(number, dep, len(tasks)); problem != "" {
+				add("%s", problem)
+			}
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const pushAttempts = 3 // Number of attempts for a push that fails transiently
+
+// errNoRunBranch is returned by pushBranch when the run is not on a branch
+// it created or was given with --branch.
+var errNoRunBranch = errors.New("not on a branch created by sleepship or given with --branch, skipping push")
+
+// pushRetryDelay is the delay before the first push retry; it doubles on every retry.
+var pushRetryDelay = 2 * time.Second
+
+// permanentPushErrors are git push messages that retrying cannot fix.
+var permanentPushErrors = []string{
+	"[rejected]",
+	"non-fast-forward",
+	"stale info",
+	"does not appear to be a git repository",
+	"No such remote",
+	"Permission denied",
+	"Authentication failed",
+}
+
+// pushBranch pushes branch to remote and returns the pushed ref
+// (e.g. "origin/feature/foo"). Branches created by sleepship are pushed with
+// --force-with-lease, since sleepship may rewrite their history (for example
+// when rolling back a failed task). Other branches are never force-pushed.
+// Transient failures are retried with exponential backoff. Without a branch
+// nothing is pushed: the run did not check out a branch of its own, and
+// whatever is checked out, such as main, must not be pushed unattended.
+func pushBranch(remote, branch string, sleepshipBranch bool, logFile *os.File) (string, error) {
+	if branch == "" {
+		return "", errNoRunBranch
+	}
+
+	args := []string{"push", "--porcelain"}
+	if sleepshipBranch {
+		args = append(args, "--force-with-lease")
+	}
+	args = append(args, remote, fmt.Sprintf("refs/heads/%s:refs/heads/%s", branch, branch))
+
+	fmt.Printf("\n🚀 Pushing %s to %s\n", branch, remote)
+	_, _ = fmt.Fprintf(logFile, "\n=== Pushing %s to %s ===\n", branch, remote)
+
+	delay := pushRetryDelay
+	var lastErr error
+	for attempt := 1; attempt <= pushAttempts; attempt++ {
+		pushCmd := exec.Command("git", args...)
+		pushCmd.Dir = projectDir
+		output, err := pushCmd.CombinedOutput()
+		_, _ = logFile.Write(output)
+
+		if err == nil {
+			pushedRef := fmt.Sprintf("%s/%s", remote, branch)
+			fmt.Printf("✅ Pushed: %s\n", pushedRef)
+			return pushedRef, nil
+		}
+
+		lastErr = fmt.Errorf("failed to push: %w\nOutput: %s", err, string(output))
+		if isPermanentPushError(string(output)) || attempt == pushAttempts {
+			break
+		}
+
+		log.Printf("⚠️ Push failed (attempt %d/%d), retrying in %s\n", attempt, pushAttempts, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	return "", lastErr
+}
+
+// isPermanentPushError reports whether git push output describes a failure
+// that retrying cannot fix.
+func isPermanentPushError(output string) bool {
+	for _, msg := range permanentPushErrors {
+		if strings.Contains(output, msg) {
+			return true
+		}
+	}
+	return false
+}
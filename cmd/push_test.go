@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// initTestRemote creates a bare repository and registers it as the origin
+// remote of the repository in dir.
+func initTestRemote(t *testing.T, dir string) string {
+	t.Helper()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	if output, err := exec.Command("git", "init", "-q", "--bare", remoteDir).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare failed: %v\n%s", err, output)
+	}
+	gitOutput(t, dir, "remote", "add", "origin", remoteDir)
+	return remoteDir
+}
+
+func TestPushBranch(t *testing.T) {
+	oldDelay := pushRetryDelay
+	pushRetryDelay = 0
+	defer func() { pushRetryDelay = oldDelay }()
+
+	dir := initTestRepo(t)
+	remoteDir := initTestRemote(t, dir)
+	logFile, err := os.CreateTemp(t.TempDir(), "sync-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = logFile.Close() }()
+
+	gitOutput(t, dir, "checkout", "-q", "-b", "feature/push-test")
+	writeTestFile(t, dir, "a.txt", "a")
+	gitOutput(t, dir, "add", ".")
+	gitOutput(t, dir, "commit", "-q", "-m", "task 1")
+
+	t.Run("push sleepship branch", func(t *testing.T) {
+		ref, err := pushBranch("origin", "feature/push-test", true, logFile)
+		if err != nil {
+			t.Fatalf("pushBranch() error = %v", err)
+		}
+		if ref != "origin/feature/push-test" {
+			t.Errorf("pushBranch() = %q, want origin/feature/push-test", ref)
+		}
+		if got, want := gitOutput(t, remoteDir, "rev-parse", "feature/push-test"), gitOutput(t, dir, "rev-parse", "HEAD"); got != want {
+			t.Errorf("remote branch = %s, want %s", got, want)
+		}
+	})
+
+	// Rewrite history as a rollback would
+	gitOutput(t, dir, "commit", "-q", "--amend", "-m", "task 1 (rewritten)")
+
+	t.Run("force-with-lease on sleepship branch", func(t *testing.T) {
+		if _, err := pushBranch("origin", "feature/push-test", true, logFile); err != nil {
+			t.Fatalf("pushBranch() error = %v", err)
+		}
+		if got, want := gitOutput(t, remoteDir, "rev-parse", "feature/push-test"), gitOutput(t, dir, "rev-parse", "HEAD"); got != want {
+			t.Errorf("remote branch = %s, want %s", got, want)
+		}
+	})
+
+	gitOutput(t, dir, "commit", "-q", "--amend", "-m", "task 1 (rewritten again)")
+
+	t.Run("no force on other branches", func(t *testing.T) {
+		if _, err := pushBranch("origin", "feature/push-test", false, logFile); err == nil {
+			t.Error("pushBranch() expected non-fast-forward rejection")
+		}
+	})
+
+	t.Run("unknown remote", func(t *testing.T) {
+		if _, err := pushBranch("missing", "feature/push-test", true, logFile); err == nil {
+			t.Error("pushBranch() expected error for unknown remote")
+		}
+	})
+}
+
+func TestPushWithoutRunBranch(t *testing.T) {
+	dir := initTestRepo(t)
+	remoteDir := initTestRemote(t, dir)
+	logFile, err := os.CreateTemp(t.TempDir(), "sync-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = logFile.Close() }()
+
+	// The feature branch already exists, so creating it fails and the run
+	// stays on the branch that was checked out
+	gitOutput(t, dir, "branch", "feature/tasks")
+	branch, created := setUpRunBranch("tasks.txt", logFile)
+	if branch != "" || created {
+		t.Fatalf("setUpRunBranch() = %q, %v, want no branch", branch, created)
+	}
+
+	if _, err := pushBranch("origin", branch, created, logFile); !errors.Is(err, errNoRunBranch) {
+		t.Errorf("pushBranch() error = %v, want errNoRunBranch", err)
+	}
+	if refs := gitOutput(t, remoteDir, "for-each-ref"); refs != "" {
+		t.Errorf("remote refs = %q, want nothing pushed", refs)
+	}
+}
+
+func TestIsPermanentPushError(t *testing.T) {
+	if !isPermanentPushError(" ! [rejected] main -> main (non-fast-forward)") {
+		t.Error("non-fast-forward rejection should be permanent")
+	}
+	if isPermanentPushError("fatal: unable to access 'https://example.com/': Could not resolve host") {
+		t.Error("network errors should be retried")
+	}
+}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/isiidaisuke0926/sleepship/internal/history"
+)
+
+// Task result statuses
+const (
+	taskSucceeded = history.TaskSucceeded
+	taskFailed    = history.TaskFailed
+	taskSkipped   = history.TaskSkipped
+)
+
+// maxRecordedErrorLen limits the length of error output stored in history
+const maxRecordedErrorLen = 500
+
+// taskResult records the outcome of a single task in a sync run.
+type taskResult struct {
+	Number int
+	Title  string
+	Status string
+	Reason string // Error message for failed tasks, skip reason for skipped tasks
+
+	Attempts       int // Number of agent calls
+	AgentExitCodes []int
+	Verifications  []history.VerificationRecord
+	Duration       time.Duration
+	CommitSHA      string
+	Usage          history.Usage   // Total agent usage of the task
+	AttemptUsage   []history.Usage // Agent usage of each call
+}
+
+// recordAgentCall records the outcome and usage of an agent call made for the task.
+func (r *taskResult) recordAgentCall(usage history.Usage, err error) {
+	if r == nil {
+		return
+	}
+	r.Attempts++
+	r.AgentExitCodes = append(r.AgentExitCodes, exitCode(err))
+	r.AttemptUsage = append(r.AttemptUsage, usage)
+	r.Usage.Add(usage)
+}
+
+// recordVerification records the outcome of a verification command.
+func (r *taskResult) recordVerification(command string, err error) {
+	if r == nil {
+		return
+	}
+	record := history.VerificationRecord{Command: command, Passed: err == nil}
+	if err != nil {
+		record.Error = truncate(err.Error(), maxRecordedErrorLen)
+	}
+	r.Verifications = append(r.Verifications, record)
+}
+
+// toRecord converts the result into a history task record. Usage is only
+// recorded if the agent reported any.
+func (r *taskResult) toRecord() history.TaskRecord {
+	var attemptUsage []history.Usage
+	if !r.Usage.IsZero() {
+		attemptUsage = r.AttemptUsage
+	}
+
+	return history.TaskRecord{
+		Number:         r.Number,
+		Title:          r.Title,
+		Status:         r.Status,
+		Attempts:       r.Attempts,
+		Verifications:  r.Verifications,
+		Duration:       r.Duration,
+		CommitSHA:      r.CommitSHA,
+		AgentExitCodes: r.AgentExitCodes,
+		Error:          truncate(r.Reason, maxRecordedErrorLen),
+		Usage:          usageRecord(r.Usage),
+		AttemptUsage:   attemptUsage,
+	}
+}
+
+// taskRecords converts results into history task records.
+func taskRecords(results []taskResult) []history.TaskRecord {
+	records := make([]history.TaskRecord, 0, len(results))
+	for i := range results {
+		records = append(records, results[i].toRecord())
+	}
+	return records
+}
+
+// exitCode returns the exit code of a command error: 0 for success, the
+// process exit code if it ran, and -1 if it could not be started.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n - 3
+	for cut > 0 && !utf8RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
+
+// utf8RuneStart reports whether b is the first byte of a UTF-8 encoded rune.
+func utf8RuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// blockingDependency returns the number of the first dependency of task that
+// was attempted in this run but did not succeed, or 0 if the task can run.
+// Dependencies skipped via --start-from are assumed to be done already.
+func blockingDependency(task Task, results []taskResult) int {
+	for _, dep := range task.Depends {
+		for _, result := range results {
+			if result.Number == dep && result.Status != taskSucceeded {
+				return dep
+			}
+		}
+	}
+	return 0
+}
+
+// checkDependencies returns an error for the first dependency that is not
+// on an earlier task of the task file. Such a task would otherwise run as if
+// its dependency had succeeded.
+func checkDependencies(tasks []Task) error {
+	for i, task := range tasks {
+		for _, dep := range task.Depends {
+			if problem := dependencyProblem(i+1, dep, len(tasks)); problem != "" {
+				return fmt.Errorf("task %d %s", i+1, problem)
+			}
+		}
+	}
+	return nil
+}
+
+// dependencyProblem describes why task number cannot depend on task dep, or
+// returns an empty string if it can.
+func dependencyProblem(number, dep, taskCount int) string {
+	switch {
+	case dep < 1 || dep > taskCount:
+		return fmt.Sprintf("depends on task %d, which does not exist", dep)
+	case dep == number:
+		return "depends on itself"
+	case dep > number:
+		return fmt.Sprintf("depends on task %d, which runs after it", dep)
+	}
+	return ""
+}
+
+// countResults returns the number of results with the given status.
+func countResults(results []taskResult, status string) int {
+	count := 0
+	for _, result := range results {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// summarizeFailures returns a one-line description of failed and skipped
+// tasks for the history entry, or an empty string if every task succeeded.
+func summarizeFailures(results []taskResult) string {
+	var failed, skipped []string
+	for _, result := range results {
+		switch result.Status {
+		case taskFailed:
+			failed = append(failed, fmt.Sprintf("task %d: %s", result.Number, result.Reason))
+		case taskSkipped:
+			skipped = append(skipped, fmt.Sprintf("%d", result.Number))
+		}
+	}
+
+	if len(failed) == 0 {
+		return ""
+	}
+
+	summary := fmt.Sprintf("%d of %d tasks failed (%s)", len(failed), len(results), strings.Join(failed, "; "))
+	if len(skipped) > 0 {
+		summary += fmt.Sprintf("; skipped tasks: %s", strings.Join(skipped, ", "))
+	}
+	return summary
+}
+
+// statusIcon returns the icon used to display a task result status.
+func statusIcon(status string) string {
+	switch status {
+	case taskSucceeded:
+		return "✅"
+	case taskFailed:
+		return "❌"
+	default:
+		return "⏭️"
+	}
+}
+
+// writeTaskSummary prints the per-task summary table to stdout and the log file.
+func writeTaskSummary(results []taskResult, logFile *os.File) {
+	var summary strings.Builder
+
+	summary.WriteString("========================================\n")
+	summary.WriteString("📊 Task Summary\n")
+	summary.WriteString("========================================\n")
+	summary.WriteString(fmt.Sprintf("%-4s %-12s %s\n", "#", "Status", "Task"))
+	summary.WriteString(strings.Repeat("-", 60) + "\n")
+
+	for _, result := range results {
+		summary.WriteString(fmt.Sprintf("%-4d %s %-9s %s\n", result.Number, statusIcon(result.Status), result.Status, result.Title))
+		if result.Reason != "" {
+			reason := truncate(result.Reason, 100)
+			summary.WriteString(fmt.Sprintf("     ↳ %s\n", reason))
+		}
+	}
+
+	summary.WriteString(fmt.Sprintf("\nSucceeded: %d | Failed: %d | Skipped: %d\n\n",
+		countResults(results, taskSucceeded), countResults(results, taskFailed), countResults(results, taskSkipped)))
+
+	fmt.Print(summary.String())
+	_, _ = logFile.WriteString(summary.String())
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/isiidaisuke0926/sleepship/internal/history"
+)
+
+func TestBlockingDependency(t *testing.T) {
+	results := []taskResult{
+		{Number: 2, Status: taskSucceeded},
+		{Number: 3, Status: taskFailed},
+		{Number: 4, Status: taskSkipped},
+	}
+
+	tests := []struct {
+		name    string
+		depends []int
+		want    int
+	}{
+		{name: "no dependencies", depends: nil, want: 0},
+		{name: "dependency skipped via start-from", depends: []int{1}, want: 0},
+		{name: "dependency succeeded", depends: []int{2}, want: 0},
+		{name: "dependency failed", depends: []int{2, 3}, want: 3},
+		{name: "dependency skipped after failure", depends: []int{4}, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := blockingDependency(Task{Depends: tt.depends}, results)
+			if got != tt.want {
+				t.Errorf("blockingDependency() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeFailures(t *testing.T) {
+	if got := summarizeFailures([]taskResult{{Number: 1, Status: taskSucceeded}}); got != "" {
+		t.Errorf("summarizeFailures() = %q, want empty", got)
+	}
+
+	results := []taskResult{
+		{Number: 1, Status: taskSucceeded},
+		{Number: 2, Status: taskFailed, Reason: "verification failed"},
+		{Number: 3, Status: taskSkipped, Reason: "depends on task 2"},
+	}
+	want := "1 of 3 tasks failed (task 2: verification failed); skipped tasks: 3"
+	if got := summarizeFailures(results); got != want {
+		t.Errorf("summarizeFailures() = %q, want %q", got, want)
+	}
+}
+
+func TestGeneratePRBodyResults(t *testing.T) {
+	tasks := []Task{{Title: "1: First"}, {Title: "2: Second"}}
+
+	body := generatePRBody(tasks, []taskResult{
+		{Number: 1, Title: "1: First", Status: taskSucceeded},
+		{Number: 2, Title: "2: Second", Status: taskFailed, Reason: "a | b"},
+	})
+	if !strings.Contains(body, "## 実行結果") {
+		t.Errorf("PR body should contain the result table:\n%s", body)
+	}
+	if !strings.Contains(body, `a \| b`) {
+		t.Errorf("PR body should escape table separators:\n%s", body)
+	}
+
+	body = generatePRBody(tasks, []taskResult{{Number: 1, Status: taskSucceeded}})
+	if strings.Contains(body, "## 実行結果") {
+		t.Errorf("PR body should not contain the result table when all tasks succeeded:\n%s", body)
+	}
+}
+
+func TestGeneratePRBodyCommands(t *testing.T) {
+	tasks := []Task{
+		{Title: "1: First", Commands: []string{"go build ./...", "go test ./..."}},
+		{Title: "2: Second", Commands: []string{"go vet ./...", "go test ./..."}},
+	}
+
+	body := generatePRBody(tasks, nil)
+	want := "- `go build ./...`\n- `go test ./...`\n- `go vet ./...`\n"
+	if !strings.Contains(body, want) {
+		t.Errorf("PR body should list every command once, in order:\n%s", body)
+	}
+}
+
+func TestTaskResultRecords(t *testing.T) {
+	result := &taskResult{Number: 2, Title: "Build", Status: taskFailed, Reason: strings.Repeat("x", 600)}
+	result.recordAgentCall(history.Usage{InputTokens: 100, OutputTokens: 10, CostUSD: 0.5, Models: []string{"sonnet"}}, nil)
+	result.recordAgentCall(history.Usage{InputTokens: 50, CostUSD: 0.25, Models: []string{"haiku", "sonnet"}}, exec.Command("sh", "-c", "exit 3").Run())
+	result.recordAgentCall(history.Usage{}, exec.Command("sleepship-missing-command").Run())
+	result.recordVerification("go test ./...", errors.New("exit status 1"))
+	result.recordVerification("go vet ./...", nil)
+
+	record := taskRecords([]taskResult{*result})[0]
+	if record.Attempts != 3 {
+		t.Errorf("Attempts = %d, want 3", record.Attempts)
+	}
+	if got := fmt.Sprint(record.AgentExitCodes); got != "[0 3 -1]" {
+		t.Errorf("AgentExitCodes = %s, want [0 3 -1]", got)
+	}
+	if len(record.Verifications) != 2 || record.Verifications[0].Passed || !record.Verifications[1].Passed {
+		t.Errorf("Verifications = %+v", record.Verifications)
+	}
+	if record.Usage == nil || record.Usage.InputTokens != 150 || record.Usage.CostUSD != 0.75 || fmt.Sprint(record.Usage.Models) != "[haiku sonnet]" {
+		t.Errorf("Usage = %+v, want 150 input tokens, $0.75, [haiku sonnet]", record.Usage)
+	}
+	if len(record.AttemptUsage) != 3 {
+		t.Errorf("AttemptUsage = %+v, want one entry per agent call", record.AttemptUsage)
+	}
+	if len(record.Error) != maxRecordedErrorLen {
+		t.Errorf("Error length = %d, want %d", len(record.Error), maxRecordedErrorLen)
+	}
+
+	// Recording on a nil result is a no-op
+	var none *taskResult
+	none.recordAgentCall(history.Usage{}, nil)
+
+	// Usage is omitted when the agent reported none
+	legacy := &taskResult{Number: 1}
+	legacy.recordAgentCall(history.Usage{}, nil)
+	if record := legacy.toRecord(); record.Usage != nil || record.AttemptUsage != nil {
+		t.Errorf("toRecord() without usage = %+v", record)
+	}
+	none.recordVerification("true", nil)
+}
+
+func TestCheckDependencies(t *testing.T) {
+	tests := []struct {
+		name    string
+		depends [][]int
+		wantErr string
+	}{
+		{name: "earlier tasks", depends: [][]int{nil, {1}, {1, 2}}},
+		{name: "forward dependency", depends: [][]int{nil, {3}, nil}, wantErr: "task 2 depends on task 3, which runs after it"},
+		{name: "missing task", depends: [][]int{nil, {5}, nil}, wantErr: "task 2 depends on task 5, which does not exist"},
+		{name: "task zero", depends: [][]int{{0}}, wantErr: "task 1 depends on task 0, which does not exist"},
+		{name: "itself", depends: [][]int{nil, {2}}, wantErr: "task 2 depends on itself"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := make([]Task, len(tt.depends))
+			for i, deps := range tt.depends {
+				tasks[i].Depends = deps
+			}
+			err := checkDependencies(tasks)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkDependencies() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("checkDependencies() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/isiidaisuke0926/sleepship/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rerunFromFailed bool
+	rerunResume     bool
+	rerunStartFrom  int
+	rerunDryRun     bool
+
+	// Budgets that replace the recorded ones
+	rerunMaxDuration   string
+	rerunMaxAgentCalls int
+	rerunMaxCost       float64
+)
+
+var rerunCmd = &cobra.Command{
+	Use:   "rerun <id>",
+	Short: "Run a past execution again",
+	Long: `Run a past execution again with the same task file, project directory,
+options and branch.
+
+The task file is restored from the snapshot taken when the execution ran,
+so later edits to the task file do not affect the rerun. Executions recorded
+before run archives existed cannot be rerun.
+
+An execution that was stopped by a run budget or a failed task can be
+resumed with --resume, which continues from the task it stopped at. Pass
+--max-duration, --max-agent-calls or --max-cost to raise the recorded budgets.
+
+The execution is identified by its run ID or position, as in
+"sleepship history show".
+
+Examples:
+  sleepship rerun 20250101-020000.123               # Run the whole task file again
+  sleepship rerun 20250101-020000.123 --from-failed # Continue from the failed task
+  sleepship rerun 20250101-020000.123 --resume --max-cost 20
+  sleepship rerun 3 --start-from 4
+  sleepship rerun 20250101-020000.123 --dry-run     # Show the sync command only`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRerun,
+}
+
+func init() {
+	rootCmd.AddCommand(rerunCmd)
+
+	rerunCmd.Flags().StringVar(&projectDir, "dir", "", "Project directory whose history is searched (default: current directory)")
+	rerunCmd.Flags().BoolVar(&historyAllProjects, "all-projects", false, "Search executions of every project sleepship has run in")
+	rerunCmd.Flags().BoolVar(&rerunFromFailed, "from-failed", false, "Start from the task that failed")
+	rerunCmd.Flags().BoolVar(&rerunResume, "resume", false, "Continue from the task the execution stopped at")
+	rerunCmd.Flags().IntVar(&rerunStartFrom, "start-from", 1, "Start from specified task number")
+	rerunCmd.Flags().BoolVar(&rerunDryRun, "dry-run", false, "Show the sync command without running it")
+	rerunCmd.Flags().StringVar(&rerunMaxDuration, "max-duration", "", "Run time budget instead of the recorded one, e.g. 8h (0 = unlimited)")
+	rerunCmd.Flags().IntVar(&rerunMaxAgentCalls, "max-agent-calls", 0, "Agent call budget instead of the recorded one (0 = unlimited)")
+	rerunCmd.Flags().Float64Var(&rerunMaxCost, "max-cost", 0, "Cost budget in USD instead of the recorded one (0 = unlimited)")
+}
+
+func runRerun(cmd *cobra.Command, args []string) error {
+	starts := 0
+	for _, flag := range []string{"from-failed", "resume", "start-from"} {
+		if cmd.Flags().Changed(flag) {
+			starts++
+		}
+	}
+	if starts > 1 {
+		return fmt.Errorf("only one of --from-failed, --resume and --start-from can be used")
+	}
+
+	hist, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	entry, err := hist.Find(args[0])
+	if err != nil {
+		return err
+	}
+
+	dir, err := entryProjectDir(entry)
+	if err != nil {
+		return err
+	}
+
+	taskFile, err := history.FindSnapshot(dir, *entry)
+	if err != nil {
+		return err
+	}
+
+	start := rerunStartFrom
+	switch {
+	case rerunFromFailed:
+		if start, err = failedTaskNumber(entry); err != nil {
+			return err
+		}
+	case rerunResume:
+		if start, err = resumeTaskNumber(entry); err != nil {
+			return err
+		}
+	}
+
+	// Budget flags go last so that they replace the recorded budgets
+	syncArgs := rerunSyncArgs(entry, dir, start)
+	if cmd.Flags().Changed("max-duration") {
+		syncArgs = append(syncArgs, "--max-duration", rerunMaxDuration)
+	}
+	if cmd.Flags().Changed("max-agent-calls") {
+		syncArgs = append(syncArgs, "--max-agent-calls", strconv.Itoa(rerunMaxAgentCalls))
+	}
+	if cmd.Flags().Changed("max-cost") {
+		syncArgs = append(syncArgs, "--max-cost", strconv.FormatFloat(rerunMaxCost, 'f', -1, 64))
+	}
+	fmt.Printf("🔁 Rerunning %s: sleepship sync %s %s\n", valueOrDash(entry.ID), taskFile, strings.Join(syncArgs, " "))
+	if rerunDryRun {
+		return nil
+	}
+
+	if err := syncCmd.ParseFlags(syncArgs); err != nil {
+		return fmt.Errorf("failed to restore options: %w", err)
+	}
+	// The snapshot is only read: the rerun is recorded under the original
+	// task file, so that it shows up with the runs of that task file
+	recordedTaskFile = entry.TaskFile
+	return runSync(syncCmd, []string{taskFile})
+}
+
+// rerunSyncArgs returns the sync flags that reproduce an execution.
+// Executions recorded without options only restore the retry limit and branch.
+func rerunSyncArgs(entry *history.Entry, dir string, start int) []string {
+	args := []string{
+		"--dir", dir,
+		"--start-from", strconv.Itoa(start),
+		"--max-retries", strconv.Itoa(entry.MaxRetries),
+	}
+	if entry.BranchName != "" {
+		args = append(args, "--branch", entry.BranchName)
+	}
+
+	if opts := entry.Options; opts != nil {
+		if opts.LogDir != "" {
+			args = append(args, "--log-dir", opts.LogDir)
+		}
+		if opts.CommitStrategy != "" {
+			args = append(args, "--commit-strategy", opts.CommitStrategy)
+		}
+		if opts.RollbackPolicy != "" {
+			args = append(args, "--rollback", opts.RollbackPolicy)
+		}
+		if opts.KeepGoing {
+			args = append(args, "--keep-going")
+		}
+		for _, check := range opts.PreCommitChecks {
+			args = append(args, "--pre-commit-check", check)
+		}
+		if opts.Push != "" {
+			args = append(args, "--push", opts.Push)
+		}
+		if opts.PushRemote != "" {
+			args = append(args, "--push-remote", opts.PushRemote)
+		}
+		if opts.BranchBase != "" {
+			args = append(args, "--branch-base", opts.BranchBase)
+		}
+		if opts.MaxDuration != "" {
+			args = append(args, "--max-duration", opts.MaxDuration)
+		}
+		if opts.MaxAgentCalls > 0 {
+			args = append(args, "--max-agent-calls", strconv.Itoa(opts.MaxAgentCalls))
+		}
+		if opts.MaxCost > 0 {
+			args = append(args, "--max-cost", strconv.FormatFloat(opts.MaxCost, 'f', -1, 64))
+		}
+	}
+
+	return args
+}
+
+// failedTaskNumber returns the number of the first failed task of an execution.
+func failedTaskNumber(entry *history.Entry) (int, error) {
+	for _, task := range entry.Tasks {
+		if task.Status == history.TaskFailed {
+			return task.Number, nil
+		}
+	}
+	if entry.Success {
+		return 0, fmt.Errorf("execution %s did not fail", valueOrDash(entry.ID))
+	}
+	return 0, fmt.Errorf("no failed task recorded for execution %s", valueOrDash(entry.ID))
+}
+
+// resumeTaskNumber returns the task an execution stopped at. Executions
+// recorded before stop points existed resume from their failed task.
+func resumeTaskNumber(entry *history.Entry) (int, error) {
+	if entry.NextTask > 0 {
+		return entry.NextTask, nil
+	}
+	if entry.Success {
+		return 0, fmt.Errorf("execution %s ran to completion", valueOrDash(entry.ID))
+	}
+	return failedTaskNumber(entry)
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/isiidaisuke0926/sleepship/internal/config"
+	"github.com/isiidaisuke0926/sleepship/internal/history"
+)
+
+func TestRerunSyncArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry history.Entry
+		start int
+		want  []string
+	}{
+		{
+			name:  "legacy entry",
+			entry: history.Entry{MaxRetries: 3},
+			start: 1,
+			want:  []string{"--dir", "/proj", "--start-from", "1", "--max-retries", "3"},
+		},
+		{
+			name: "recorded options",
+			entry: history.Entry{
+				MaxRetries: 0,
+				BranchName: "feature/tasks",
+				Options: &history.RunOptions{
+					LogDir:          "out",
+					CommitStrategy:  "squash",
+					RollbackPolicy:  "reset",
+					KeepGoing:       true,
+					PreCommitChecks: []string{"go vet ./...", "go test ./..."},
+					Push:            "end",
+					PushRemote:      "origin",
+					BranchBase:      "origin/main",
+				},
+			},
+			start: 2,
+			want: []string{
+				"--dir", "/proj", "--start-from", "2", "--max-retries", "0",
+				"--branch", "feature/tasks", "--log-dir", "out",
+				"--commit-strategy", "squash", "--rollback", "reset", "--keep-going",
+				"--pre-commit-check", "go vet ./...", "--pre-commit-check", "go test ./...",
+				"--push", "end", "--push-remote", "origin", "--branch-base", "origin/main",
+			},
+		},
+		{
+			name: "recorded budgets",
+			entry: history.Entry{
+				MaxRetries: 3,
+				Options:    &history.RunOptions{MaxDuration: "8h", MaxAgentCalls: 50, MaxCost: 12.5},
+			},
+			start: 4,
+			want: []string{
+				"--dir", "/proj", "--start-from", "4", "--max-retries", "3",
+				"--max-duration", "8h", "--max-agent-calls", "50", "--max-cost", "12.5",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rerunSyncArgs(&tt.entry, "/proj", tt.start)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("rerunSyncArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArchiveRerunSnapshot(t *testing.T) {
+	oldProjectDir, oldRecordDir := projectDir, recordDir
+	defer func() { projectDir, recordDir = oldProjectDir, oldRecordDir }()
+	projectDir = t.TempDir()
+	recordDir = projectDir
+
+	// The first run archives the task file, the rerun reads the snapshot
+	writeTestFile(t, projectDir, "tasks/nightly.txt", "## タスク1: Build\n")
+	hash, err := archiveRun(filepath.Join(projectDir, "tasks/nightly.txt"), "tasks/nightly.txt", config.NewDefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := history.FindSnapshot(projectDir, history.Entry{RunHash: hash, TaskFile: "tasks/nightly.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "tasks/nightly.txt"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rerunHash, err := archiveRun(snapshot, "tasks/nightly.txt", config.NewDefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := history.LoadArchive(projectDir, history.Entry{RunHash: rerunHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archive.TaskFile != "tasks/nightly.txt" {
+		t.Errorf("archived task file = %q, want tasks/nightly.txt", archive.TaskFile)
+	}
+	rerunSnapshot, err := history.FindSnapshot(projectDir, history.Entry{RunHash: rerunHash, TaskFile: archive.TaskFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(rerunSnapshot); string(content) != "## タスク1: Build\n" {
+		t.Errorf("rerun snapshot = %q, want the content of the first run", content)
+	}
+}
+
+func TestFailedTaskNumber(t *testing.T) {
+	entry := &history.Entry{Tasks: []history.TaskRecord{
+		{Number: 1, Status: history.TaskSucceeded},
+		{Number: 2, Status: history.TaskFailed},
+		{Number: 3, Status: history.TaskSkipped},
+	}}
+	if got, err := failedTaskNumber(entry); err != nil || got != 2 {
+		t.Errorf("failedTaskNumber() = %d, %v, want 2", got, err)
+	}
+
+	if _, err := failedTaskNumber(&history.Entry{Success: true}); err == nil {
+		t.Error("failedTaskNumber() on a successful execution should fail")
+	}
+}
+
+func TestResumeTaskNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   history.Entry
+		want    int
+		wantErr bool
+	}{
+		{name: "stopped by budget", entry: history.Entry{StopReason: history.StopMaxCost, NextTask: 4}, want: 4},
+		{
+			name: "recorded before stop points",
+			entry: history.Entry{Tasks: []history.TaskRecord{
+				{Number: 1, Status: history.TaskSucceeded},
+				{Number: 2, Status: history.TaskFailed},
+			}},
+			want: 2,
+		},
+		{name: "completed", entry: history.Entry{Success: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resumeTaskNumber(&tt.entry)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resumeTaskNumber() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resumeTaskNumber() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/isiidaisuke0926/sleepship/internal/config"
+)
+
+// failedRefPrefix is the ref namespace under which failed task attempts are preserved.
+const failedRefPrefix = "refs/sleepship/"
+
+// checkpoint records the repository state before a task starts so that a
+// failed task can be rolled back to it.
+type checkpoint struct {
+	Head string // Commit checked out before the task started
+	Tree string // Tree object capturing the whole working tree before the task started
+}
+
+// runGit runs a git command in the project directory and returns its trimmed output.
+func runGit(args ...string) (string, error) {
+	return runGitEnv(nil, args...)
+}
+
+// runGitEnv runs a git command with additional environment variables.
+func runGitEnv(env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = projectDir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w\nOutput: %s", strings.Join(args, " "), err, stderr.String())
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// createCheckpoint records HEAD and a snapshot of the working tree.
+func createCheckpoint() (*checkpoint, error) {
+	head, err := runGit("rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	tree, err := snapshotWorkingTree()
+	if err != nil {
+		return nil, err
+	}
+
+	return &checkpoint{Head: head, Tree: tree}, nil
+}
+
+// snapshotWorkingTree writes the current working tree, including untracked
+// files, to a git tree object without touching the real index. The log
+// directory and sleepship's own state directory are excluded so that rolling
+// back never rewrites the files sleepship is writing to.
+func snapshotWorkingTree() (string, error) {
+	indexFile, err := os.CreateTemp("", "sleepship-index-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	indexPath := indexFile.Name()
+	_ = indexFile.Close()
+	// git refuses to read an empty index file, so let it create a fresh one
+	_ = os.Remove(indexPath)
+	defer func() { _ = os.Remove(indexPath) }()
+
+	env := []string{"GIT_INDEX_FILE=" + indexPath}
+	addArgs := append([]string{"add", "-A", "--", "."}, snapshotExcludes()...)
+	if _, err := runGitEnv(env, addArgs...); err != nil {
+		return "", fmt.Errorf("failed to snapshot working tree: %w", err)
+	}
+
+	tree, err := runGitEnv(env, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to snapshot working tree: %w", err)
+	}
+	return tree, nil
+}
+
+// snapshotExcludes returns pathspecs for directories that snapshots must ignore.
+func snapshotExcludes() []string {
+	excludes := []string{":(exclude).sleepship"}
+	if rel := filepath.ToSlash(filepath.Clean(logDir)); rel != "." && !strings.HasPrefix(rel, "..") {
+		excludes = append(excludes, ":(exclude)"+strings.TrimPrefix(rel, "/"))
+	}
+	return excludes
+}
+
+// failedTaskRef returns the ref under which a failed task attempt is preserved.
+func failedTaskRef(runID string, taskNum int) string {
+	return fmt.Sprintf("%s%s/task-%d-failed", failedRefPrefix, runID, taskNum)
+}
+
+// rollbackTask applies the rollback policy to a task that exhausted its
+// retries. It returns the ref of the preserved attempt, if any.
+func rollbackTask(cp *checkpoint, runID string, taskNum int, task Task, policy string, logFile *os.File) (string, error) {
+	if policy == config.RollbackLeave || cp == nil {
+		return "", nil
+	}
+
+	_, _ = fmt.Fprintf(logFile, "\n=== Rolling Back Task %d (%s) ===\n", taskNum, policy)
+
+	failedTree, err := snapshotWorkingTree()
+	if err != nil {
+		return "", err
+	}
+
+	var preservedRef string
+	if policy == config.RollbackPreserve && failedTree != cp.Tree {
+		preservedRef, err = preserveFailedAttempt(cp, failedTree, runID, taskNum, task)
+		if err != nil {
+			return "", err
+		}
+		fmt.Printf("💾 Failed attempt preserved: %s\n", preservedRef)
+		_, _ = fmt.Fprintf(logFile, "Failed attempt preserved: %s\n", preservedRef)
+	}
+
+	if err := restoreCheckpoint(cp, failedTree); err != nil {
+		return preservedRef, err
+	}
+
+	fmt.Printf("⏪ Task %d rolled back to its pre-task state\n", taskNum)
+	_, _ = fmt.Fprintf(logFile, "Rolled back to %s\n", cp.Head)
+	return preservedRef, nil
+}
+
+// preserveFailedAttempt stores the failed working tree as a commit whose
+// parent is the pre-task state, and points a named ref at it.
+func preserveFailedAttempt(cp *checkpoint, failedTree, runID string, taskNum int, task Task) (string, error) {
+	baseCommit, err := runGit("commit-tree", cp.Tree, "-p", cp.Head, "-m", fmt.Sprintf("sleepship: state before task %d", taskNum))
+	if err != nil {
+		return "", fmt.Errorf("failed to record pre-task state: %w", err)
+	}
+
+	failedCommit, err := runGit("commit-tree", failedTree, "-p", baseCommit, "-m", fmt.Sprintf("sleepship: failed attempt of task %d: %s", taskNum, task.Title))
+	if err != nil {
+		return "", fmt.Errorf("failed to record failed attempt: %w", err)
+	}
+
+	ref := failedTaskRef(runID, taskNum)
+	if _, err := runGit("update-ref", ref, failedCommit); err != nil {
+		return "", fmt.Errorf("failed to create ref %s: %w", ref, err)
+	}
+	return ref, nil
+}
+
+// restoreCheckpoint moves HEAD back to the checkpoint and restores every path
+// that differs between the checkpoint tree and the current tree. Only those
+// paths are touched, so excluded directories are left alone.
+func restoreCheckpoint(cp *checkpoint, currentTree string) error {
+	if _, err := runGit("reset", "-q", "--soft", cp.Head); err != nil {
+		return fmt.Errorf("failed to reset HEAD: %w", err)
+	}
+
+	diff, err := runGit("diff-tree", "-r", "-z", "--no-renames", "--name-status", cp.Tree, currentTree)
+	if err != nil {
+		return fmt.Errorf("failed to compare working tree: %w", err)
+	}
+
+	var restore []string
+	fields := strings.Split(diff, "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status, path := fields[i], fields[i+1]
+		if status == "A" {
+			// Created by the failed task: remove it
+			if err := os.Remove(filepath.Join(projectDir, path)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+			removeEmptyParents(filepath.Dir(filepath.Join(projectDir, path)))
+			continue
+		}
+		restore = append(restore, path)
+	}
+
+	if len(restore) > 0 {
+		args := append([]string{"--literal-pathspecs", "checkout", cp.Tree, "--"}, restore...)
+		if _, err := runGit(args...); err != nil {
+			return fmt.Errorf("failed to restore files: %w", err)
+		}
+	}
+
+	// Leave the restored changes unstaged, as they were before the task
+	if _, err := runGit("reset", "-q"); err != nil {
+		return fmt.Errorf("failed to reset index: %w", err)
+	}
+	return nil
+}
+
+// removeEmptyParents removes dir and its parents up to the project directory
+// as long as they are empty.
+func removeEmptyParents(dir string) {
+	for dir != projectDir && strings.HasPrefix(dir, projectDir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// rollbackFailedTask applies the configured rollback policy and reports
+// problems as warnings, since the task has already failed. A task stopped by
+// a run budget (taskErr) is never reset without preserving its partial work,
+// so that it is not lost when the run is resumed. It returns the ref of the
+// preserved attempt, if any.
+func rollbackFailedTask(cp *checkpoint, runID string, taskNum int, task Task, taskErr error, logFile *os.File) string {
+	policy := rollbackPolicy
+	if policy == config.RollbackReset && errors.Is(taskErr, errBudgetExceeded) {
+		policy = config.RollbackPreserve
+	}
+	ref, err := rollbackTask(cp, runID, taskNum, task, policy, logFile)
+	if err != nil {
+		log.Printf("⚠️ Warning: Failed to roll back task %d: %v\n", taskNum, err)
+	}
+	if ref != "" {
+		fmt.Printf("💡 Inspect: sleepship inspect-failure %s --task %d\n", runID, taskNum)
+	}
+	return ref
+}
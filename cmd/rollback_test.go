@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/isiidaisuke0926/sleepship/internal/config"
+)
+
+func TestRollbackTask(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		wantRef     bool
+		wantRestore bool
+	}{
+		{name: "leave keeps the failed changes", policy: config.RollbackLeave, wantRef: false, wantRestore: false},
+		{name: "reset restores the pre-task state", policy: config.RollbackReset, wantRef: false, wantRestore: true},
+		{name: "preserve saves a ref and restores", policy: config.RollbackPreserve, wantRef: true, wantRestore: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := initTestRepo(t)
+			logFile, err := os.CreateTemp(t.TempDir(), "sync-*.log")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = logFile.Close() }()
+
+			// State before the task: a committed file and an uncommitted change
+			writeTestFile(t, dir, "tracked.txt", "committed")
+			gitOutput(t, dir, "add", ".")
+			gitOutput(t, dir, "commit", "-q", "-m", "tracked")
+			writeTestFile(t, dir, "tracked.txt", "previous task")
+			writeTestFile(t, dir, "untracked.txt", "previous task")
+
+			cp, err := createCheckpoint()
+			if err != nil {
+				t.Fatalf("createCheckpoint() error = %v", err)
+			}
+
+			// The failed task edits, creates and commits files
+			writeTestFile(t, dir, "tracked.txt", "broken")
+			writeTestFile(t, dir, filepath.Join("new", "broken.txt"), "broken")
+			if err := os.Remove(filepath.Join(dir, "untracked.txt")); err != nil {
+				t.Fatal(err)
+			}
+			gitOutput(t, dir, "add", "-A")
+			gitOutput(t, dir, "commit", "-q", "-m", "broken attempt")
+			writeTestFile(t, dir, "logs/sync.log", "log output")
+
+			ref, err := rollbackTask(cp, "20250101-000000", 2, Task{Title: "2: Broken"}, tt.policy, logFile)
+			if err != nil {
+				t.Fatalf("rollbackTask() error = %v", err)
+			}
+
+			if (ref != "") != tt.wantRef {
+				t.Errorf("preserved ref = %q, want ref: %v", ref, tt.wantRef)
+			}
+			if tt.wantRef {
+				if ref != "refs/sleepship/20250101-000000/task-2-failed" {
+					t.Errorf("preserved ref = %q", ref)
+				}
+				files := gitOutput(t, dir, "diff", "--name-only", ref+"^", ref)
+				if files != "new/broken.txt\ntracked.txt\nuntracked.txt" {
+					t.Errorf("preserved diff files = %q", files)
+				}
+			}
+
+			if !tt.wantRestore {
+				return
+			}
+
+			if head := gitOutput(t, dir, "rev-parse", "HEAD"); head != cp.Head {
+				t.Errorf("HEAD = %s, want %s", head, cp.Head)
+			}
+			assertFileContent(t, dir, "tracked.txt", "previous task")
+			assertFileContent(t, dir, "untracked.txt", "previous task")
+			assertFileContent(t, dir, "logs/sync.log", "log output")
+			if _, err := os.Stat(filepath.Join(dir, "new")); !os.IsNotExist(err) {
+				t.Errorf("directory created by the failed task still exists")
+			}
+		})
+	}
+}
+
+func TestRollbackTaskStoppedByBudget(t *testing.T) {
+	oldPolicy := rollbackPolicy
+	rollbackPolicy = config.RollbackReset
+	defer func() { rollbackPolicy = oldPolicy }()
+
+	dir := initTestRepo(t)
+	logFile, err := os.CreateTemp(t.TempDir(), "sync-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = logFile.Close() }()
+
+	cp, err := createCheckpoint()
+	if err != nil {
+		t.Fatalf("createCheckpoint() error = %v", err)
+	}
+	writeTestFile(t, dir, "partial.txt", "half done")
+
+	// The partial work of a task stopped by a budget is preserved, not reset away
+	ref := rollbackFailedTask(cp, "20250101-000000", 1, Task{Title: "1: Partial"}, timeBudgetError(time.Hour), logFile)
+	if ref != "refs/sleepship/20250101-000000/task-1-failed" {
+		t.Fatalf("preserved ref = %q", ref)
+	}
+	if files := gitOutput(t, dir, "diff", "--name-only", ref+"^", ref); files != "partial.txt" {
+		t.Errorf("preserved diff files = %q, want partial.txt", files)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "partial.txt")); !os.IsNotExist(err) {
+		t.Errorf("partial work still in the working tree after rollback")
+	}
+}
+
+func TestSelectFailedRef(t *testing.T) {
+	refs := []string{
+		"refs/sleepship/20250102-000000/task-1-failed",
+		"refs/sleepship/20250101-000000/task-3-failed",
+		"refs/sleepship/20250101-000000/task-2-failed",
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		task    int
+		want    string
+		wantErr bool
+	}{
+		{name: "latest", want: refs[0]},
+		{name: "by run ID", query: "20250101-000000", want: refs[1]},
+		{name: "by run ID and task", query: "20250101-000000", task: 2, want: refs[2]},
+		{name: "by full ref", query: refs[2], want: refs[2]},
+		{name: "unknown run", query: "20240101-000000", wantErr: true},
+		{name: "unknown task", query: "20250102-000000", task: 5, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectFailedRef(refs, tt.query, tt.task)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectFailedRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("selectFailedRef() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := selectFailedRef(nil, "", 0); err == nil {
+		t.Error("selectFailedRef() expected error without refs")
+	}
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertFileContent(t *testing.T, dir, name, want string) {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Errorf("failed to read %s: %v", name, err)
+		return
+	}
+	if string(data) != want {
+		t.Errorf("%s = %q, want %q", name, string(data), want)
+	}
+}
@@ -3,22 +3,75 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime/debug"
 	"strings"
 
 	"github.com/isiidaisuke0926/sleepship/internal/config"
 	"github.com/spf13/cobra"
 )
 
+// Version is the sleepship version. Release builds set it with
+// -ldflags "-X github.com/isiidaisuke0926/sleepship/cmd.Version=v1.0.0".
+var Version = "dev"
+
+var (
+	configFile  string // Config file selected with --config
+	contextName string // Context selected with --context
+)
+
+// globalFlags are the flags that take effect before aliases are resolved
+// when given before the command
+var globalFlags = []string{"config", "context"}
+
 var rootCmd = &cobra.Command{
 	Use:   "sleepship",
 	Short: "Autonomous development system with Claude Code",
 	Long: `sleepship is a CLI tool for autonomous software development.
 It executes development tasks synchronously using Claude Code, with automatic
 error detection and correction.`,
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		if cmd.Flags().Changed("config") {
+			if err := useConfigFile(configFile); err != nil {
+				return err
+			}
+		}
+		if cmd.Flags().Changed("context") {
+			if err := useContext(contextName); err != nil {
+				return err
+			}
+		}
+		// The worker's parent has already checked the configuration
+		if worker || cmd == configValidateCmd || cmd.Name() == "help" {
+			return nil
+		}
+		if err := checkConfig(); err != nil {
+			// A configuration problem is not a usage error
+			cmd.SilenceUsage = true
+			return err
+		}
+		return nil
+	},
 }
 
 // Execute runs the root command
 func Execute() {
+	// --config before the command also selects the file aliases are loaded from
+	args, flags := extractGlobalFlags(os.Args[1:])
+	if path, ok := flags["config"]; ok {
+		if err := useConfigFile(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if name, ok := flags["context"]; ok {
+		if err := useContext(name); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	os.Args = append([]string{os.Args[0]}, args...)
+
 	// Pre-process arguments to resolve aliases
 	if len(os.Args) > 1 {
 		firstArg := os.Args[1]
@@ -37,24 +90,26 @@ func Execute() {
 		aliases, err := config.LoadAliases()
 		if err == nil && len(aliases) > 0 {
 			if _, exists := aliases[firstArg]; exists {
-				// Resolve the alias
-				resolved, err := config.ResolveAlias(firstArg, aliases)
+				// Resolve the alias, substituting the remaining arguments
+				parts, err := config.ResolveAliasArgs(firstArg, os.Args[2:], aliases)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Error resolving alias '%s': %v\n", firstArg, err)
 					os.Exit(1)
 				}
-
-				// Expand with remaining arguments
-				remainingArgs := os.Args[2:]
-				fullCommand := config.ExpandAliasArgs(resolved, remainingArgs)
-
-				// Parse the expanded command
-				parts := strings.Fields(fullCommand)
 				if len(parts) == 0 {
 					fmt.Fprintln(os.Stderr, "Error: empty command after alias expansion")
 					os.Exit(1)
 				}
 
+				// Run the steps of a multi-step alias instead of a command
+				if alias := aliases[parts[0]]; alias.IsMultiStep() {
+					if err := runAliasSteps(parts[0], alias, parts[1:], aliases); err != nil {
+						fmt.Fprintln(os.Stderr, err)
+						os.Exit(1)
+					}
+					return
+				}
+
 				// Update os.Args with resolved command
 				os.Args = append([]string{os.Args[0]}, parts...)
 			}
@@ -70,4 +125,75 @@ func Execute() {
 
 func init() {
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.Version = sleepshipVersion()
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file to use instead of searching for "+config.ConfigFileName)
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "Context to use instead of the current one")
+}
+
+// extractGlobalFlags removes the global flags given before the command from
+// args and returns the remaining arguments and the values of the flags.
+func extractGlobalFlags(args []string) ([]string, map[string]string) {
+	values := make(map[string]string)
+	for len(args) > 0 {
+		found := false
+		for _, name := range globalFlags {
+			flag := "--" + name
+			switch {
+			case args[0] == flag && len(args) > 1:
+				values[name], args, found = args[1], args[2:], true
+			case strings.HasPrefix(args[0], flag+"="):
+				values[name], args, found = strings.TrimPrefix(args[0], flag+"="), args[1:], true
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return args, values
+}
+
+// useConfigFile selects the config file used by this process and, through
+// SLEEPSHIP_CONFIG, by the background worker and alias steps it starts.
+func useConfigFile(path string) error {
+	if path == "" {
+		return fmt.Errorf("--config requires a file path")
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config file path: %w", err)
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return fmt.Errorf("config file not found: %s", path)
+	}
+	return os.Setenv(config.ConfigEnvVar, abs)
+}
+
+// useContext selects the context used by this process and, through
+// SLEEPSHIP_CONTEXT, by the background worker and alias steps it starts.
+func useContext(name string) error {
+	if name == "" {
+		return fmt.Errorf("--context requires a context name")
+	}
+	contexts, err := config.LoadContexts()
+	if err != nil {
+		return err
+	}
+	if _, ok := contexts[name]; !ok {
+		return fmt.Errorf("context %q is not defined", name)
+	}
+	return os.Setenv(config.ContextEnvVar, name)
+}
+
+// sleepshipVersion returns Version, or the module version for binaries built
+// without setting it (e.g. with go install).
+func sleepshipVersion() string {
+	if Version == "dev" {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			return info.Main.Version
+		}
+	}
+	return Version
 }
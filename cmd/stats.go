@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/isiidaisuke0926/sleepship/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsSince    string
+	statsTaskFile string
+	statsTop      int
+	statsJSON     bool
+)
+
+// sparkBlocks are the characters used to draw sparklines, lowest first
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show success rates, durations and flaky tasks from the history",
+	Long: `Show statistics computed from the execution history:
+
+  - success rate, mean and p95 duration and mean cost per task file, with trends
+  - success rate, duration, average retries and mean cost per task
+  - the verification commands that fail most often
+  - flaky tasks that succeed only after fixes
+
+Per-task statistics are available for executions recorded with per-task
+results (see "sleepship history show").
+
+Examples:
+  sleepship stats                      # Statistics over the whole history
+  sleepship stats --since 30d          # Statistics over the last 30 days
+  sleepship stats --task-file 'tasks-*.txt' --top 5
+  sleepship stats --json > stats.json  # Export for dashboards`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().StringVar(&statsSince, "since", "", "Only use executions at or after a date, time or age (e.g. 2025-01-31, 30d)")
+	statsCmd.Flags().StringVar(&statsTaskFile, "task-file", "", "Only use executions of task files matching a glob or path substring")
+	statsCmd.Flags().IntVar(&statsTop, "top", 10, "Number of rows to show per table (0 = all)")
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Output statistics as JSON")
+}
+
+func runStats(_ *cobra.Command, _ []string) error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	filter := history.Filter{TaskFile: statsTaskFile}
+	if statsSince != "" {
+		if filter.Since, err = parseTimeBound(statsSince, time.Now(), false); err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+
+	hist, err := history.Load(dir)
+	if err != nil {
+		return fmt.Errorf("failed to load history: %w", err)
+	}
+
+	stats := history.ComputeStats(hist.Query(filter))
+
+	if statsJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	}
+
+	if stats.Runs == 0 {
+		fmt.Println("📋 No execution history found")
+		return nil
+	}
+
+	displayStats(stats, statsTop)
+	return nil
+}
+
+func displayStats(stats history.Stats, top int) {
+	fmt.Printf("📊 Execution Statistics (%d executions)\n\n", stats.Runs)
+	fmt.Printf("   Success rate:   %s (%d/%d)\n", formatRate(stats.SuccessRate), stats.Succeeded, stats.Runs)
+	fmt.Printf("   Total duration: %s\n", formatDuration(stats.TotalDuration))
+	if stats.TotalTokens > 0 || stats.TotalCost > 0 {
+		fmt.Printf("   Total cost:     %s (%s tokens)\n", formatCost(stats.TotalCost), formatTokens(stats.TotalTokens))
+	}
+	fmt.Println()
+
+	fmt.Println("📁 Task Files")
+	fmt.Printf("%-30s %6s %8s %10s %10s %9s  %-10s %s\n", "Task File", "Runs", "Success", "Mean", "p95", "Cost", "Trend", "Duration")
+	fmt.Println(strings.Repeat("-", 110))
+	for _, file := range limitRows(stats.TaskFiles, top) {
+		durations := make([]float64, len(file.DurationTrend))
+		for i, d := range file.DurationTrend {
+			durations[i] = d.Seconds()
+		}
+		fmt.Printf("%-30s %6d %8s %10s %10s %9s  %-10s %s\n",
+			shorten(file.TaskFile, 30), file.Runs, formatRate(file.SuccessRate),
+			formatDuration(file.MeanDuration), formatDuration(file.P95Duration), formatMeanCost(file.MeanCost),
+			sparkline(file.SuccessTrend, 0, 1), sparkline(durations, 0, 0))
+	}
+	fmt.Println()
+
+	if len(stats.Tasks) == 0 {
+		fmt.Println("ℹ️  No per-task records yet (recorded by sync from this version on)")
+		return
+	}
+
+	fmt.Println("📝 Tasks (least reliable first)")
+	fmt.Printf("%-40s %6s %8s %10s %10s %8s %9s  %s\n", "Task", "Runs", "Success", "Mean", "p95", "Retries", "Cost", "Trend")
+	fmt.Println(strings.Repeat("-", 110))
+	for _, task := range limitRows(stats.Tasks, top) {
+		fmt.Printf("%-40s %6d %8s %10s %10s %8.1f %9s  %s\n",
+			shorten(strings.TrimSpace(task.Title), 40), task.Runs, formatRate(task.SuccessRate),
+			formatDuration(task.MeanDuration), formatDuration(task.P95Duration),
+			task.AverageRetries, formatMeanCost(task.MeanCost), sparkline(task.SuccessTrend, 0, 1))
+	}
+	fmt.Println()
+
+	if len(stats.VerificationFailures) > 0 {
+		fmt.Println("🔍 Most Frequent Verification Failures")
+		for _, failure := range limitRows(stats.VerificationFailures, top) {
+			fmt.Printf("   %4d  %s\n", failure.Failures, failure.Command)
+		}
+		fmt.Println()
+	}
+
+	if len(stats.FlakyTasks) > 0 {
+		fmt.Println("🎲 Flaky Tasks (succeeded only after fixes)")
+		for _, task := range limitRows(stats.FlakyTasks, top) {
+			fmt.Printf("   %d/%d successful runs needed fixes  %s\n", task.FlakyRuns, task.Succeeded, strings.TrimSpace(task.Title))
+		}
+		fmt.Println()
+	}
+}
+
+// sparkline draws values as a line of block characters. If lo and hi are
+// equal, the range is taken from the values.
+func sparkline(values []float64, lo, hi float64) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	if lo == hi {
+		lo, hi = values[0], values[0]
+		for _, v := range values {
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		level := len(sparkBlocks) - 1
+		if hi > lo {
+			level = int((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+		}
+		if level < 0 {
+			level = 0
+		}
+		if level >= len(sparkBlocks) {
+			level = len(sparkBlocks) - 1
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
+
+// formatMeanCost formats a mean cost, or "-" if no usage was reported.
+func formatMeanCost(usd float64) string {
+	if usd == 0 {
+		return "-"
+	}
+	return formatCost(usd)
+}
+
+// formatRate formats a ratio as a percentage.
+func formatRate(r float64) string {
+	return fmt.Sprintf("%.0f%%", r*100)
+}
+
+// shorten truncates s to n characters for table display.
+func shorten(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+// limitRows returns the first n rows, or all rows if n is 0.
+func limitRows[T any](rows []T, n int) []T {
+	if n > 0 && len(rows) > n {
+		return rows[:n]
+	}
+	return rows
+}
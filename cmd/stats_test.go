@@ -0,0 +1,25 @@
+package cmd
+
+import "testing"
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		lo, hi float64
+		want   string
+	}{
+		{name: "fixed range", values: []float64{0, 0.5, 1}, lo: 0, hi: 1, want: "▁▄█"},
+		{name: "range from values", values: []float64{10, 20, 30}, want: "▁▄█"},
+		{name: "constant values", values: []float64{5, 5}, want: "██"},
+		{name: "empty", values: nil, want: "-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sparkline(tt.values, tt.lo, tt.hi); got != tt.want {
+				t.Errorf("sparkline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,12 +3,17 @@ package cmd
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -20,6 +25,10 @@ import (
 
 const (
 	maxRecursionDepth = 3 // Maximum depth for recursive sleepship calls
+
+	// agentWaitDelay is how long a stopped agent may keep its output open
+	// before it is abandoned, e.g. because a child process still holds it
+	agentWaitDelay = 5 * time.Second
 )
 
 var (
@@ -28,15 +37,54 @@ var (
 	worker     bool // Internal flag for background worker process
 	startFrom  int  // Start from specified task number
 	maxRetries int  // Maximum number of retries for failed verifications (default: 3)
+
+	commitStrategy string // How task changes are committed (per-task, per-attempt, squash, none)
+	rollbackPolicy string // What happens to a failed task's changes (leave, reset, preserve)
+	keepGoing      bool   // Continue with independent tasks after a task fails
+
+	preCommitChecks []string // Commands run as extra verification steps after every task
+
+	pushMode   string // When to push the branch (never, task, end)
+	pushRemote string // Remote to push to
+
+	branchOverride string // Branch to run on instead of feature/<task file>
+	branchBase     string // Ref new branches are created from (default: HEAD)
+
+	maxDurationFlag string // Value of --max-duration, parsed into maxDuration
+
+	claudeFlags []string // Extra flags passed to the agent CLI
+
+	syncWait bool // Run in the foreground instead of spawning a background worker
+
+	syncMembers     []string // Workspace members to run
+	allMembers      bool     // Run every workspace member
+	syncParallel    bool     // Run workspace members at the same time
+	workspaceMember string   // Workspace member this run is for
+	memberWorkDir   string   // Directory a member run was started from
+	startPoint      string   // Commit a new branch starts from when no branch base is set
+	worktreeDir     string   // Member directory in the worktree a member runs in, if any
+
+	// Directory history, logs and run archives are written to: the project
+	// directory, or the member directory when a member runs in a worktree
+	recordDir string
+
+	recordedTaskFile string // Task file a run is recorded under when it reads a snapshot of it (rerun)
 )
 
-// Task represents a development task with title, description, and verification command.
+// Task represents a development task with title, description, and verification commands.
 type Task struct {
 	Title       string
 	Description string
-	Command     string // 確認コマンド（go build, go test等）
+	Commands    []string // 確認コマンド（go build, go test等）、記述順
+	Depends     []int    // Numbers of the tasks this task depends on (依存: 1, 2)
 }
 
+// dependsPattern matches dependency declarations such as "依存: 1, 2" or "Depends on: タスク1"
+var dependsPattern = regexp.MustCompile(`^(?:[-*]\s*)?(?:依存|Depends(?: on)?)\s*[:：]\s*(.+)$`)
+
+// numberPattern extracts task numbers from a dependency declaration
+var numberPattern = regexp.MustCompile(`\d+`)
+
 var syncCmd = &cobra.Command{
 	Use:   "sync [task-file]",
 	Short: "Synchronously execute development tasks with Claude Code",
@@ -48,7 +96,8 @@ var syncCmd = &cobra.Command{
 		"  Tasks are defined using markdown headers starting with \"## タスク\" or \"## Task\".\n" +
 		"  Each task can have:\n" +
 		"  - Implementation instructions in the body\n" +
-		"  - Verification command in a code block starting with \"- `\"\n\n" +
+		"  - Verification commands, one per line starting with \"- `\", run in order\n" +
+		"  - Dependencies on other tasks in a line like \"依存: 1, 2\" or \"Depends on: 1\"\n\n" +
 		"Example:\n" +
 		"  ## タスク1: Add new feature\n\n" +
 		"  ### 実装\n" +
@@ -58,9 +107,19 @@ var syncCmd = &cobra.Command{
 		"  - `go test ./...`\n\n" +
 		"Examples:\n" +
 		"  sleepship sync tasks.txt\n" +
+		"  sleepship sync                  # default_task_file from .sleepship.toml\n" +
 		"  sleepship sync tasks.txt --dir=/path/to/project\n" +
-		"  sleepship sync tasks.txt --dir=/path/to/project --log-dir=./logs",
-	Args: cobra.ExactArgs(1),
+		"  sleepship sync tasks.txt --dir=/path/to/project --log-dir=./logs\n" +
+		"  sleepship sync tasks.txt --commit-strategy=squash\n" +
+		"  sleepship sync tasks.txt --rollback=preserve\n" +
+		"  sleepship sync tasks.txt --keep-going\n" +
+		"  sleepship sync tasks.txt --wait\n" +
+		"  sleepship sync tasks.txt --push=end --push-remote=origin\n" +
+		"  sleepship sync tasks.txt --branch-base=origin/main\n" +
+		"  sleepship sync tasks.txt --max-duration=6h --max-agent-calls=50 --max-cost=10\n" +
+		"  sleepship sync --member api     # Workspace member from [workspace.members.api]\n" +
+		"  sleepship sync --all-members --parallel",
+	Args: cobra.MaximumNArgs(1),
 	RunE: runSync,
 }
 
@@ -71,25 +130,81 @@ func init() {
 	syncCmd.Flags().StringVar(&logDir, "log-dir", "logs", "Log output directory")
 	syncCmd.Flags().IntVar(&startFrom, "start-from", 1, "Start from specified task number (default: 1)")
 	syncCmd.Flags().IntVar(&maxRetries, "max-retries", 3, "Maximum number of retries for failed verifications (default: 3)")
+	syncCmd.Flags().StringVar(&commitStrategy, "commit-strategy", config.CommitPerTask, "Commit strategy: per-task, per-attempt, squash, none")
+	syncCmd.Flags().StringVar(&rollbackPolicy, "rollback", config.RollbackLeave, "What to do with a failed task's changes: leave, reset, preserve")
+	syncCmd.Flags().BoolVar(&keepGoing, "keep-going", false, "Roll back a failed task and continue with tasks that do not depend on it")
+	syncCmd.Flags().StringArrayVar(&preCommitChecks, "pre-commit-check", nil, "Command run as an extra verification step after every task (repeatable)")
+	syncCmd.Flags().StringVar(&pushMode, "push", config.PushNever, "When to push the branch: never, task, end")
+	syncCmd.Flags().StringVar(&pushRemote, "push-remote", "origin", "Remote to push to")
+	syncCmd.Flags().StringVar(&maxDurationFlag, "max-duration", "0", "Stop the run once it has run this long, e.g. 90m, 8h (0 = unlimited)")
+	syncCmd.Flags().IntVar(&maxAgentCalls, "max-agent-calls", 0, "Stop the run once the agent has been called this many times (0 = unlimited)")
+	syncCmd.Flags().Float64Var(&maxCost, "max-cost", 0, "Stop the run once the agent has spent this many USD (0 = unlimited)")
+	syncCmd.Flags().StringVar(&branchOverride, "branch", "", "Branch to run on, created if missing (default: feature/<task file>)")
+	syncCmd.Flags().StringVar(&branchBase, "branch-base", "", "Ref to create new branches from, e.g. origin/main (default: HEAD)")
+	syncCmd.Flags().BoolVar(&syncWait, "wait", false, "Run in the foreground and wait for the run to finish")
+	syncCmd.Flags().StringArrayVar(&syncMembers, "member", nil, "Workspace member to run in its own directory, with its own config files (repeatable)")
+	syncCmd.Flags().BoolVar(&allMembers, "all-members", false, "Run every workspace member")
+	syncCmd.Flags().BoolVar(&syncParallel, "parallel", false, "Run workspace members at the same time (default: workspace.parallel)")
 	syncCmd.Flags().BoolVar(&worker, "worker", false, "Internal: run as background worker")
 	_ = syncCmd.Flags().MarkHidden("worker")
+	syncCmd.Flags().StringVar(&recordedTaskFile, "recorded-task-file", "", "Internal: task file to record the run under")
+	_ = syncCmd.Flags().MarkHidden("recorded-task-file")
+	syncCmd.Flags().StringVar(&startPoint, "start-point", "", "Internal: commit a new branch starts from when no branch base is set")
+	_ = syncCmd.Flags().MarkHidden("start-point")
+	syncCmd.Flags().StringVar(&worktreeDir, "worktree", "", "Internal: member directory in the worktree the member runs in")
+	_ = syncCmd.Flags().MarkHidden("worktree")
 }
 
 //nolint:gocyclo // runSync is complex by nature, handling the full task execution lifecycle
 func runSync(cmd *cobra.Command, args []string) error {
-	taskFile := args[0]
 	startTime := time.Now()
 
-	// Load configuration from environment variables
+	// Run a workspace member in its own directory, or hand several members
+	// to runWorkspace
+	memberConfig := config.FromMember(config.Member{})
+	var member *config.Member
+	if len(syncMembers) > 0 || allMembers {
+		if len(syncMembers) > 0 && allMembers {
+			return fmt.Errorf("--member and --all-members cannot be used together")
+		}
+		if cmd.Flags().Changed("dir") {
+			return fmt.Errorf("--dir cannot be used with --member or --all-members")
+		}
+		workspace, names, err := selectMembers()
+		if err != nil {
+			return err
+		}
+		if allMembers || len(names) > 1 {
+			return runWorkspace(cmd, args, workspace, names)
+		}
+		m := workspace.Members[names[0]]
+		member = &m
+		workspaceMember = names[0]
+		memberConfig = config.FromMember(m)
+		if args, err = enterMember(m, args); err != nil {
+			return err
+		}
+	}
+
+	runID := newRunID(startTime, workspaceMember)
+
+	// Load configuration from environment variables and the context
 	envConfig := config.LoadFromEnv()
 	defaultConfig := config.NewDefaultConfig()
+	contextConfig, contextName, err := config.LoadContextConfig()
+	if err != nil {
+		return err
+	}
 
 	// Create CLI config from flags
 	cliConfig := &config.Config{
-		ProjectDir: projectDir,
-		LogDir:     logDir,
-		MaxRetries: -1,
-		StartFrom:  -1,
+		ProjectDir:        projectDir,
+		LogDir:            logDir,
+		MaxRetries:        -1,
+		StartFrom:         -1,
+		MaxAgentCalls:     -1,
+		MaxCost:           -1,
+		HistoryMaxEntries: -1,
 	}
 
 	// Check if flags were explicitly set
@@ -99,17 +214,120 @@ func runSync(cmd *cobra.Command, args []string) error {
 	if cmd.Flags().Changed("start-from") {
 		cliConfig.StartFrom = startFrom
 	}
+	if cmd.Flags().Changed("commit-strategy") {
+		if !config.IsValidCommitStrategy(commitStrategy) {
+			return fmt.Errorf("invalid --commit-strategy %q (valid: %s)", commitStrategy, strings.Join(config.CommitStrategies, ", "))
+		}
+		cliConfig.CommitStrategy = commitStrategy
+	}
+	if cmd.Flags().Changed("rollback") {
+		if !config.IsValidRollbackPolicy(rollbackPolicy) {
+			return fmt.Errorf("invalid --rollback %q (valid: %s)", rollbackPolicy, strings.Join(config.RollbackPolicies, ", "))
+		}
+		cliConfig.RollbackPolicy = rollbackPolicy
+	}
+	if cmd.Flags().Changed("pre-commit-check") {
+		cliConfig.PreCommitChecks = preCommitChecks
+	}
+	if cmd.Flags().Changed("push") {
+		if !config.IsValidPushMode(pushMode) {
+			return fmt.Errorf("invalid --push %q (valid: %s)", pushMode, strings.Join(config.PushModes, ", "))
+		}
+		cliConfig.Push = pushMode
+	}
+	if cmd.Flags().Changed("push-remote") {
+		cliConfig.PushRemote = pushRemote
+	}
+	if cmd.Flags().Changed("branch-base") {
+		cliConfig.BranchBase = branchBase
+	}
+	if cmd.Flags().Changed("max-duration") {
+		if _, err := config.ParseAge(maxDurationFlag); err != nil {
+			return fmt.Errorf("invalid --max-duration %q: %w", maxDurationFlag, err)
+		}
+		cliConfig.MaxDuration = maxDurationFlag
+	}
+	if cmd.Flags().Changed("max-agent-calls") {
+		if maxAgentCalls < 0 {
+			return fmt.Errorf("invalid --max-agent-calls %d (must be 0 or greater)", maxAgentCalls)
+		}
+		cliConfig.MaxAgentCalls = maxAgentCalls
+	}
+	if cmd.Flags().Changed("max-cost") {
+		if maxCost < 0 {
+			return fmt.Errorf("invalid --max-cost %v (must be 0 or greater)", maxCost)
+		}
+		cliConfig.MaxCost = maxCost
+	}
+
+	// The config file layer is the one of the project that is run, which
+	// a higher layer may have chosen
+	runDir := config.MergeLayers(defaultConfig, cliConfig, config.FromEnv(envConfig), memberConfig, contextConfig).ProjectDir
+	fileConfig, err := config.LoadFileConfigIn(runDir)
+	if err != nil {
+		return fmt.Errorf("failed to load config file: %w", err)
+	}
 
-	// Merge configurations: CLI > Env > Default
-	mergedConfig := config.MergeConfig(cliConfig, config.FromEnv(envConfig), defaultConfig)
+	// Merge configurations: CLI > Env > Member > Context > Config file > Default
+	mergedConfig := config.MergeLayers(defaultConfig, cliConfig, config.FromEnv(envConfig), memberConfig, contextConfig, config.FromFile(fileConfig))
+
+	var taskFile string
+	if member != nil {
+		// A member always runs in its own directory
+		mergedConfig.ProjectDir = member.Path
+		if worktreeDir != "" {
+			mergedConfig.ProjectDir = worktreeDir
+		}
+		taskFile, err = memberTaskFile(args, *member, mergedConfig)
+	} else {
+		taskFile, err = taskFileArg(args, envConfig, mergedConfig)
+	}
+	if err != nil {
+		return err
+	}
+	if taskFile == "" {
+		return fmt.Errorf("no task file given and no default_task_file configured")
+	}
+
+	// A rerun reads a snapshot of its task file, but is recorded, archived
+	// and named after the task file it was first run from
+	taskFileName := taskFile
+	if recordedTaskFile != "" {
+		taskFileName = recordedTaskFile
+	}
 
 	// Apply merged configuration
 	projectDir = mergedConfig.ProjectDir
 	logDir = mergedConfig.LogDir
 	maxRetries = mergedConfig.MaxRetries
 	startFrom = mergedConfig.StartFrom
+	commitStrategy = mergedConfig.CommitStrategy
+	rollbackPolicy = mergedConfig.RollbackPolicy
+	preCommitChecks = mergedConfig.PreCommitChecks
+	pushMode = mergedConfig.Push
+	pushRemote = mergedConfig.PushRemote
+	branchBase = mergedConfig.BranchBase
+	claudeFlags = mergedConfig.ClaudeFlags
+	maxDurationFlag = mergedConfig.MaxDuration
+	maxAgentCalls = mergedConfig.MaxAgentCalls
+	maxCost = mergedConfig.MaxCost
+
+	if maxDuration, err = config.ParseAge(maxDurationFlag); err != nil {
+		return fmt.Errorf("invalid max duration %q: %w", maxDurationFlag, err)
+	}
+
+	retention, err := historyRetention(mergedConfig)
+	if err != nil {
+		return err
+	}
 
 	// Log configuration source for debugging
+	if contextName != "" {
+		log.Printf("ℹ️  Using context: %s\n", contextName)
+	}
+	if workspaceMember != "" {
+		log.Printf("ℹ️  Running workspace member: %s\n", workspaceMember)
+	}
 	if envConfig.HasMaxRetries() && !cmd.Flags().Changed("max-retries") {
 		log.Printf("ℹ️  Using max-retries from environment: %d\n", maxRetries)
 	}
@@ -119,14 +337,26 @@ func runSync(cmd *cobra.Command, args []string) error {
 	if envConfig.HasLogDir() && !cmd.Flags().Changed("log-dir") {
 		log.Printf("ℹ️  Using log-dir from environment: %s\n", logDir)
 	}
-	if envConfig.HasProjectDir() && !cmd.Flags().Changed("dir") {
+	if envConfig.HasProjectDir() && !cmd.Flags().Changed("dir") && member == nil {
 		log.Printf("ℹ️  Using project directory from environment: %s\n", projectDir)
 	}
+	if envConfig.HasCommitStrategy() && !cmd.Flags().Changed("commit-strategy") {
+		log.Printf("ℹ️  Using commit strategy from environment: %s\n", commitStrategy)
+	}
+	if envConfig.HasRollbackPolicy() && !cmd.Flags().Changed("rollback") {
+		log.Printf("ℹ️  Using rollback policy from environment: %s\n", rollbackPolicy)
+	}
+
+	// A failed task must not leave its changes behind when later tasks still run
+	if keepGoing && rollbackPolicy == config.RollbackLeave {
+		rollbackPolicy = config.RollbackPreserve
+	}
 
 	// If not running as worker, spawn background process
-	if !worker {
+	if !worker && !syncWait {
 		return spawnBackgroundWorker(taskFile)
 	}
+	resetRunBudget(startTime)
 
 	// Check recursion depth
 	currentDepth := getCurrentRecursionDepth()
@@ -149,6 +379,12 @@ func runSync(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to resolve project directory: %w", err)
 	}
 	projectDir = absProjectDir
+	recordDir = projectDir
+	if member != nil && worktreeDir != "" {
+		if recordDir, err = filepath.Abs(member.Path); err != nil {
+			return fmt.Errorf("failed to resolve member directory: %w", err)
+		}
+	}
 
 	// Parse task file
 	tasks, err := parseTaskFile(taskFile)
@@ -159,6 +395,15 @@ func runSync(cmd *cobra.Command, args []string) error {
 	if len(tasks) == 0 {
 		return fmt.Errorf("no tasks found in task file")
 	}
+	if err := checkDependencies(tasks); err != nil {
+		return fmt.Errorf("invalid task file: %w", err)
+	}
+
+	// Archive what this run starts from so that it can be audited and repeated
+	runHash, err := archiveRun(taskFile, taskFileName, mergedConfig)
+	if err != nil {
+		log.Printf("⚠️ Warning: Failed to archive run: %v\n", err)
+	}
 
 	// Validate startFrom value
 	if startFrom < 1 {
@@ -176,13 +421,13 @@ func runSync(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create log directory
-	absLogDir := filepath.Join(projectDir, logDir)
+	absLogDir := filepath.Join(recordDir, logDir)
 	if err := os.MkdirAll(absLogDir, 0755); err != nil {
 		return fmt.Errorf("failed to create log directory: %w", err)
 	}
 
 	// Create log file
-	logFileName := fmt.Sprintf("sync-%s.log", time.Now().Format("20060102-150405"))
+	logFileName := fmt.Sprintf("sync-%s.log", runID)
 	logFilePath := filepath.Join(absLogDir, logFileName)
 	f, err := os.Create(logFilePath)
 	if err != nil {
@@ -190,6 +435,11 @@ func runSync(cmd *cobra.Command, args []string) error {
 	}
 	defer func() { _ = f.Close() }()
 
+	relLogFilePath := logFilePath
+	if rel, err := filepath.Rel(recordDir, logFilePath); err == nil {
+		relLogFilePath = rel
+	}
+
 	// Write task info to both stdout and log file
 	taskInfo := fmt.Sprintf("📋 Total tasks: %d\n", len(tasks))
 	fmt.Print(taskInfo)
@@ -201,21 +451,90 @@ func runSync(cmd *cobra.Command, args []string) error {
 		_, _ = f.WriteString(startInfo)
 	}
 
+	if commitStrategy != config.CommitPerTask {
+		strategyInfo := fmt.Sprintf("💾 Commit strategy: %s\n", commitStrategy)
+		fmt.Print(strategyInfo)
+		_, _ = f.WriteString(strategyInfo)
+	}
+
 	dirInfo := fmt.Sprintf("📁 Project directory: %s\n\n", projectDir)
 	fmt.Print(dirInfo)
 	_, _ = f.WriteString(dirInfo)
 
 	// Create branch for this sync execution
-	var branchName string
-	if err := createBranchForSync(taskFile, f); err != nil {
-		log.Printf("⚠️ Warning: Failed to create branch: %v\n", err)
-		// Continue anyway - branch creation is not critical
-		branchName = ""
-	} else {
-		// Extract branch name from task file
-		filename := filepath.Base(taskFile)
-		sanitized := sanitizeBranchName(filename)
-		branchName = fmt.Sprintf("feature/%s", sanitized)
+	branchName, createdBranch := setUpRunBranch(taskFileName, f)
+
+	// Push the branch, force-with-lease only if sleepship created it
+	var pushedRef string
+	pushChanges := func() {
+		ref, err := pushBranch(pushRemote, branchName, createdBranch, f)
+		if errors.Is(err, errNoRunBranch) {
+			log.Printf("⚠️ Warning: %v\n", err)
+			return
+		}
+		if err != nil {
+			log.Printf("⚠️ Warning: Failed to push: %v\n", err)
+			return
+		}
+		pushedRef = ref
+	}
+
+	// Record execution to history
+	var results []taskResult
+	var stopReason string // Budget that stopped the run
+	var nextTask int      // Task to resume from if the run stops early
+	recordHistory := func(success bool, errorMsg string) {
+		err := history.RecordEntry(recordDir, history.Entry{
+			ID:           runID,
+			TaskFile:     taskFileName,
+			Member:       workspaceMember,
+			RunHash:      runHash,
+			Success:      success,
+			Duration:     time.Since(startTime),
+			TaskCount:    len(tasks),
+			ErrorMessage: errorMsg,
+			StartFrom:    startFrom,
+			MaxRetries:   maxRetries,
+			BranchName:   branchName,
+			PushedRef:    pushedRef,
+			LogFile:      relLogFilePath,
+			Tasks:        taskRecords(results),
+			Options: &history.RunOptions{
+				LogDir:          logDir,
+				CommitStrategy:  commitStrategy,
+				RollbackPolicy:  rollbackPolicy,
+				KeepGoing:       keepGoing,
+				PreCommitChecks: preCommitChecks,
+				Push:            pushMode,
+				PushRemote:      pushRemote,
+				BranchBase:      branchBase,
+				MaxDuration:     maxDurationValue(),
+				MaxAgentCalls:   maxAgentCalls,
+				MaxCost:         maxCost,
+			},
+			Usage:      usageRecord(runUsage),
+			StopReason: stopReason,
+			NextTask:   nextTask,
+		})
+		if err != nil {
+			log.Printf("⚠️ Warning: Failed to record history: %v\n", err)
+			return
+		}
+
+		// Register the project in the global index for --all-projects
+		if indexDir, err := config.UserConfigDir(); err == nil {
+			if err := history.RegisterProject(indexDir, recordDir, time.Now()); err != nil {
+				log.Printf("⚠️ Warning: Failed to update global history index: %v\n", err)
+			}
+		}
+
+		// Drop entries and logs beyond the retention limits
+		pruned, err := history.Prune(recordDir, retention, false)
+		if err != nil {
+			log.Printf("⚠️ Warning: Failed to prune history: %v\n", err)
+		} else if len(pruned) > 0 {
+			log.Printf("🧹 Pruned %d old history entries\n", len(pruned))
+		}
 	}
 
 	// Execute tasks
@@ -230,38 +549,167 @@ func runSync(cmd *cobra.Command, args []string) error {
 			continue
 		}
 
+		// Skip tasks that depend on a task that did not succeed
+		if dep := blockingDependency(task, results); dep > 0 {
+			reason := fmt.Sprintf("depends on task %d", dep)
+			skipMsg := fmt.Sprintf("⏭️  Skipping task %d/%d (%s): %s\n", taskNum, len(tasks), reason, task.Title)
+			fmt.Print(skipMsg)
+			_, _ = f.WriteString(skipMsg)
+			results = append(results, taskResult{Number: taskNum, Title: task.Title, Status: taskSkipped, Reason: reason})
+			continue
+		}
+
+		// Stop before the next task once a budget is used up
+		if err := checkBudget(); err != nil {
+			log.Printf("💰 予算を使い切ったため実行を停止します: %v\n", err)
+			if pushMode == config.PushEnd {
+				pushChanges()
+			}
+			stopReason, nextTask = budgetStopReason(err), taskNum
+			recordHistory(false, err.Error())
+			return err
+		}
+
 		taskHeader := fmt.Sprintf("========================================\nTask %d/%d: %s\n========================================\n\n", taskNum, len(tasks), task.Title)
 		fmt.Print(taskHeader)
 		_, _ = f.WriteString(taskHeader)
 
-		// Execute task with Claude with retry logic
-		var lastErr error
-		taskRetryCount := 0
+		// Record the pre-task state so that a failed task can be rolled back
+		var cp *checkpoint
+		if rollbackPolicy != config.RollbackLeave {
+			var cpErr error
+			cp, cpErr = createCheckpoint()
+			if cpErr != nil {
+				log.Printf("⚠️ Warning: Failed to record pre-task state, rollback disabled for task %d: %v\n", taskNum, cpErr)
+			}
+		}
 
-		for taskRetryCount <= maxRetries {
-			if err := executeTask(task, f); err != nil {
-				lastErr = err
-				taskRetryCount++
+		result := taskResult{Number: taskNum, Title: task.Title}
+		taskStart := time.Now()
+		headBefore, _ := runGit("rev-parse", "--verify", "HEAD")
 
-				if taskRetryCount > maxRetries {
-					log.Printf("❌ タスク %d が %d 回の試行後も失敗しました: %v\n", taskNum, maxRetries+1, err)
-					log.Printf("実行を停止します。\n")
+		err := executeTaskWithRetries(task, taskNum, &result, f)
 
-					// Record failed execution to history
-					duration := time.Since(startTime)
-					histErr := history.Record(projectDir, taskFile, branchName, false, duration, len(tasks), startFrom, maxRetries, fmt.Sprintf("Task %d failed: %v", taskNum, err))
-					if histErr != nil {
-						log.Printf("⚠️ Warning: Failed to record history: %v\n", histErr)
-					}
+		// Commit changes for this task, letting Claude fix problems reported by git hooks
+		if err == nil && (commitStrategy == config.CommitPerTask || commitStrategy == config.CommitPerAttempt) {
+			err = commitWithHookFixes(
+				func() error { return commitTaskChanges(task, taskNum, f) },
+				func() error { _, err := runVerification(verificationCommands(task), &result, f); return err },
+				fmt.Sprintf("task %d", taskNum), &result, f)
+		}
+		result.Duration = time.Since(taskStart)
 
-					return fmt.Errorf("task %d failed after %d attempts: %w", taskNum, maxRetries+1, err)
+		if err != nil {
+			errorMsg := err.Error()
+			if ref := rollbackFailedTask(cp, runID, taskNum, task, err, f); ref != "" {
+				errorMsg += fmt.Sprintf(" (preserved: %s)", ref)
+			}
+			result.Status = taskFailed
+			result.Reason = errorMsg
+			results = append(results, result)
+
+			if !keepGoing || errors.Is(err, errBudgetExceeded) {
+				log.Printf("実行を停止します。\n")
+
+				// Push the tasks completed so far
+				if pushMode == config.PushEnd {
+					pushChanges()
 				}
 
-				log.Printf("❌ タスク %d の実行に失敗しました。リトライ %d/%d 回目を実行します\n", taskNum, taskRetryCount, maxRetries)
-				log.Printf("エラー内容: %v\n", err)
+				// Record failed execution to history
+				stopReason, nextTask = budgetStopReason(err), taskNum
+				recordHistory(false, errorMsg)
+
+				return err
+			}
+
+			log.Printf("⏩ 次のタスクに進みます (--keep-going)\n")
+			continue
+		}
 
-				// Retry with error context
-				retryPrompt := fmt.Sprintf(`前回のタスク実行でエラーが発生しました (リトライ %d/%d):
+		if head, err := runGit("rev-parse", "--verify", "HEAD"); err == nil && head != headBefore {
+			result.CommitSHA = head
+		}
+		result.Status = taskSucceeded
+		results = append(results, result)
+		if pushMode == config.PushTask {
+			pushChanges()
+		}
+		fmt.Printf("\n✅ Task %d completed\n\n", taskNum)
+		time.Sleep(1 * time.Second)
+	}
+
+	// Commit all changes of the run at once
+	if commitStrategy == config.CommitSquash {
+		err := commitWithHookFixes(
+			func() error { return commitSquashedChanges(tasks, taskFileName, results, f) },
+			nil, "squashed changes", nil, f)
+		if err != nil {
+			log.Printf("⚠️ Warning: Changes were left uncommitted: %v\n", err)
+		}
+	}
+
+	if pushMode == config.PushEnd {
+		pushChanges()
+	}
+
+	failedCount := countResults(results, taskFailed)
+	if keepGoing {
+		writeTaskSummary(results, f)
+	}
+
+	fmt.Printf("========================================\n")
+	if failedCount == 0 {
+		fmt.Printf("✅ All tasks completed successfully!\n")
+	} else {
+		fmt.Printf("❌ %d of %d tasks failed\n", failedCount, len(results))
+	}
+	fmt.Printf("========================================\n")
+	fmt.Printf("📝 Log file: %s\n", logFilePath)
+
+	// Record execution to history
+	recordHistory(failedCount == 0, summarizeFailures(results))
+
+	// Generate and display PR information
+	generatePRInfo(tasks, taskFileName, results)
+
+	if failedCount > 0 {
+		return fmt.Errorf("%d of %d tasks failed", failedCount, len(results))
+	}
+
+	return nil
+}
+
+// executeTaskWithRetries runs a task with Claude and verifies it, retrying
+// and asking Claude to fix errors up to maxRetries times. Agent calls and
+// verification runs are recorded in result.
+func executeTaskWithRetries(task Task, taskNum int, result *taskResult, f *os.File) error {
+	// Execute task with Claude with retry logic
+	taskRetryCount := 0
+	attempt := 0
+
+	for taskRetryCount <= maxRetries {
+		attempt++
+		err := executeTask(task, result, f)
+		if errors.Is(err, errBudgetExceeded) {
+			return err
+		}
+		commitAttemptChanges(task, taskNum, attempt, f)
+		if err == nil {
+			break
+		}
+
+		taskRetryCount++
+		if taskRetryCount > maxRetries {
+			log.Printf("❌ タスク %d が %d 回の試行後も失敗しました: %v\n", taskNum, maxRetries+1, err)
+			return fmt.Errorf("task %d failed after %d attempts: %w", taskNum, maxRetries+1, err)
+		}
+
+		log.Printf("❌ タスク %d の実行に失敗しました。リトライ %d/%d 回目を実行します\n", taskNum, taskRetryCount, maxRetries)
+		log.Printf("エラー内容: %v\n", err)
+
+		// Retry with error context
+		retryPrompt := fmt.Sprintf(`前回のタスク実行でエラーが発生しました (リトライ %d/%d):
 エラー: %v
 
 # タスク
@@ -280,58 +728,54 @@ func runSync(cmd *cobra.Command, args []string) error {
 
 実装を開始してください。`, taskRetryCount, maxRetries, err, task.Title, task.Description, projectDir)
 
-				if err := executeClaude(retryPrompt, f); err != nil {
-					log.Printf("❌ リトライ実行に失敗しました: %v\n", err)
-					// Continue to next retry attempt
-					continue
-				}
-
-				// Retry succeeded, break out of retry loop
-				lastErr = nil
-				break
-			}
-
-			// Task succeeded on first try
-			break
-		}
-
-		if lastErr != nil {
-			return fmt.Errorf("task %d failed after all retries: %w", taskNum, lastErr)
+		attempt++
+		err = callAgent(retryPrompt, result, f)
+		if errors.Is(err, errBudgetExceeded) {
+			return err
 		}
-
-		if taskRetryCount > 0 {
-			fmt.Printf("✅ タスク %d が %d 回のリトライ後に成功しました\n", taskNum, taskRetryCount)
+		commitAttemptChanges(task, taskNum, attempt, f)
+		if err != nil {
+			log.Printf("❌ リトライ実行に失敗しました: %v\n", err)
+			// Continue to next retry attempt
+			continue
 		}
 
-		// Run verification command with retry logic
-		if task.Command != "" {
-			fmt.Printf("\n🔍 Running verification: %s\n", task.Command)
+		// Retry succeeded, break out of retry loop
+		break
+	}
 
-			retryCount := 0
-			verificationPassed := false
+	if taskRetryCount > 0 {
+		fmt.Printf("✅ タスク %d が %d 回のリトライ後に成功しました\n", taskNum, taskRetryCount)
+	}
 
-			for retryCount <= maxRetries {
-				if err := runCommand(task.Command, f); err != nil {
-					retryCount++
+	commands := verificationCommands(task)
+	if len(commands) == 0 {
+		return nil
+	}
 
-					if retryCount > maxRetries {
-						log.Printf("❌ 検証が %d 回の試行後も失敗しました: %v\n", maxRetries+1, err)
-						log.Printf("実行を停止します。\n")
+	// Run verification commands with retry logic
+	fmt.Printf("\n🔍 Running verification: %s\n", strings.Join(commands, ", "))
 
-						// Record failed execution to history
-						duration := time.Since(startTime)
-						histErr := history.Record(projectDir, taskFile, branchName, false, duration, len(tasks), startFrom, maxRetries, fmt.Sprintf("Verification failed for task %d: %v", taskNum, err))
-						if histErr != nil {
-							log.Printf("⚠️ Warning: Failed to record history: %v\n", histErr)
-						}
+	for retryCount := 0; ; retryCount++ {
+		failedCommand, err := runVerification(commands, result, f)
+		if err == nil {
+			if retryCount > 0 {
+				fmt.Printf("✅ 検証が %d 回のリトライ後に成功しました\n", retryCount)
+			} else {
+				fmt.Printf("✅ Verification passed\n")
+			}
+			return nil
+		}
 
-						return fmt.Errorf("verification failed after %d attempts: %w", maxRetries+1, err)
-					}
+		if retryCount >= maxRetries {
+			log.Printf("❌ 検証が %d 回の試行後も失敗しました: %v\n", maxRetries+1, err)
+			return fmt.Errorf("verification failed for task %d after %d attempts: %w", taskNum, maxRetries+1, err)
+		}
 
-					log.Printf("❌ 検証失敗、修正を試みます（リトライ %d/%d 回目）: %v\n", retryCount, maxRetries, err)
+		log.Printf("❌ 検証失敗、修正を試みます（リトライ %d/%d 回目）: %v\n", retryCount+1, maxRetries, err)
 
-					// Attempt to fix
-					fixPrompt := fmt.Sprintf(`検証コマンドが失敗しました（リトライ %d/%d 回目）:
+		// Attempt to fix
+		fixPrompt := fmt.Sprintf(`検証コマンドが失敗しました（リトライ %d/%d 回目）:
 
 コマンド: %s
 エラー: %v
@@ -343,59 +787,22 @@ func runSync(cmd *cobra.Command, args []string) error {
 
 プロジェクトディレクトリ: %s
 
-修正を開始してください。`, retryCount, maxRetries, task.Command, err, projectDir)
-
-					if err := executeClaude(fixPrompt, f); err != nil {
-						log.Printf("❌ 修正の実行に失敗しました: %v\n", err)
-						// Continue to next retry attempt
-						continue
-					}
-
-					log.Printf("🔍 修正後、検証を再実行します...\n")
-					// Continue to retry verification
-					continue
-				}
-
-				// Verification passed
-				verificationPassed = true
-				if retryCount > 0 {
-					fmt.Printf("✅ 検証が %d 回のリトライ後に成功しました\n", retryCount)
-				} else {
-					fmt.Printf("✅ Verification passed\n")
-				}
-				break
-			}
+修正を開始してください。`, retryCount+1, maxRetries, failedCommand, err, projectDir)
 
-			if !verificationPassed {
-				return fmt.Errorf("verification failed after all retries")
-			}
+		attempt++
+		err = callAgent(fixPrompt, result, f)
+		if errors.Is(err, errBudgetExceeded) {
+			return err
 		}
-
-		// Commit changes for this task
-		if err := commitTaskChanges(task, taskNum, f); err != nil {
-			log.Printf("⚠️ Warning: Failed to commit changes: %v\n", err)
-			// Continue anyway - commit failure is not critical
+		commitAttemptChanges(task, taskNum, attempt, f)
+		if err != nil {
+			log.Printf("❌ 修正の実行に失敗しました: %v\n", err)
+			// Continue to next retry attempt
+			continue
 		}
 
-		fmt.Printf("\n✅ Task %d completed\n\n", taskNum)
-		time.Sleep(1 * time.Second)
+		log.Printf("🔍 修正後、検証を再実行します...\n")
 	}
-
-	fmt.Printf("========================================\n")
-	fmt.Printf("✅ All tasks completed successfully!\n")
-	fmt.Printf("========================================\n")
-	fmt.Printf("📝 Log file: %s\n", logFilePath)
-
-	// Record successful execution to history
-	duration := time.Since(startTime)
-	if err := history.Record(projectDir, taskFile, branchName, true, duration, len(tasks), startFrom, maxRetries, ""); err != nil {
-		log.Printf("⚠️ Warning: Failed to record history: %v\n", err)
-	}
-
-	// Generate and display PR information
-	generatePRInfo(tasks, taskFile)
-
-	return nil
 }
 
 func parseTaskFile(filename string) ([]Task, error) {
@@ -404,17 +811,21 @@ func parseTaskFile(filename string) ([]Task, error) {
 		return nil, err
 	}
 	defer func() { _ = file.Close() }()
+	return parseTasks(file)
+}
 
+// parseTasks parses the tasks of a task file.
+func parseTasks(r io.Reader) ([]Task, error) {
 	var tasks []Task
 	var currentTask *Task
 	var descLines []string
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := scanner.Text()
 
 		// Task title (starts with "## タスク" or "## Task")
-		if strings.HasPrefix(line, "## タスク") || strings.HasPrefix(line, "## Task") {
+		if isTaskHeading(line) {
 			// Save previous task
 			if currentTask != nil {
 				currentTask.Description = strings.Join(descLines, "\n")
@@ -429,12 +840,20 @@ func parseTaskFile(filename string) ([]Task, error) {
 			continue
 		}
 
+		// Dependencies on other tasks
+		if currentTask != nil {
+			if m := dependsPattern.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+				for _, num := range numberPattern.FindAllString(m[1], -1) {
+					if n, err := strconv.Atoi(num); err == nil {
+						currentTask.Depends = append(currentTask.Depends, n)
+					}
+				}
+			}
+		}
+
 		// Verification command (line starting with "- `")
-		if currentTask != nil && strings.HasPrefix(line, "- `") && strings.HasSuffix(line, "`") {
-			// Extract command from "- `command`" format
-			cmd := strings.TrimPrefix(line, "- `")
-			cmd = strings.TrimSuffix(cmd, "`")
-			currentTask.Command = cmd
+		if cmd, ok := verificationLine(line); currentTask != nil && ok {
+			currentTask.Commands = append(currentTask.Commands, cmd)
 			continue
 		}
 
@@ -453,7 +872,41 @@ func parseTaskFile(filename string) ([]Task, error) {
 	return tasks, scanner.Err()
 }
 
-func executeTask(task Task, logFile *os.File) error {
+// isTaskHeading reports whether line starts a task.
+func isTaskHeading(line string) bool {
+	return strings.HasPrefix(line, "## タスク") || strings.HasPrefix(line, "## Task")
+}
+
+// verificationLine returns the command of a verification command line in
+// "- `command`" format.
+func verificationLine(line string) (string, bool) {
+	if !strings.HasPrefix(line, "- `") || !strings.HasSuffix(line, "`") {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(line, "- `"), "`"), true
+}
+
+// verificationCommands returns the task's verification commands followed by
+// the configured pre-commit checks.
+func verificationCommands(task Task) []string {
+	commands := slices.Clone(task.Commands)
+	return append(commands, preCommitChecks...)
+}
+
+// runVerification runs the commands in order and returns the first one that
+// fails. Each run is recorded in result, which may be nil.
+func runVerification(commands []string, result *taskResult, logFile *os.File) (string, error) {
+	for _, command := range commands {
+		err := runCommand(command, logFile)
+		result.recordVerification(command, err)
+		if err != nil {
+			return command, err
+		}
+	}
+	return "", nil
+}
+
+func executeTask(task Task, result *taskResult, logFile *os.File) error {
 	prompt := fmt.Sprintf(`あなたは自律的にソフトウェア開発を行うエンジニアです。
 
 # タスク
@@ -471,28 +924,124 @@ func executeTask(task Task, logFile *os.File) error {
 
 実装を開始してください。`, task.Title, task.Description, projectDir)
 
-	return executeClaude(prompt, logFile)
+	return callAgent(prompt, result, logFile)
+}
+
+// taskFileArg returns the task file given as an argument, or the configured
+// default task file. A default from a config file is relative to the
+// directory of that file, so that it is found from subdirectories too.
+func taskFileArg(args []string, envConfig *config.EnvConfig, cfg *config.Config) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	taskFile := cfg.DefaultTaskFile
+	if taskFile == "" || filepath.IsAbs(taskFile) || envConfig.HasDefaultTaskFile() {
+		return taskFile, nil
+	}
+	if configPath := config.FindConfigPath(); configPath != "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get current directory: %w", err)
+		}
+		// Keep the path short when the config file is in the current directory
+		if rel, err := filepath.Rel(cwd, filepath.Join(filepath.Dir(configPath), taskFile)); err == nil {
+			return rel, nil
+		}
+		return filepath.Join(filepath.Dir(configPath), taskFile), nil
+	}
+	return taskFile, nil
+}
+
+// archiveRun stores the task file read from path, the merged configuration,
+// the sleepship and agent versions and the current commit in a run archive
+// and returns its hash. The task file is archived under taskFile.
+func archiveRun(path, taskFile string, cfg *config.Config) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read task file: %w", err)
+	}
+
+	archived := *cfg
+	archived.ProjectDir = recordDir
+	configJSON, err := json.Marshal(archived)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	gitHead, _ := runGit("rev-parse", "HEAD")
+	return history.SaveArchive(recordDir, history.Archive{
+		TaskFile:         taskFile,
+		Config:           configJSON,
+		SleepshipVersion: sleepshipVersion(),
+		AgentVersion:     agentVersion(),
+		GitHead:          gitHead,
+	}, content)
+}
+
+// agentVersion returns the version reported by the agent CLI, or an empty
+// string if it cannot be determined.
+func agentVersion() string {
+	output, err := exec.Command("claude", "--version").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
 }
 
-func executeClaude(prompt string, logFile *os.File) error {
-	cmd := exec.Command("claude", "-p", "--dangerously-skip-permissions")
+// executeClaude runs the agent with prompt and returns the usage it reports.
+// The agent runs in JSON output mode; its final message is printed once it
+// finishes.
+// The agent is stopped when the time budget of the run is used up.
+func executeClaude(prompt string, logFile *os.File) (history.Usage, error) {
+	ctx, cancel := agentContext()
+	defer cancel()
+
+	// stream-json needs --verbose in print mode
+	args := []string{"-p", "--dangerously-skip-permissions", "--output-format", "stream-json"}
+	if !slices.Contains(claudeFlags, "--verbose") {
+		args = append(args, "--verbose")
+	}
+	args = append(args, claudeFlags...)
+	cmd := exec.CommandContext(ctx, "claude", args...)
+	cmd.WaitDelay = agentWaitDelay
 	cmd.Stdin = strings.NewReader(prompt)
 	cmd.Dir = projectDir
 
-	// Output to both screen and log file
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
 	_, _ = fmt.Fprintf(logFile, "\n=== Claude Execution ===\n%s\n\n", time.Now().Format("2006-01-02 15:04:05"))
 	_, _ = logFile.WriteString(prompt)
 	_, _ = logFile.WriteString("\n\n")
 
+	// Show the agent's progress while it runs
+	stream := &agentStream{out: io.MultiWriter(os.Stdout, logFile)}
+	cmd.Stdout = stream
+	cmd.Stderr = os.Stderr
+
 	fmt.Println("🤖 Executing with Claude...")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("claude execution failed: %w", err)
+	runErr := cmd.Run()
+	stream.Flush()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		_, _ = fmt.Fprintf(logFile, "\n=== Claude Stopped: time budget of %s used up ===\n", formatDuration(maxDuration))
+		return history.Usage{}, timeBudgetError(time.Since(runStart))
 	}
 
-	return nil
+	result, parseErr := parseAgentResult(stream.result)
+	if parseErr != nil {
+		// The usage is unknown
+		if runErr != nil {
+			return history.Usage{}, fmt.Errorf("claude execution failed: %w", runErr)
+		}
+		log.Printf("⚠️ Warning: Failed to read agent usage: %v\n", parseErr)
+		return history.Usage{}, nil
+	}
+
+	usage := result.usage()
+	if runErr != nil {
+		return usage, fmt.Errorf("claude execution failed: %w", runErr)
+	}
+	if result.IsError {
+		return usage, fmt.Errorf("claude execution failed: %s", truncate(result.Result, maxRecordedErrorLen))
+	}
+	return usage, nil
 }
 
 func runCommand(command string, logFile *os.File) error {
@@ -586,16 +1135,69 @@ func sanitizeBranchName(name string) string {
 	return name
 }
 
+// newRunID returns the ID of a run started at start. It names the log file
+// and the refs of failed attempts, so runs of workspace members started at
+// the same moment are told apart by the member name.
+func newRunID(start time.Time, member string) string {
+	id := start.Format("20060102-150405.000")
+	if member != "" {
+		id += "-" + sanitizeBranchName(member)
+	}
+	return id
+}
+
+// featureBranchName returns the branch a run of taskFile creates. Member
+// runs include the member name, since the members of a monorepo often use
+// task files with the same name.
+func featureBranchName(taskFile string) string {
+	name := sanitizeBranchName(filepath.Base(taskFile))
+	if workspaceMember != "" {
+		name = sanitizeBranchName(workspaceMember) + "-" + name
+	}
+	return "feature/" + name
+}
+
+// setUpRunBranch checks out the branch given with --branch or creates the
+// feature branch of taskFile. It returns the branch, or an empty string if
+// it could not be checked out, and whether sleepship created it. A run
+// continues without its branch, but is then never pushed.
+func setUpRunBranch(taskFile string, logFile *os.File) (string, bool) {
+	if branchOverride != "" {
+		created, err := checkoutBranch(branchOverride, logFile)
+		if err != nil {
+			log.Printf("⚠️ Warning: Failed to check out branch: %v\n", err)
+			return "", false
+		}
+		return branchOverride, created
+	}
+	if err := createBranchForSync(taskFile, logFile); err != nil {
+		log.Printf("⚠️ Warning: Failed to create branch: %v\n", err)
+		// Continue anyway - branch creation is not critical
+		return "", false
+	}
+	return featureBranchName(taskFile), true
+}
+
+// newBranchBase returns what a new branch starts from: the branch base, or
+// else the start point of a workspace member run. An empty string means HEAD.
+func newBranchBase() string {
+	if branchBase != "" {
+		return branchBase
+	}
+	return startPoint
+}
+
 func createBranchForSync(taskFile string, logFile *os.File) error {
-	// Extract filename from path
-	filename := filepath.Base(taskFile)
-	sanitized := sanitizeBranchName(filename)
-	branchName := fmt.Sprintf("feature/%s", sanitized)
+	branchName := featureBranchName(taskFile)
 
 	fmt.Printf("🌿 Creating branch: %s\n", branchName)
 	_, _ = fmt.Fprintf(logFile, "\n=== Creating Branch: %s ===\n", branchName)
 
-	cmd := exec.Command("git", "checkout", "-b", branchName)
+	args := []string{"checkout", "-b", branchName}
+	if base := newBranchBase(); base != "" {
+		args = append(args, base)
+	}
+	cmd := exec.Command("git", args...)
 	cmd.Dir = projectDir
 
 	output, err := cmd.CombinedOutput()
@@ -609,11 +1211,72 @@ func createBranchForSync(taskFile string, logFile *os.File) error {
 	return nil
 }
 
+// checkoutBranch checks out branch, creating it from HEAD if it does not
+// exist, and reports whether it was created.
+func checkoutBranch(branch string, logFile *os.File) (bool, error) {
+	args := []string{"checkout", branch}
+	_, err := runGit("rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	created := err != nil
+	if created {
+		args = []string{"checkout", "-b", branch}
+		if base := newBranchBase(); base != "" {
+			args = append(args, base)
+		}
+	}
+
+	fmt.Printf("🌿 Checking out branch: %s\n", branch)
+	_, _ = fmt.Fprintf(logFile, "\n=== Checking Out Branch: %s ===\n", branch)
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = projectDir
+
+	output, err := cmd.CombinedOutput()
+	_, _ = logFile.Write(output)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to check out branch: %w\nOutput: %s", err, string(output))
+	}
+
+	fmt.Printf("✅ On branch: %s\n\n", branch)
+	return created, nil
+}
+
 func commitTaskChanges(task Task, taskNumber int, logFile *os.File) error {
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	commitMessage := fmt.Sprintf("タスク%d: %s (%s)", taskNumber, task.Title, timestamp)
 
-	fmt.Printf("\n💾 Committing changes: %s\n", commitMessage)
+	return commitChanges(commitMessage, logFile)
+}
+
+// commitAttemptChanges commits the result of a single agent call when the
+// per-attempt commit strategy is active. Failures are logged, not returned,
+// so that a commit problem never aborts the retry loop.
+func commitAttemptChanges(task Task, taskNumber, attempt int, logFile *os.File) {
+	if commitStrategy != config.CommitPerAttempt {
+		return
+	}
+
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	commitMessage := fmt.Sprintf("タスク%d: %s [試行%d] (%s)", taskNumber, task.Title, attempt, timestamp)
+
+	if err := commitChanges(commitMessage, logFile); err != nil {
+		log.Printf("⚠️ Warning: Failed to commit attempt %d of task %d: %v\n", attempt, taskNumber, err)
+	}
+}
+
+// commitSquashedChanges creates a single commit containing the changes of all
+// tasks, using the generated PR title and body as the commit message.
+func commitSquashedChanges(tasks []Task, taskFile string, results []taskResult, logFile *os.File) error {
+	featureName := sanitizeBranchName(filepath.Base(taskFile))
+	commitMessage := generatePRTitle(tasks, featureName) + "\n\n" + generatePRBody(tasks, results)
+
+	return commitChanges(commitMessage, logFile)
+}
+
+// commitChanges stages all changes in the project directory and commits them.
+func commitChanges(commitMessage string, logFile *os.File) error {
+	subject, _, _ := strings.Cut(commitMessage, "\n")
+	fmt.Printf("\n💾 Committing changes: %s\n", subject)
 	_, _ = logFile.WriteString("\n=== Committing Changes ===\n")
 
 	// Add all changes
@@ -638,6 +1301,10 @@ func commitTaskChanges(task Task, taskNumber int, logFile *os.File) error {
 			fmt.Printf("ℹ️ No changes to commit\n")
 			return nil
 		}
+		// Check if a git hook rejected the commit
+		if hooks := activeCommitHooks(); len(hooks) > 0 && hookRejectedCommit(err) {
+			return &commitHookError{Hooks: hooks, Output: string(commitOutput)}
+		}
 		return fmt.Errorf("failed to commit: %w\nOutput: %s", err, string(commitOutput))
 	}
 
@@ -645,7 +1312,7 @@ func commitTaskChanges(task Task, taskNumber int, logFile *os.File) error {
 	return nil
 }
 
-func generatePRInfo(tasks []Task, taskFile string) {
+func generatePRInfo(tasks []Task, taskFile string, results []taskResult) {
 	// Extract feature name from task file
 	filename := filepath.Base(taskFile)
 	featureName := sanitizeBranchName(filename)
@@ -654,7 +1321,7 @@ func generatePRInfo(tasks []Task, taskFile string) {
 	prTitle := generatePRTitle(tasks, featureName)
 
 	// Generate PR body
-	prBody := generatePRBody(tasks)
+	prBody := generatePRBody(tasks, results)
 
 	// Display PR information
 	fmt.Printf("\n========================================\n")
@@ -685,7 +1352,9 @@ func generatePRTitle(tasks []Task, featureName string) string {
 	return fmt.Sprintf("%sの実装", featureName)
 }
 
-func generatePRBody(tasks []Task) string {
+// generatePRBody builds the PR description. When results contain failed or
+// skipped tasks, an execution result table is included.
+func generatePRBody(tasks []Task, results []taskResult) string {
 	var body strings.Builder
 
 	body.WriteString("## 概要\n\n")
@@ -700,21 +1369,33 @@ func generatePRBody(tasks []Task) string {
 		body.WriteString(fmt.Sprintf("%d. %s\n", i+1, taskTitle))
 	}
 
+	if countResults(results, taskSucceeded) < len(results) {
+		body.WriteString("\n## 実行結果\n\n")
+		body.WriteString(fmt.Sprintf("成功: %d / 失敗: %d / スキップ: %d\n\n",
+			countResults(results, taskSucceeded), countResults(results, taskFailed), countResults(results, taskSkipped)))
+		body.WriteString("| # | タスク | 結果 | 備考 |\n")
+		body.WriteString("|---|--------|------|------|\n")
+		for _, result := range results {
+			reason := strings.ReplaceAll(result.Reason, "\n", " ")
+			reason = strings.ReplaceAll(reason, "|", "\\|")
+			body.WriteString(fmt.Sprintf("| %d | %s | %s %s | %s |\n", result.Number, result.Title, statusIcon(result.Status), result.Status, reason))
+		}
+	}
+
 	body.WriteString("\n## テスト\n\n")
 	body.WriteString("各タスク完了時に以下の確認を実施済み:\n\n")
 
-	// Collect unique verification commands
+	// Collect unique verification commands in the order they are run
 	verificationCmds := make(map[string]bool)
 	for _, task := range tasks {
-		if task.Command != "" {
-			verificationCmds[task.Command] = true
+		for _, cmd := range task.Commands {
+			if !verificationCmds[cmd] {
+				verificationCmds[cmd] = true
+				body.WriteString(fmt.Sprintf("- `%s`\n", cmd))
+			}
 		}
 	}
 
-	for cmd := range verificationCmds {
-		body.WriteString(fmt.Sprintf("- `%s`\n", cmd))
-	}
-
 	body.WriteString("\n## 備考\n\n")
 	body.WriteString("このPRは自律開発ツール（sleepship）により自動生成されました。\n")
 
@@ -734,6 +1415,75 @@ func spawnBackgroundWorker(taskFile string) error {
 		targetDir = cwd
 	}
 
+	// Member runs are started from where the workspace was found
+	workDir := cwd
+	if workspaceMember != "" {
+		workDir = memberWorkDir
+	}
+
+	// Build command arguments
+	cmdArgs := []string{"sync", taskFile, "--worker"}
+	if workspaceMember != "" {
+		cmdArgs = append(cmdArgs, "--member", workspaceMember)
+	} else if projectDir != "" {
+		cmdArgs = append(cmdArgs, "--dir", projectDir)
+	}
+	if logDir != "logs" {
+		cmdArgs = append(cmdArgs, "--log-dir", logDir)
+	}
+	if startFrom != 1 {
+		cmdArgs = append(cmdArgs, "--start-from", fmt.Sprintf("%d", startFrom))
+	}
+	if maxRetries != 3 {
+		cmdArgs = append(cmdArgs, "--max-retries", fmt.Sprintf("%d", maxRetries))
+	}
+	if commitStrategy != config.CommitPerTask {
+		cmdArgs = append(cmdArgs, "--commit-strategy", commitStrategy)
+	}
+	if rollbackPolicy != config.RollbackLeave {
+		cmdArgs = append(cmdArgs, "--rollback", rollbackPolicy)
+	}
+	if keepGoing {
+		cmdArgs = append(cmdArgs, "--keep-going")
+	}
+	for _, check := range preCommitChecks {
+		cmdArgs = append(cmdArgs, "--pre-commit-check", check)
+	}
+	if pushMode != config.PushNever {
+		cmdArgs = append(cmdArgs, "--push", pushMode)
+	}
+	if pushRemote != "origin" {
+		cmdArgs = append(cmdArgs, "--push-remote", pushRemote)
+	}
+	if maxDuration != 0 {
+		cmdArgs = append(cmdArgs, "--max-duration", maxDurationFlag)
+	}
+	if maxAgentCalls != 0 {
+		cmdArgs = append(cmdArgs, "--max-agent-calls", strconv.Itoa(maxAgentCalls))
+	}
+	if maxCost != 0 {
+		cmdArgs = append(cmdArgs, "--max-cost", strconv.FormatFloat(maxCost, 'f', -1, 64))
+	}
+	if branchOverride != "" {
+		cmdArgs = append(cmdArgs, "--branch", branchOverride)
+	}
+	if branchBase != "" {
+		cmdArgs = append(cmdArgs, "--branch-base", branchBase)
+	}
+	if recordedTaskFile != "" {
+		cmdArgs = append(cmdArgs, "--recorded-task-file", recordedTaskFile)
+	}
+	if startPoint != "" {
+		cmdArgs = append(cmdArgs, "--start-point", startPoint)
+	}
+
+	return startWorker(targetDir, workDir, cmdArgs)
+}
+
+// startWorker starts sleepship with cmdArgs as a background process in
+// workDir, writing its output to a new log file in the log directory of
+// targetDir.
+func startWorker(targetDir, workDir string, cmdArgs []string) error {
 	// Create log directory
 	absLogDir := filepath.Join(targetDir, logDir)
 	if err := os.MkdirAll(absLogDir, 0755); err != nil {
@@ -757,24 +1507,9 @@ func spawnBackgroundWorker(taskFile string) error {
 		return fmt.Errorf("failed to get executable path: %w", err)
 	}
 
-	// Build command arguments
-	cmdArgs := []string{"sync", taskFile, "--worker"}
-	if projectDir != "" {
-		cmdArgs = append(cmdArgs, "--dir", projectDir)
-	}
-	if logDir != "logs" {
-		cmdArgs = append(cmdArgs, "--log-dir", logDir)
-	}
-	if startFrom != 1 {
-		cmdArgs = append(cmdArgs, "--start-from", fmt.Sprintf("%d", startFrom))
-	}
-	if maxRetries != 3 {
-		cmdArgs = append(cmdArgs, "--max-retries", fmt.Sprintf("%d", maxRetries))
-	}
-
 	// Start background process
 	cmd := exec.Command(executable, cmdArgs...)
-	cmd.Dir = cwd
+	cmd.Dir = workDir
 	cmd.Stdout = logFile
 	cmd.Stderr = logFile
 
@@ -3,7 +3,13 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/isiidaisuke0926/sleepship/internal/config"
 )
 
 func TestTaskSkipLogic(t *testing.T) {
@@ -180,6 +186,7 @@ func TestParseTaskFile(t *testing.T) {
 
 ### 確認
 - ` + "`test -f test2.txt && test -f test3.txt`" + `
+- ` + "`test -s test3.txt`" + `
 `
 
 	tmpFile := "../test_parse_temp.txt"
@@ -217,10 +224,15 @@ func TestParseTaskFile(t *testing.T) {
 
 	// Verify all tasks have verification commands
 	for i, task := range tasks {
-		if task.Command == "" {
+		if len(task.Commands) == 0 {
 			t.Errorf("Task %d should have a verification command", i+1)
 		}
 	}
+
+	// Every verification command of a task is kept, in order
+	if want := []string{"test -f test2.txt && test -f test3.txt", "test -s test3.txt"}; !slices.Equal(tasks[2].Commands, want) {
+		t.Errorf("Task 3 commands = %q, want %q", tasks[2].Commands, want)
+	}
 }
 
 // Helper functions for test file operations
@@ -605,3 +617,231 @@ func TestDepthLimit(t *testing.T) {
 		})
 	}
 }
+
+// initTestRepo creates a git repository with an initial commit in a temporary
+// directory and points projectDir at it for the duration of the test.
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		gitCmd := exec.Command("git", args...)
+		gitCmd.Dir = dir
+		if output, err := gitCmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	oldProjectDir := projectDir
+	projectDir = dir
+	t.Cleanup(func() { projectDir = oldProjectDir })
+
+	return dir
+}
+
+// gitOutput runs a git command in dir and returns its trimmed output.
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	gitCmd := exec.Command("git", args...)
+	gitCmd.Dir = dir
+	output, err := gitCmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestCommitStrategies(t *testing.T) {
+	tasks := []Task{
+		{Title: "1: First"},
+		{Title: "2: Second"},
+	}
+
+	tests := []struct {
+		name        string
+		strategy    string
+		wantCommits int
+	}{
+		{name: "per-attempt commits every agent call", strategy: config.CommitPerAttempt, wantCommits: 3},
+		{name: "per-task ignores attempts", strategy: config.CommitPerTask, wantCommits: 1},
+		{name: "squash commits once at the end", strategy: config.CommitSquash, wantCommits: 2},
+		{name: "none never commits", strategy: config.CommitNone, wantCommits: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := initTestRepo(t)
+			logFile, err := os.CreateTemp(t.TempDir(), "sync-*.log")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = logFile.Close() }()
+
+			oldStrategy := commitStrategy
+			commitStrategy = tt.strategy
+			defer func() { commitStrategy = oldStrategy }()
+
+			// Simulate two agent calls for the first task
+			for attempt := 1; attempt <= 2; attempt++ {
+				path := filepath.Join(dir, fmt.Sprintf("attempt%d.txt", attempt))
+				if err := os.WriteFile(path, []byte("change"), 0600); err != nil {
+					t.Fatal(err)
+				}
+				commitAttemptChanges(tasks[0], 1, attempt, logFile)
+			}
+
+			if tt.strategy == config.CommitSquash {
+				if err := commitSquashedChanges(tasks, "tasks-feature.txt", nil, logFile); err != nil {
+					t.Fatalf("commitSquashedChanges() error = %v", err)
+				}
+			}
+
+			count := gitOutput(t, dir, "rev-list", "--count", "HEAD")
+			if count != fmt.Sprintf("%d", tt.wantCommits) {
+				t.Errorf("commit count = %s, want %d", count, tt.wantCommits)
+			}
+
+			status := gitOutput(t, dir, "status", "--porcelain")
+			wantDirty := tt.strategy == config.CommitPerTask || tt.strategy == config.CommitNone
+			if (status != "") != wantDirty {
+				t.Errorf("working tree dirty = %v, want %v (status: %q)", status != "", wantDirty, status)
+			}
+		})
+	}
+}
+
+func TestParseTaskFileDependencies(t *testing.T) {
+	content := `## タスク1: モデル作成
+
+### 確認
+- ` + "`go build`" + `
+
+## タスク2: API実装
+依存: 1
+
+## Task 3: Docs
+- Depends on: Task 1, Task 2
+
+## Task 4: Independent
+`
+
+	tmpFile := filepath.Join(t.TempDir(), "tasks.txt")
+	if err := writeFile(tmpFile, content); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	tasks, err := parseTaskFile(tmpFile)
+	if err != nil {
+		t.Fatalf("Failed to parse task file: %v", err)
+	}
+	if len(tasks) != 4 {
+		t.Fatalf("Expected 4 tasks, got %d", len(tasks))
+	}
+
+	want := [][]int{nil, {1}, {1, 2}, nil}
+	for i, deps := range want {
+		if fmt.Sprint(tasks[i].Depends) != fmt.Sprint(deps) {
+			t.Errorf("Task %d depends = %v, want %v", i+1, tasks[i].Depends, deps)
+		}
+	}
+}
+
+func TestCheckoutBranch(t *testing.T) {
+	dir := initTestRepo(t)
+	logFile, err := os.CreateTemp(t.TempDir(), "sync-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = logFile.Close() }()
+
+	created, err := checkoutBranch("feature/rerun", logFile)
+	if err != nil || !created {
+		t.Fatalf("checkoutBranch() new branch = %v, %v, want created", created, err)
+	}
+
+	gitOutput(t, dir, "checkout", "-q", "-")
+	created, err = checkoutBranch("feature/rerun", logFile)
+	if err != nil || created {
+		t.Fatalf("checkoutBranch() existing branch = %v, %v, want not created", created, err)
+	}
+	if got := gitOutput(t, dir, "symbolic-ref", "--short", "HEAD"); got != "feature/rerun" {
+		t.Errorf("current branch = %s, want feature/rerun", got)
+	}
+}
+
+func TestTaskFileArg(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(config.ConfigEnvVar, "")
+
+	// repo/.sleepship.toml sets the default task file, run from repo/sub
+	repo := t.TempDir()
+	subDir := filepath.Join(repo, "sub")
+	for _, dir := range []string{filepath.Join(repo, ".git"), subDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(repo, config.ConfigFileName), []byte("[sync]\ndefault_task_file = \"tasks/nightly.txt\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(oldDir) }()
+	_ = os.Chdir(subDir)
+
+	fileConfig, err := config.LoadFileConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := config.MergeLayers(config.NewDefaultConfig(), config.FromFile(fileConfig))
+
+	tests := []struct {
+		name string
+		args []string
+		env  *config.EnvConfig
+		want string
+	}{
+		{name: "argument", args: []string{"tasks.txt"}, env: &config.EnvConfig{}, want: "tasks.txt"},
+		{name: "relative to the config file", env: &config.EnvConfig{}, want: filepath.Join("..", "tasks", "nightly.txt")},
+		{name: "environment variable", env: &config.EnvConfig{DefaultTaskFile: "tasks/nightly.txt"}, want: "tasks/nightly.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := taskFileArg(tt.args, tt.env, merged)
+			if err != nil {
+				t.Fatalf("taskFileArg() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("taskFileArg() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractGlobalFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantArgs  []string
+		wantFlags map[string]string
+	}{
+		{name: "no flag", args: []string{"sync", "tasks.txt"}, wantArgs: []string{"sync", "tasks.txt"}, wantFlags: map[string]string{}},
+		{name: "separate value", args: []string{"--config", "ci.toml", "dev"}, wantArgs: []string{"dev"}, wantFlags: map[string]string{"config": "ci.toml"}},
+		{name: "equals value", args: []string{"--config=ci.toml", "sync", "x"}, wantArgs: []string{"sync", "x"}, wantFlags: map[string]string{"config": "ci.toml"}},
+		{name: "several flags", args: []string{"--context", "work", "--config=ci.toml", "dev"}, wantArgs: []string{"dev"}, wantFlags: map[string]string{"config": "ci.toml", "context": "work"}},
+		{name: "after the command", args: []string{"dev", "--config", "ci.toml"}, wantArgs: []string{"dev", "--config", "ci.toml"}, wantFlags: map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, flags := extractGlobalFlags(tt.args)
+			if strings.Join(args, " ") != strings.Join(tt.wantArgs, " ") || fmt.Sprint(flags) != fmt.Sprint(tt.wantFlags) {
+				t.Errorf("extractGlobalFlags() = %v, %v, want %v, %v", args, flags, tt.wantArgs, tt.wantFlags)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/isiidaisuke0926/sleepship/internal/config"
+	"github.com/isiidaisuke0926/sleepship/internal/tasktemplate"
+	"github.com/spf13/cobra"
+)
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Manage task file templates",
+	Long: `Manage the templates that "sleepship init" creates task files from.
+
+Besides the built-in templates, a template can be any task file saved as
+<name>.txt or <name>.md in .sleepship/templates of the project (or one of its
+parents up to the repository root) or in ~/.sleepship/templates. A template
+there hides a built-in template with the same name.
+
+Templates may contain variables, set with "sleepship init --var name=value":
+
+  ${name}           the value of name (required)
+  ${name:-default}  the value of name, or default if it is not given
+  $$                a literal $
+
+A ${NAME} without a default in a verification command is left for the
+shell, unless the template uses it elsewhere or it is set with --var.
+
+Examples:
+  sleepship template list
+  sleepship init --template tdd --var feature="rate limiting"`,
+}
+
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List task file templates",
+	Args:  cobra.NoArgs,
+	RunE:  runTemplateList,
+}
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateListCmd)
+}
+
+func runTemplateList(_ *cobra.Command, _ []string) error {
+	templates, err := tasktemplate.List(config.TemplateDirs())
+	if err != nil {
+		return err
+	}
+
+	nameLen := 0
+	for _, t := range templates {
+		nameLen = max(nameLen, len(t.Name))
+	}
+
+	fmt.Printf("Templates (%d):\n\n", len(templates))
+	for _, t := range templates {
+		marker := " "
+		if t.Name == tasktemplate.DefaultName {
+			marker = "*"
+		}
+		source := t.Source
+		if source != tasktemplate.BuiltinSource {
+			source = displayPath(source)
+		}
+		fmt.Printf("%s %-*s  %s [%s]\n", marker, nameLen, t.Name, valueOrDash(t.Description), source)
+		if vars := t.Variables(); len(vars) > 0 {
+			fmt.Printf("  %-*s  vars: %s\n", nameLen, "", formatTemplateVars(vars))
+		}
+	}
+	fmt.Printf("\n* Default template. Use one with: sleepship init --template <name> [--var name=value]\n")
+	fmt.Printf("  Variables are written ${name} or ${name:-default}; write $$ for a literal $.\n")
+	return nil
+}
+
+// formatTemplateVars formats the variables of a template as name=default,
+// with required variables marked.
+func formatTemplateVars(vars []tasktemplate.Variable) string {
+	formatted := make([]string, len(vars))
+	for i, v := range vars {
+		if v.HasDefault {
+			formatted[i] = fmt.Sprintf("%s=%q", v.Name, v.Default)
+		} else {
+			formatted[i] = v.Name + " (required)"
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
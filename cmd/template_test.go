@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/isiidaisuke0926/sleepship/internal/tasktemplate"
+)
+
+func TestBuiltinTemplatesParse(t *testing.T) {
+	templates, err := tasktemplate.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tmpl := range templates {
+		t.Run(tmpl.Name, func(t *testing.T) {
+			values := make(map[string]string)
+			for _, v := range tmpl.Variables() {
+				if !v.HasDefault {
+					values[v.Name] = "value of " + v.Name
+				}
+			}
+			content, err := tmpl.Render(values)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+
+			path := filepath.Join(t.TempDir(), "tasks.txt")
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tasks, err := parseTaskFile(path)
+			if err != nil {
+				t.Fatalf("parseTaskFile() error = %v", err)
+			}
+			if len(tasks) == 0 {
+				t.Fatal("template has no tasks")
+			}
+			checks := templateChecks(content)
+			if len(checks) != len(tasks) {
+				t.Fatalf("template has %d tasks, parsed %d", len(checks), len(tasks))
+			}
+			for i, task := range tasks {
+				if len(task.Commands) == 0 || !slices.Equal(task.Commands, checks[i]) {
+					t.Errorf("task %d (%s) runs %q, want %q", i+1, task.Title, task.Commands, checks[i])
+				}
+			}
+			problems, err := lintTaskFile(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, problem := range problems {
+				t.Errorf("lint: %s", problem)
+			}
+		})
+	}
+}
+
+// templateChecks returns the verification lines written under each task of
+// a task file.
+func templateChecks(content string) [][]string {
+	var checks [][]string
+	for _, line := range strings.Split(content, "\n") {
+		if isTaskHeading(line) {
+			checks = append(checks, nil)
+			continue
+		}
+		if cmd, ok := verificationLine(line); ok && len(checks) > 0 {
+			checks[len(checks)-1] = append(checks[len(checks)-1], cmd)
+		}
+	}
+	return checks
+}
+
+func TestParseTemplateVars(t *testing.T) {
+	vars, err := parseTemplateVars([]string{"module=example.com/x", "goal=a=b", "empty="})
+	if err != nil {
+		t.Fatalf("parseTemplateVars() error = %v", err)
+	}
+	if vars["module"] != "example.com/x" || vars["goal"] != "a=b" || vars["empty"] != "" || len(vars) != 3 {
+		t.Errorf("parseTemplateVars() = %v", vars)
+	}
+	for _, arg := range []string{"module", "=value"} {
+		if _, err := parseTemplateVars([]string{arg}); err == nil {
+			t.Errorf("parseTemplateVars(%q) succeeded", arg)
+		}
+	}
+}
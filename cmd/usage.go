@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/isiidaisuke0926/sleepship/internal/history"
+)
+
+// agentResult is the result event printed by "claude -p --output-format stream-json".
+type agentResult struct {
+	IsError      bool    `json:"is_error"`
+	Result       string  `json:"result"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	Usage        struct {
+		InputTokens              int64 `json:"input_tokens"`
+		OutputTokens             int64 `json:"output_tokens"`
+		CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+	} `json:"usage"`
+	ModelUsage map[string]json.RawMessage `json:"modelUsage"`
+}
+
+// parseAgentResult parses the JSON output of the agent CLI. If the output
+// contains other lines, the last line is used.
+func parseAgentResult(output []byte) (*agentResult, error) {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return nil, fmt.Errorf("no output from agent")
+	}
+
+	var result agentResult
+	if err := json.Unmarshal(output, &result); err != nil {
+		i := bytes.LastIndexByte(output, '\n')
+		if i < 0 || json.Unmarshal(output[i+1:], &result) != nil {
+			return nil, fmt.Errorf("failed to parse agent output: %w", err)
+		}
+	}
+	return &result, nil
+}
+
+// agentEvent is an event printed by "claude -p --output-format stream-json".
+// Only the fields shown as progress are decoded.
+type agentEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+			Name string `json:"name"`
+		} `json:"content"`
+	} `json:"message"`
+}
+
+// agentStream prints the agent's events to out as they arrive and keeps the
+// final result event. Lines that are not events are printed unchanged.
+type agentStream struct {
+	out    io.Writer
+	line   []byte // Incomplete last line
+	result []byte // Last result event
+}
+
+func (s *agentStream) Write(p []byte) (int, error) {
+	s.line = append(s.line, p...)
+	for {
+		i := bytes.IndexByte(s.line, '\n')
+		if i < 0 {
+			break
+		}
+		s.handleLine(s.line[:i])
+		s.line = s.line[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush handles output left after the last newline.
+func (s *agentStream) Flush() {
+	if len(s.line) > 0 {
+		s.handleLine(s.line)
+		s.line = nil
+	}
+}
+
+func (s *agentStream) handleLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+
+	var event agentEvent
+	if json.Unmarshal(line, &event) != nil || event.Type == "" {
+		_, _ = fmt.Fprintf(s.out, "%s\n", line)
+		return
+	}
+
+	switch event.Type {
+	case "assistant":
+		for _, block := range event.Message.Content {
+			switch block.Type {
+			case "text":
+				_, _ = fmt.Fprintln(s.out, block.Text)
+			case "tool_use":
+				_, _ = fmt.Fprintf(s.out, "🔧 %s\n", block.Name)
+			}
+		}
+	case "result":
+		s.result = slices.Clone(line)
+	}
+}
+
+// usage converts the reported usage into a history record.
+func (r *agentResult) usage() history.Usage {
+	usage := history.Usage{
+		InputTokens:              r.Usage.InputTokens,
+		OutputTokens:             r.Usage.OutputTokens,
+		CacheCreationInputTokens: r.Usage.CacheCreationInputTokens,
+		CacheReadInputTokens:     r.Usage.CacheReadInputTokens,
+		CostUSD:                  r.TotalCostUSD,
+	}
+	for model := range r.ModelUsage {
+		usage.Models = append(usage.Models, model)
+	}
+	sort.Strings(usage.Models)
+	return usage
+}
+
+// callAgent runs the agent with prompt unless the run is over budget, and
+// records the call and its usage in result and the run usage.
+func callAgent(prompt string, result *taskResult, logFile *os.File) error {
+	if err := checkBudget(); err != nil {
+		return err
+	}
+
+	usage, err := executeClaude(prompt, logFile)
+	runAgentCalls++
+	runUsage.Add(usage)
+	result.recordAgentCall(usage, err)
+
+	if !usage.IsZero() {
+		usageInfo := fmt.Sprintf("💰 Usage: %s (run total: %s)\n", formatUsage(usage), formatCost(runUsage.CostUSD))
+		fmt.Print(usageInfo)
+		_, _ = logFile.WriteString(usageInfo)
+	}
+	return err
+}
+
+// usageRecord returns a pointer to usage for history, or nil if nothing was
+// recorded.
+func usageRecord(usage history.Usage) *history.Usage {
+	if usage.IsZero() {
+		return nil
+	}
+	return &usage
+}
+
+// formatCost formats a cost in USD.
+func formatCost(usd float64) string {
+	return fmt.Sprintf("$%.2f", usd)
+}
+
+// formatTokens formats a token count, abbreviating thousands and millions.
+func formatTokens(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// formatUsage formats usage as token counts and cost.
+func formatUsage(usage history.Usage) string {
+	return fmt.Sprintf("%s in / %s out tokens, %s",
+		formatTokens(usage.InputTokens+usage.CacheCreationInputTokens+usage.CacheReadInputTokens),
+		formatTokens(usage.OutputTokens), formatCost(usage.CostUSD))
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/isiidaisuke0926/sleepship/internal/history"
+)
+
+func TestParseAgentResult(t *testing.T) {
+	output := `{"type":"result","subtype":"success","is_error":false,"result":"Done","total_cost_usd":0.0123,
+"usage":{"input_tokens":12,"cache_creation_input_tokens":300,"cache_read_input_tokens":4000,"output_tokens":56},
+"modelUsage":{"claude-sonnet":{"costUSD":0.01},"claude-haiku":{"costUSD":0.0023}}}`
+
+	result, err := parseAgentResult([]byte(output))
+	if err != nil {
+		t.Fatalf("parseAgentResult() error = %v", err)
+	}
+	if result.Result != "Done" || result.IsError {
+		t.Errorf("parseAgentResult() = %+v", result)
+	}
+
+	usage := result.usage()
+	want := history.Usage{
+		InputTokens:              12,
+		OutputTokens:             56,
+		CacheCreationInputTokens: 300,
+		CacheReadInputTokens:     4000,
+		CostUSD:                  0.0123,
+	}
+	if usage.InputTokens != want.InputTokens || usage.OutputTokens != want.OutputTokens ||
+		usage.CacheCreationInputTokens != want.CacheCreationInputTokens || usage.CacheReadInputTokens != want.CacheReadInputTokens ||
+		usage.CostUSD != want.CostUSD {
+		t.Errorf("usage() = %+v, want %+v", usage, want)
+	}
+	if !slices.Equal(usage.Models, []string{"claude-haiku", "claude-sonnet"}) {
+		t.Errorf("usage() models = %v", usage.Models)
+	}
+
+	// Output printed before the result line is ignored
+	if result, err := parseAgentResult([]byte("warning: something\n{\"result\":\"ok\",\"total_cost_usd\":1}\n")); err != nil || result.TotalCostUSD != 1 {
+		t.Errorf("parseAgentResult() with leading output = %+v, %v", result, err)
+	}
+
+	for _, output := range []string{"", "plain text output"} {
+		if _, err := parseAgentResult([]byte(output)); err == nil {
+			t.Errorf("parseAgentResult(%q) expected error", output)
+		}
+	}
+}
+
+func TestAgentStream(t *testing.T) {
+	var out bytes.Buffer
+	stream := &agentStream{out: &out}
+
+	events := `{"type":"system","subtype":"init"}
+{"type":"assistant","message":{"content":[{"type":"text","text":"Reading the code"},{"type":"tool_use","name":"Bash","input":{}}]}}
+{"type":"user","message":{"content":[{"type":"tool_result","content":"ok"}]}}
+warning: something
+{"type":"result","is_error":false,"result":"Done","total_cost_usd":0.5}`
+
+	// Write in small pieces, as a pipe may split lines
+	for chunk := range slices.Chunk([]byte(events), 7) {
+		if _, err := stream.Write(chunk); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if stream.result != nil {
+		t.Errorf("result read before its line was complete: %q", stream.result)
+	}
+	stream.Flush()
+
+	want := "Reading the code\n🔧 Bash\nwarning: something\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	result, err := parseAgentResult(stream.result)
+	if err != nil || result.Result != "Done" || result.TotalCostUSD != 0.5 {
+		t.Errorf("result = %+v, %v", result, err)
+	}
+}
+
+func TestFormatUsage(t *testing.T) {
+	usage := history.Usage{InputTokens: 500, CacheReadInputTokens: 12000, OutputTokens: 2500000, CostUSD: 1.234}
+	if got := formatUsage(usage); got != "12.5k in / 2.5M out tokens, $1.23" {
+		t.Errorf("formatUsage() = %q", got)
+	}
+}
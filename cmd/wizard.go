@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// noneAnswer clears the default answer of a wizard question
+const noneAnswer = "-"
+
+// taskFileSpec is the content of a task file authored with the wizard
+type taskFileSpec struct {
+	Goal   string
+	Scope  string   // Files or directories the changes are limited to
+	Verify []string // Verification commands of tasks that do not set their own
+	Tasks  []taskSpec
+}
+
+// taskSpec is a task of a taskFileSpec
+type taskSpec struct {
+	Title       string
+	Description string
+	Verify      []string
+}
+
+// render formats the spec as a task file.
+func (s taskFileSpec) render() string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "# %s\n\n", oneLine(s.Goal))
+	if s.Scope != "" {
+		_, _ = fmt.Fprintf(&b, "変更範囲: %s\n\n", oneLine(s.Scope))
+	}
+
+	for i, task := range s.Tasks {
+		b.WriteString("---\n\n")
+		_, _ = fmt.Fprintf(&b, "## タスク%d: %s\n\n", i+1, oneLine(task.Title))
+		description := oneLine(task.Description)
+		if description == "" {
+			description = oneLine(task.Title)
+		}
+		b.WriteString(description + "\n\n")
+
+		b.WriteString("### 実装\n")
+		_, _ = fmt.Fprintf(&b, "- 全体の目標: %s\n", oneLine(s.Goal))
+		if s.Scope != "" {
+			_, _ = fmt.Fprintf(&b, "- 変更範囲: %s（この範囲外のファイルは変更しない）\n", oneLine(s.Scope))
+		}
+		if len(task.Verify) > 0 {
+			b.WriteString("\n### 確認\n")
+			for _, command := range task.Verify {
+				_, _ = fmt.Fprintf(&b, "- `%s`\n", oneLine(command))
+			}
+		}
+		if i < len(s.Tasks)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// oneLine joins the lines of s, so that a value cannot start a new section
+// of the task file.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// parseTaskFlag parses a --task value, "title" or "title: description".
+func parseTaskFlag(value string) taskSpec {
+	title, description, _ := strings.Cut(value, ": ")
+	return taskSpec{Title: strings.TrimSpace(title), Description: strings.TrimSpace(description)}
+}
+
+// flagTaskFileSpec returns the spec given with --goal, --scope, --verify and
+// --task.
+func flagTaskFileSpec() taskFileSpec {
+	spec := taskFileSpec{
+		Goal:  strings.TrimSpace(initGoal),
+		Scope: strings.TrimSpace(initScope),
+	}
+	for _, command := range initVerify {
+		if command = strings.TrimSpace(command); command != "" {
+			spec.Verify = append(spec.Verify, command)
+		}
+	}
+	for _, value := range initTasks {
+		task := parseTaskFlag(value)
+		task.Verify = spec.Verify
+		spec.Tasks = append(spec.Tasks, task)
+	}
+	return spec
+}
+
+// prompter asks the questions of the wizard.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{in: bufio.NewReader(in), out: out}
+}
+
+// ask asks a question and returns the answer, or def if the answer is empty.
+func (p *prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		_, _ = fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		_, _ = fmt.Fprintf(p.out, "%s: ", question)
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("input ended before the wizard finished")
+		}
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// askOptional asks a question whose default can be cleared with noneAnswer.
+func (p *prompter) askOptional(question, def string) (string, error) {
+	if def != "" {
+		question += fmt.Sprintf(" (%s for none)", noneAnswer)
+	}
+	answer, err := p.ask(question, def)
+	if answer == noneAnswer {
+		answer = ""
+	}
+	return answer, err
+}
+
+// askCommands asks for verification commands. Several default commands are
+// offered joined with &&, and are kept as they are if the default is taken.
+func (p *prompter) askCommands(question string, def []string) ([]string, error) {
+	defText := strings.Join(def, " && ")
+	answer, err := p.askOptional(question, defText)
+	switch {
+	case err != nil || answer == "":
+		return nil, err
+	case answer == defText:
+		return def, nil
+	}
+	return []string{answer}, nil
+}
+
+// confirm asks a yes/no question.
+func (p *prompter) confirm(question string) (bool, error) {
+	answer, err := p.ask(question+" [Y/n]", "")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "" || answer == "y" || answer == "yes", nil
+}
+
+// askTaskFileSpec asks for the goal, scope, verification command and tasks
+// of a task file. The values of spec are the defaults, and its tasks are kept.
+func askTaskFileSpec(p *prompter, spec taskFileSpec) (taskFileSpec, error) {
+	var err error
+	_, _ = fmt.Fprintln(p.out, "🧙 Task file wizard (press Ctrl+C to cancel)")
+	_, _ = fmt.Fprintln(p.out)
+
+	for spec.Goal == "" {
+		if spec.Goal, err = p.ask("Goal of the work", ""); err != nil {
+			return spec, err
+		}
+	}
+	if spec.Scope, err = p.askOptional("Scope: files or directories to change (optional)", spec.Scope); err != nil {
+		return spec, err
+	}
+	if len(spec.Verify) == 0 {
+		spec.Verify = suggestedVerifyCommands()
+	}
+	if spec.Verify, err = p.askCommands("Verification command for every task", spec.Verify); err != nil {
+		return spec, err
+	}
+
+	for i, task := range spec.Tasks {
+		_, _ = fmt.Fprintf(p.out, "Task %d: %s\n", i+1, task.Title)
+		spec.Tasks[i].Verify = spec.Verify
+	}
+	for {
+		number := len(spec.Tasks) + 1
+		title, err := p.ask(fmt.Sprintf("Task %d title (empty to finish)", number), "")
+		if err != nil {
+			return spec, err
+		}
+		if title == "" {
+			if len(spec.Tasks) > 0 {
+				break
+			}
+			_, _ = fmt.Fprintln(p.out, "At least one task is needed.")
+			continue
+		}
+
+		task := taskSpec{Title: title}
+		if task.Description, err = p.ask("  What should be done (optional)", ""); err != nil {
+			return spec, err
+		}
+		if task.Verify, err = p.askCommands("  Verification command", spec.Verify); err != nil {
+			return spec, err
+		}
+		spec.Tasks = append(spec.Tasks, task)
+	}
+	_, _ = fmt.Fprintln(p.out)
+	return spec, nil
+}
+
+// suggestedVerifyCommands returns the verification commands suggested by the
+// wizard for the project in the current directory.
+func suggestedVerifyCommands() []string {
+	if _, err := os.Stat("go.mod"); err == nil {
+		return []string{"go build ./...", "go test ./..."}
+	}
+	return nil
+}
+
+// previewTaskFile prints a task file and the problems lintTaskFile finds in
+// it, and reports whether it can be written.
+func previewTaskFile(out io.Writer, taskFile, content string) (bool, error) {
+	problems, err := lintTaskFile(content)
+	if err != nil {
+		return false, err
+	}
+
+	_, _ = fmt.Fprintf(out, "📄 Preview of %s:\n", taskFile)
+	_, _ = fmt.Fprintln(out, "----------------------------------------")
+	_, _ = fmt.Fprint(out, content)
+	_, _ = fmt.Fprintln(out, "----------------------------------------")
+
+	if len(problems) == 0 {
+		_, _ = fmt.Fprintln(out, "🔍 Lint: no problems found")
+		return true, nil
+	}
+	ok := true
+	_, _ = fmt.Fprintf(out, "🔍 Lint: %d problems found\n", len(problems))
+	for _, problem := range problems {
+		marker := "⚠️"
+		if problem.Error {
+			marker = "❌"
+			ok = false
+		}
+		_, _ = fmt.Fprintf(out, "  %s %s\n", marker, problem)
+	}
+	return ok, nil
+}
+
+// authorTaskFile returns the content of a task file authored with the
+// wizard, and false if it should not be written. Without a prompter, the
+// task file is built from the flags alone.
+func authorTaskFile(p *prompter, out io.Writer, taskFile string) (string, bool, error) {
+	spec := flagTaskFileSpec()
+	if p != nil {
+		var err error
+		if spec, err = askTaskFileSpec(p, spec); err != nil {
+			return "", false, err
+		}
+	} else if spec.Goal == "" || len(spec.Tasks) == 0 {
+		return "", false, fmt.Errorf("--goal and at least one --task are needed without an interactive terminal")
+	}
+
+	content := spec.render()
+	ok, err := previewTaskFile(out, taskFile, content)
+	if err != nil {
+		return "", false, err
+	}
+	if !ok {
+		return "", false, fmt.Errorf("the task file has problems that keep sync from running it")
+	}
+	if p == nil {
+		return content, true, nil
+	}
+
+	write, err := p.confirm(fmt.Sprintf("Write %s?", taskFile))
+	if err != nil {
+		return "", false, err
+	}
+	return content, write, nil
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
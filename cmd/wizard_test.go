@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAskTaskFileSpec(t *testing.T) {
+	// No go.mod, so no verification command is suggested
+	oldDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(oldDir) }()
+	_ = os.Chdir(t.TempDir())
+
+	// Goal, scope, verification for every task, then two tasks: the first
+	// with the default verification, the second without one
+	input := strings.Join([]string{
+		"", // Goal is required, asked again
+		"Add rate limiting",
+		"internal/http",
+		"go test ./...",
+		"Add middleware",
+		"Limit requests per IP",
+		"",
+		"Document the limit",
+		"",
+		"-",
+		"",
+	}, "\n") + "\n"
+	var out bytes.Buffer
+	spec, err := askTaskFileSpec(newPrompter(strings.NewReader(input), &out), taskFileSpec{})
+	if err != nil {
+		t.Fatalf("askTaskFileSpec() error = %v\noutput: %s", err, out.String())
+	}
+
+	want := taskFileSpec{
+		Goal:   "Add rate limiting",
+		Scope:  "internal/http",
+		Verify: []string{"go test ./..."},
+		Tasks: []taskSpec{
+			{Title: "Add middleware", Description: "Limit requests per IP", Verify: []string{"go test ./..."}},
+			{Title: "Document the limit"},
+		},
+	}
+	if spec.Goal != want.Goal || spec.Scope != want.Scope || !slices.Equal(spec.Verify, want.Verify) || len(spec.Tasks) != len(want.Tasks) {
+		t.Fatalf("askTaskFileSpec() = %+v, want %+v", spec, want)
+	}
+	for i := range want.Tasks {
+		if got := spec.Tasks[i]; got.Title != want.Tasks[i].Title || got.Description != want.Tasks[i].Description || !slices.Equal(got.Verify, want.Tasks[i].Verify) {
+			t.Errorf("task %d = %+v, want %+v", i+1, spec.Tasks[i], want.Tasks[i])
+		}
+	}
+
+	// Input that ends early is an error
+	if _, err := askTaskFileSpec(newPrompter(strings.NewReader("Goal\n"), &out), taskFileSpec{}); err == nil {
+		t.Error("askTaskFileSpec() with incomplete input succeeded")
+	}
+}
+
+func TestAskCommands(t *testing.T) {
+	def := []string{"go build ./...", "go test ./..."}
+	tests := []struct {
+		answer string
+		want   []string
+	}{
+		{answer: "", want: def},
+		{answer: "go build ./... && go test ./...", want: def},
+		{answer: "make test", want: []string{"make test"}},
+		{answer: "-", want: nil},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		got, err := newPrompter(strings.NewReader(tt.answer+"\n"), &out).askCommands("Verification command", def)
+		if err != nil {
+			t.Fatalf("askCommands(%q) error = %v", tt.answer, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("askCommands(%q) = %q, want %q", tt.answer, got, tt.want)
+		}
+	}
+}
+
+func TestAuthorTaskFileFromFlags(t *testing.T) {
+	defer func() { initGoal, initScope, initTasks, initVerify = "", "", nil, nil }()
+	initGoal = "Add rate limiting"
+	initScope = "internal/http"
+	initTasks = []string{"Add middleware: Limit requests per IP", "Write docs"}
+	initVerify = []string{"go build ./...", "go test ./..."}
+
+	var out bytes.Buffer
+	content, write, err := authorTaskFile(nil, &out, "tasks.txt")
+	if err != nil || !write {
+		t.Fatalf("authorTaskFile() = %v, %v", write, err)
+	}
+	if !strings.Contains(out.String(), "🔍 Lint: no problems found") {
+		t.Errorf("preview = %s", out.String())
+	}
+
+	tasks, err := parseTasks(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("parsed %d tasks, want 2:\n%s", len(tasks), content)
+	}
+	if tasks[0].Title != "1: Add middleware" || !strings.Contains(tasks[0].Description, "Limit requests per IP") || !strings.Contains(tasks[0].Description, "internal/http") {
+		t.Errorf("task 1 = %+v", tasks[0])
+	}
+	for _, task := range tasks {
+		if !slices.Equal(task.Commands, []string{"go build ./...", "go test ./..."}) {
+			t.Errorf("task %q commands = %q", task.Title, task.Commands)
+		}
+	}
+
+	// Without a terminal, a goal and a task are needed
+	initTasks = nil
+	if _, _, err := authorTaskFile(nil, &out, "tasks.txt"); err == nil {
+		t.Error("authorTaskFile() without tasks succeeded")
+	}
+}
+
+func TestLintTaskFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "clean",
+			content: "## タスク1: Build\n\nDo it\n\n- `go build`\n\n## タスク2: Test\n\nTest it\n依存: 1\n\n- `go test ./...`\n",
+		},
+		{
+			name:    "no tasks",
+			content: "# Notes\n\nNothing to do\n",
+			want:    []string{"no tasks found"},
+			wantErr: true,
+		},
+		{
+			name:    "task problems",
+			content: "## タスク1: Build\n\nDo it\n\n- `go build`\n- `go vet ./...`\n\n## タスク2: Build\n\nAgain\n依存: 3, 5\n\n## Task 3:\n\nMore\n\n- `true`\n",
+			want: []string{
+				"Task 2: has the same title as task 1",
+				"Task 2: has no verification command, so its changes are not checked",
+				"Task 2: depends on task 3, which runs after it",
+				"Task 2: depends on task 5, which does not exist",
+				"Task 3: has no title",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems, err := lintTaskFile(tt.content)
+			if err != nil {
+				t.Fatalf("lintTaskFile() error = %v", err)
+			}
+			var got []string
+			hasErr := false
+			for _, problem := range problems {
+				got = append(got, problem.String())
+				hasErr = hasErr || problem.Error
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("lintTaskFile() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if !strings.HasPrefix(got[i], tt.want[i]) {
+					t.Errorf("problem %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+			if hasErr != tt.wantErr {
+				t.Errorf("error problems = %v, want %v", hasErr, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,450 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isiidaisuke0926/sleepship/internal/config"
+	"github.com/isiidaisuke0926/sleepship/internal/history"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// memberResult is the outcome of the run of one workspace member
+type memberResult struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+	Entry    *history.Entry // History entry recorded by the run, if any
+}
+
+// selectMembers loads the workspace and returns the names of the members
+// selected with --member or --all-members.
+func selectMembers() (*config.Workspace, []string, error) {
+	workspace, err := config.LoadWorkspace()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load workspace: %w", err)
+	}
+	if workspace == nil || len(workspace.Members) == 0 {
+		return nil, nil, fmt.Errorf("no workspace members defined (add [workspace.members.<name>] tables to %s)", config.ConfigFileName)
+	}
+	if allMembers {
+		return workspace, workspace.MemberNames(), nil
+	}
+
+	var names []string
+	for _, name := range syncMembers {
+		if _, ok := workspace.Members[name]; !ok {
+			return nil, nil, fmt.Errorf("unknown workspace member %q (members: %s)", name, strings.Join(workspace.MemberNames(), ", "))
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return workspace, names, nil
+}
+
+// memberTaskFile returns the task file of a member run: the one given on the
+// command line, the member's task_file, or else the configured default task
+// file, relative to the member directory.
+func memberTaskFile(args []string, member config.Member, cfg *config.Config) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	if member.TaskFile != "" {
+		return member.TaskFile, nil
+	}
+	taskFile := cfg.DefaultTaskFile
+	if taskFile == "" {
+		return "", fmt.Errorf("no task file for workspace member %s (set task_file or default_task_file)", workspaceMember)
+	}
+	if !filepath.IsAbs(taskFile) {
+		taskFile = filepath.Join(member.Path, taskFile)
+	}
+	return taskFile, nil
+}
+
+// enterMember makes the directory of a member the current directory, so that
+// the member's own config files apply as if sleepship was run there, with
+// the settings of the member table on top. A task file given in args is made
+// absolute first, and the updated args are returned.
+func enterMember(member config.Member, args []string) ([]string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+	memberWorkDir = cwd
+
+	if len(args) > 0 && !filepath.IsAbs(args[0]) {
+		args = append([]string{filepath.Join(cwd, args[0])}, args[1:]...)
+	}
+	// A config file selected with SLEEPSHIP_CONFIG stays the same file
+	if explicit := config.ExplicitConfigPath(); explicit != "" {
+		if err := os.Setenv(config.ConfigEnvVar, explicit); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.Chdir(member.Path); err != nil {
+		return nil, fmt.Errorf("failed to enter workspace member %s: %w", workspaceMember, err)
+	}
+
+	// The member's config files were not checked at startup
+	if !worker {
+		if err := checkConfig(); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// runWorkspace runs several workspace members, each in a sleepship process
+// of its own, serially or in parallel, and reports the outcome of each.
+func runWorkspace(cmd *cobra.Command, args []string, workspace *config.Workspace, names []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("a task file cannot be given for several members (set task_file for each member)")
+	}
+
+	parallel := workspace.Parallel
+	if cmd.Flags().Changed("parallel") {
+		parallel = syncParallel
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	passthrough := memberSyncArgs(cmd)
+
+	// Run the members from a background worker unless asked to wait
+	if !worker && !syncWait {
+		cmdArgs := []string{"sync", "--worker", "--parallel=" + strconv.FormatBool(parallel)}
+		for _, name := range names {
+			cmdArgs = append(cmdArgs, "--member", name)
+		}
+		return startWorker(filepath.Dir(workspace.Source), cwd, append(cmdArgs, passthrough...))
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	mode := "serially"
+	if parallel {
+		mode = "in parallel"
+	}
+	fmt.Printf("📦 Workspace: running %d members %s (%s)\n\n", len(names), mode, strings.Join(names, ", "))
+
+	// Every member branches from where its repository was when the
+	// workspace run started, not from a branch an earlier member left
+	startPoints := memberStartPoints(workspace, names)
+
+	// Members that share a repository each get a worktree of their own, so
+	// that they do not check out their branches in the same working tree
+	worktrees := make(map[string]memberWorktree)
+	if parallel {
+		if worktrees, err = addMemberWorktrees(workspace, names, startPoints); err != nil {
+			return err
+		}
+		defer removeMemberWorktrees(worktrees)
+	}
+
+	var outputMu sync.Mutex
+	results := make([]memberResult, len(names))
+	run := func(i int) {
+		name := names[i]
+		cmdArgs := append([]string{"sync", "--member", name, "--wait"}, passthrough...)
+		if point := startPoints[name]; point != "" {
+			cmdArgs = append(cmdArgs, "--start-point", point)
+		}
+		if worktree, ok := worktrees[name]; ok {
+			cmdArgs = append(cmdArgs, "--worktree", worktree.dir)
+		}
+		results[i] = runMember(executable, cwd, name, workspace.Members[name], cmdArgs, &outputMu)
+	}
+	if parallel {
+		var wg sync.WaitGroup
+		for i := range names {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				run(i)
+			}()
+		}
+		wg.Wait()
+	} else {
+		for i := range names {
+			run(i)
+		}
+	}
+
+	displayWorkspaceResults(results)
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d workspace members failed", failed, len(results))
+	}
+	return nil
+}
+
+// runMember runs sleepship with cmdArgs for one member and waits for it to
+// finish. Its output is prefixed with the member name. The run starts in
+// workDir, where the workspace is found, and enters the member directory
+// itself.
+func runMember(executable, workDir, name string, member config.Member, cmdArgs []string, outputMu *sync.Mutex) memberResult {
+	start := time.Now()
+	stdout := &prefixWriter{w: os.Stdout, prefix: "[" + name + "] ", mu: outputMu}
+	stderr := &prefixWriter{w: os.Stderr, prefix: "[" + name + "] ", mu: outputMu}
+
+	c := exec.Command(executable, cmdArgs...)
+	c.Dir = workDir
+	c.Stdout = stdout
+	c.Stderr = stderr
+	err := c.Run()
+	stdout.Flush()
+	stderr.Flush()
+
+	return memberResult{
+		Name:     name,
+		Err:      err,
+		Duration: time.Since(start),
+		Entry:    lastMemberEntry(member.Path, name, start),
+	}
+}
+
+// lastMemberEntry returns the latest history entry recorded for a member
+// since start, or nil if the run recorded none.
+func lastMemberEntry(dir, name string, start time.Time) *history.Entry {
+	h, err := history.Load(dir)
+	if err != nil {
+		return nil
+	}
+	for i := len(h.Entries) - 1; i >= 0; i-- {
+		entry := h.Entries[i]
+		if entry.Member == name && !entry.ExecutedAt.Before(start) {
+			return &entry
+		}
+	}
+	return nil
+}
+
+// displayWorkspaceResults prints the outcome of each member of a workspace run.
+func displayWorkspaceResults(results []memberResult) {
+	succeeded := 0
+	nameLen, idLen := 0, 1
+	for _, result := range results {
+		if result.Err == nil {
+			succeeded++
+		}
+		nameLen = max(nameLen, len(result.Name))
+		if result.Entry != nil {
+			idLen = max(idLen, len(result.Entry.ID))
+		}
+	}
+
+	fmt.Printf("\n========================================\n")
+	fmt.Printf("📦 Workspace: %d/%d members succeeded\n", succeeded, len(results))
+	fmt.Printf("========================================\n")
+	for _, result := range results {
+		status := "✅"
+		if result.Err != nil {
+			status = "❌"
+		}
+		id, tasks, branch := "-", "-", "-"
+		if entry := result.Entry; entry != nil {
+			id = valueOrDash(entry.ID)
+			tasks = fmt.Sprintf("%d tasks", entry.TaskCount)
+			branch = valueOrDash(entry.BranchName)
+		}
+		fmt.Printf("%s %-*s  %-*s  %-9s  %-8s  %s\n", status, nameLen, result.Name, idLen, id, tasks, formatDuration(result.Duration), branch)
+
+		if result.Err != nil {
+			errorMsg := result.Err.Error()
+			if result.Entry != nil && result.Entry.ErrorMessage != "" {
+				errorMsg = result.Entry.ErrorMessage
+			}
+			errorMsg, _, _ = strings.Cut(errorMsg, "\n")
+			fmt.Printf("   Error: %s\n", errorMsg)
+		}
+	}
+}
+
+// sharedRepositories returns the members that share their git repository
+// with another member, by repository root. Each run checks out a branch of
+// its own, so these members cannot run in the same working tree at once.
+func sharedRepositories(workspace *config.Workspace, names []string) map[string][]string {
+	members := make(map[string][]string)
+	for _, name := range names {
+		if root := config.FindRepoRoot(workspace.Members[name].Path); root != "" {
+			members[root] = append(members[root], name)
+		}
+	}
+	for root, names := range members {
+		if len(names) < 2 {
+			delete(members, root)
+		}
+	}
+	return members
+}
+
+// memberWorktree is the checkout a member runs in when it runs in parallel
+// with other members of its repository.
+type memberWorktree struct {
+	repo string // Root of the repository
+	path string // Root of the worktree
+	dir  string // Member directory in the worktree
+}
+
+// addMemberWorktrees adds a detached worktree at its start point for each
+// member that shares its repository with another member, and returns the
+// worktrees by member name.
+func addMemberWorktrees(workspace *config.Workspace, names []string, startPoints map[string]string) (map[string]memberWorktree, error) {
+	worktrees := make(map[string]memberWorktree)
+	for repo, members := range sharedRepositories(workspace, names) {
+		for _, name := range members {
+			worktree, err := addMemberWorktree(repo, name, workspace.Members[name].Path, startPoints[name])
+			if err != nil {
+				removeMemberWorktrees(worktrees)
+				return nil, fmt.Errorf("failed to create a worktree for workspace member %s: %w", name, err)
+			}
+			worktrees[name] = worktree
+		}
+	}
+	return worktrees, nil
+}
+
+// addMemberWorktree adds a worktree of repo at startPoint in a new temporary
+// directory.
+func addMemberWorktree(repo, name, memberPath, startPoint string) (memberWorktree, error) {
+	if startPoint == "" {
+		return memberWorktree{}, fmt.Errorf("the repository has no commits")
+	}
+	rel, err := filepath.Rel(repo, memberPath)
+	if err != nil {
+		return memberWorktree{}, err
+	}
+
+	parent, err := os.MkdirTemp("", "sleepship-"+sanitizeBranchName(name)+"-")
+	if err != nil {
+		return memberWorktree{}, err
+	}
+	path := filepath.Join(parent, filepath.Base(repo))
+	output, err := exec.Command("git", "-C", repo, "worktree", "add", "--detach", path, startPoint).CombinedOutput()
+	if err != nil {
+		_ = os.RemoveAll(parent)
+		return memberWorktree{}, fmt.Errorf("git worktree add: %w\nOutput: %s", err, output)
+	}
+	return memberWorktree{repo: repo, path: path, dir: filepath.Join(path, rel)}, nil
+}
+
+// removeMemberWorktrees removes the worktrees of members. The branches the
+// members created stay in the repository. A worktree with changes that were
+// not committed is kept and reported.
+func removeMemberWorktrees(worktrees map[string]memberWorktree) {
+	for _, name := range slices.Sorted(maps.Keys(worktrees)) {
+		worktree := worktrees[name]
+		output, err := exec.Command("git", "-C", worktree.repo, "worktree", "remove", worktree.path).CombinedOutput()
+		if err != nil {
+			log.Printf("⚠️ Warning: Kept the worktree of workspace member %s: %s\n%s", name, worktree.path, output)
+			continue
+		}
+		_ = os.Remove(filepath.Dir(worktree.path))
+	}
+}
+
+// memberStartPoints returns the commit the repository of each member is on,
+// by member name. Members outside a repository or in a repository without
+// commits have none.
+func memberStartPoints(workspace *config.Workspace, names []string) map[string]string {
+	heads := make(map[string]string) // By repository root
+	points := make(map[string]string)
+	for _, name := range names {
+		root := config.FindRepoRoot(workspace.Members[name].Path)
+		if root == "" {
+			continue
+		}
+		head, ok := heads[root]
+		if !ok {
+			output, err := exec.Command("git", "-C", root, "rev-parse", "--verify", "--quiet", "HEAD").Output()
+			if err == nil {
+				head = strings.TrimSpace(string(output))
+			}
+			heads[root] = head
+		}
+		if head != "" {
+			points[name] = head
+		}
+	}
+	return points
+}
+
+// memberSyncArgs returns the sync flags given on the command line that are
+// passed on to the run of each member.
+func memberSyncArgs(cmd *cobra.Command) []string {
+	var args []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		switch f.Name {
+		case "member", "all-members", "parallel", "worker", "wait", "dir", "start-point", "worktree":
+			return
+		}
+		if values, ok := f.Value.(pflag.SliceValue); ok {
+			for _, value := range values.GetSlice() {
+				args = append(args, "--"+f.Name+"="+value)
+			}
+			return
+		}
+		args = append(args, "--"+f.Name+"="+f.Value.String())
+	})
+	return args
+}
+
+// prefixWriter writes complete lines to w, each prefixed with prefix, so
+// that the output of members running in parallel can be told apart.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	mu     *sync.Mutex // Shared by the writers of all members
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		p.writeLine(p.buf[:i+1])
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes the last line if it does not end in a newline.
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.writeLine(append(p.buf, '\n'))
+		p.buf = nil
+	}
+}
+
+func (p *prefixWriter) writeLine(line []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = p.w.Write(append([]byte(p.prefix), line...))
+}
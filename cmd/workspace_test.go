@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/isiidaisuke0926/sleepship/internal/config"
+)
+
+func TestMemberTaskFile(t *testing.T) {
+	api := config.Member{Path: "/src/api", TaskFile: "/src/api/tasks-api.txt"}
+	web := config.Member{Path: "/src/web"}
+
+	tests := []struct {
+		name   string
+		args   []string
+		member config.Member
+		cfg    *config.Config
+		want   string
+	}{
+		{name: "argument", args: []string{"hotfix.txt"}, member: api, cfg: &config.Config{}, want: "hotfix.txt"},
+		{name: "member task file", member: api, cfg: &config.Config{DefaultTaskFile: "tasks.txt"}, want: "/src/api/tasks-api.txt"},
+		{name: "default relative to the member", member: web, cfg: &config.Config{DefaultTaskFile: "tasks.txt"}, want: "/src/web/tasks.txt"},
+		{name: "absolute default", member: web, cfg: &config.Config{DefaultTaskFile: "/tasks/web.txt"}, want: "/tasks/web.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := memberTaskFile(tt.args, tt.member, tt.cfg)
+			if err != nil {
+				t.Fatalf("memberTaskFile() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("memberTaskFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := memberTaskFile(nil, web, &config.Config{}); err == nil {
+		t.Error("memberTaskFile() without any task file succeeded")
+	}
+}
+
+func TestEnterMemberUsesMemberConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(config.ConfigEnvVar, "")
+	root := t.TempDir()
+	oldDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(oldDir) }()
+	_ = os.Chdir(root)
+	defer func() { syncMembers, workspaceMember, memberWorkDir = nil, "", "" }()
+
+	apiDir := filepath.Join(root, "services", "api")
+	for path, content := range map[string]string{
+		filepath.Join(root, ".git", "HEAD"):          "ref: refs/heads/main\n",
+		filepath.Join(root, config.ConfigFileName):   "[sync]\nmax_retries = 1\n\n[workspace.members.api]\npath = \"services/api\"\n",
+		filepath.Join(apiDir, config.ConfigFileName): "[sync]\nmax_retries = 7\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	syncMembers = []string{"api"}
+	workspace, names, err := selectMembers()
+	if err != nil {
+		t.Fatalf("selectMembers() error = %v", err)
+	}
+	workspaceMember = names[0]
+	args, err := enterMember(workspace.Members["api"], []string{"tasks.txt"})
+	if err != nil {
+		t.Fatalf("enterMember() error = %v", err)
+	}
+
+	if want := filepath.Join(root, "tasks.txt"); args[0] != want {
+		t.Errorf("task file = %q, want %q", args[0], want)
+	}
+	if memberWorkDir != root {
+		t.Errorf("memberWorkDir = %q, want %q", memberWorkDir, root)
+	}
+	cwd, _ := os.Getwd()
+	if cwd != apiDir {
+		t.Errorf("current directory = %q, want %q", cwd, apiDir)
+	}
+
+	// The member's own config file applies instead of the workspace root's
+	fileConfig, err := config.LoadFileConfig()
+	if err != nil {
+		t.Fatalf("LoadFileConfig() error = %v", err)
+	}
+	if fileConfig.Sync.MaxRetries == nil || *fileConfig.Sync.MaxRetries != 7 {
+		t.Errorf("max_retries = %v, want 7 from the member config", fileConfig.Sync.MaxRetries)
+	}
+}
+
+func TestFeatureBranchName(t *testing.T) {
+	defer func() { workspaceMember = "" }()
+
+	workspaceMember = ""
+	if got := featureBranchName("plans/tasks-login.txt"); got != "feature/login" {
+		t.Errorf("featureBranchName() = %q, want feature/login", got)
+	}
+	workspaceMember = "services/api"
+	if got := featureBranchName("tasks.txt"); got != "feature/services-api-tasks" {
+		t.Errorf("featureBranchName() for a member = %q, want feature/services-api-tasks", got)
+	}
+}
+
+func TestNewRunID(t *testing.T) {
+	start := time.Date(2025, 1, 1, 2, 0, 0, 123456789, time.Local)
+
+	if got := newRunID(start, ""); got != "20250101-020000.123" {
+		t.Errorf("newRunID() = %q, want 20250101-020000.123", got)
+	}
+	// Members started at the same moment get different IDs
+	api, web := newRunID(start, "services/api"), newRunID(start, "web")
+	if api != "20250101-020000.123-services-api" || api == web {
+		t.Errorf("newRunID() for members = %q, %q", api, web)
+	}
+}
+
+func TestSharedRepositories(t *testing.T) {
+	monorepo := t.TempDir()
+	separate := t.TempDir()
+	for _, dir := range []string{filepath.Join(monorepo, ".git"), filepath.Join(separate, ".git")} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	workspace := &config.Workspace{Members: map[string]config.Member{
+		"api":   {Path: filepath.Join(monorepo, "services", "api")},
+		"web":   {Path: filepath.Join(monorepo, "services", "web")},
+		"docs":  {Path: separate},
+		"notes": {Path: t.TempDir()}, // Not in a repository
+	}}
+
+	shared := sharedRepositories(workspace, []string{"api", "docs", "notes", "web"})
+	if len(shared) != 1 || !slices.Equal(shared[monorepo], []string{"api", "web"}) {
+		t.Errorf("sharedRepositories() = %v, want api and web in %s", shared, monorepo)
+	}
+	if shared := sharedRepositories(workspace, []string{"api", "docs", "notes"}); len(shared) != 0 {
+		t.Errorf("sharedRepositories() = %v for members in separate repositories", shared)
+	}
+}
+
+func TestMemberWorktrees(t *testing.T) {
+	repo := initTestRepo(t)
+	start := gitOutput(t, repo, "rev-parse", "HEAD")
+	workspace := &config.Workspace{Members: map[string]config.Member{
+		"api": {Path: filepath.Join(repo, "services", "api")},
+		"web": {Path: filepath.Join(repo, "services", "web")},
+	}}
+	names := []string{"api", "web"}
+
+	worktrees, err := addMemberWorktrees(workspace, names, memberStartPoints(workspace, names))
+	if err != nil {
+		t.Fatalf("addMemberWorktrees() error = %v", err)
+	}
+	if len(worktrees) != 2 || worktrees["api"].path == worktrees["web"].path {
+		t.Fatalf("addMemberWorktrees() = %v, want a worktree for each member", worktrees)
+	}
+	for name, worktree := range worktrees {
+		if got := gitOutput(t, worktree.path, "rev-parse", "HEAD"); got != start {
+			t.Errorf("worktree of %s is at %s, want %s", name, got, start)
+		}
+		if want := filepath.Join(worktree.path, "services", name); worktree.dir != want {
+			t.Errorf("member directory of %s = %q, want %q", name, worktree.dir, want)
+		}
+	}
+
+	// A worktree with uncommitted changes is kept
+	writeTestFile(t, worktrees["web"].path, "draft.txt", "draft")
+	removeMemberWorktrees(worktrees)
+	if _, err := os.Stat(worktrees["api"].path); !os.IsNotExist(err) {
+		t.Errorf("worktree of api was not removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(worktrees["web"].path, "draft.txt")); err != nil {
+		t.Errorf("worktree of web with changes was removed: %v", err)
+	}
+	_ = os.RemoveAll(filepath.Dir(worktrees["web"].path))
+}
+
+func TestMemberStartPoints(t *testing.T) {
+	repo := initTestRepo(t)
+	start := gitOutput(t, repo, "rev-parse", "HEAD")
+	outside := t.TempDir()
+	workspace := &config.Workspace{Members: map[string]config.Member{
+		"api":   {Path: filepath.Join(repo, "services", "api")},
+		"web":   {Path: filepath.Join(repo, "services", "web")},
+		"tools": {Path: outside},
+	}}
+
+	points := memberStartPoints(workspace, []string{"api", "web", "tools"})
+	if points["api"] != start || points["web"] != start {
+		t.Errorf("memberStartPoints() = %v, want %s for api and web", points, start)
+	}
+	if _, ok := points["tools"]; ok {
+		t.Errorf("memberStartPoints() has a start point for a member outside a repository: %v", points)
+	}
+
+	// The first member leaves its branch checked out with a new commit
+	gitOutput(t, repo, "checkout", "-q", "-b", "feature/api-tasks")
+	gitOutput(t, repo, "commit", "-q", "--allow-empty", "-m", "api work")
+
+	logFile, err := os.CreateTemp(t.TempDir(), "sync-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = logFile.Close() }()
+	defer func() { workspaceMember, startPoint = "", "" }()
+
+	// The next member still branches from the start of the workspace run
+	workspaceMember, startPoint = "web", points["web"]
+	if err := createBranchForSync("tasks.txt", logFile); err != nil {
+		t.Fatalf("createBranchForSync() error = %v", err)
+	}
+	if got := gitOutput(t, repo, "rev-parse", "feature/web-tasks"); got != start {
+		t.Errorf("member branch starts at %s, want %s", got, start)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	w := &prefixWriter{w: &out, prefix: "[api] ", mu: &mu}
+
+	_, _ = w.Write([]byte("📋 Total tasks: 2\n🌿 Creating"))
+	_, _ = w.Write([]byte(" branch\n\nlast line"))
+	w.Flush()
+
+	want := "[api] 📋 Total tasks: 2\n[api] 🌿 Creating branch\n[api] \n[api] last line\n"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -4,100 +4,498 @@ package config
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/BurntSushi/toml"
 )
 
 // AliasConfig represents alias configuration from .sleepship.toml
 type AliasConfig struct {
-	Aliases map[string]string `toml:"aliases"`
+	Aliases map[string]Alias `toml:"aliases"`
 }
 
-// LoadAliases loads alias configuration from .sleepship.toml
-// It searches for .sleepship.toml in the current directory first,
-// then in the home directory.
-func LoadAliases() (map[string]string, error) {
-	// Search for config file in current directory
-	cwd, err := os.Getwd()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get current directory: %w", err)
+// Alias is the definition of an alias. It is either a single command, to
+// which the arguments of the alias are appended, or a list of steps that are
+// run one after another until a step fails:
+//
+//	dev = "sync tasks-dev.txt"
+//	nightly = ["sync --wait nightly.txt", "history --last 1"]
+//
+// A step starting with ! is run by the shell, with the arguments of the
+// alias as positional parameters.
+type Alias struct {
+	Command string
+	Steps   []string
+}
+
+// ShellStepPrefix marks a step of a multi-step alias that is run by the shell.
+const ShellStepPrefix = "!"
+
+// IsMultiStep reports whether the alias is a list of steps.
+func (a Alias) IsMultiStep() bool {
+	return a.Steps != nil
+}
+
+// String returns the command of the alias, with the steps of a multi-step
+// alias joined by &&.
+func (a Alias) String() string {
+	if a.IsMultiStep() {
+		return strings.Join(a.Steps, " && ")
 	}
+	return a.Command
+}
 
-	configPath := filepath.Join(cwd, ".sleepship.toml")
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		// Try home directory
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get home directory: %w", err)
+// UnmarshalTOML decodes an alias from a string or an array of strings.
+func (a *Alias) UnmarshalTOML(value any) error {
+	switch v := value.(type) {
+	case string:
+		*a = Alias{Command: v}
+		return nil
+	case []any:
+		steps := make([]string, 0, len(v))
+		for _, step := range v {
+			s, ok := step.(string)
+			if !ok {
+				return fmt.Errorf("alias steps must be strings, got %T", step)
+			}
+			steps = append(steps, s)
 		}
-		configPath = filepath.Join(homeDir, ".sleepship.toml")
-		if _, err := os.Stat(configPath); os.IsNotExist(err) {
-			// No config file found, return empty map
-			return make(map[string]string), nil
+		if len(steps) == 0 {
+			return fmt.Errorf("alias must have at least one step")
 		}
+		*a = Alias{Steps: steps}
+		return nil
+	default:
+		return fmt.Errorf("alias must be a string or an array of strings, got %T", value)
 	}
+}
+
+// AliasSource is the set of aliases defined in one config file.
+type AliasSource struct {
+	Path    string
+	Aliases map[string]Alias
+}
 
-	// Load config file
-	var config AliasConfig
-	if _, err := toml.DecodeFile(configPath, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+// AliasDefinition is the definition of an alias in one config file.
+type AliasDefinition struct {
+	Alias  Alias
+	Source string // Path of the config file
+}
+
+// LoadAliases loads the aliases in effect in the current directory. Aliases
+// are merged from ~/.sleepship.toml and every .sleepship.toml from the
+// repository root down to the current directory; definitions closer to the
+// current directory shadow the others.
+func LoadAliases() (map[string]Alias, error) {
+	sources, err := LoadAliasSources()
+	if err != nil {
+		return nil, err
 	}
+	return MergeAliases(sources), nil
+}
 
-	return config.Aliases, nil
+// LoadAliasSources loads the aliases of every config file that applies to
+// the current directory, lowest priority first.
+func LoadAliasSources() ([]AliasSource, error) {
+	var sources []AliasSource
+	for _, path := range ConfigPaths() {
+		var config AliasConfig
+		if _, err := toml.DecodeFile(path, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		sources = append(sources, AliasSource{Path: path, Aliases: config.Aliases})
+	}
+	return sources, nil
+}
+
+// MergeAliases merges alias sources given lowest priority first.
+func MergeAliases(sources []AliasSource) map[string]Alias {
+	merged := make(map[string]Alias)
+	for _, source := range sources {
+		for name, alias := range source.Aliases {
+			merged[name] = alias
+		}
+	}
+	return merged
+}
+
+// AliasDefinitions returns every definition of each alias, highest priority
+// first. The first definition is the one in effect; the others are shadowed.
+func AliasDefinitions(sources []AliasSource) map[string][]AliasDefinition {
+	definitions := make(map[string][]AliasDefinition)
+	for i := len(sources) - 1; i >= 0; i-- {
+		for name, alias := range sources[i].Aliases {
+			definitions[name] = append(definitions[name], AliasDefinition{Alias: alias, Source: sources[i].Path})
+		}
+	}
+	return definitions
 }
 
 // ResolveAlias resolves an alias to its command
-// It detects and prevents circular references
-func ResolveAlias(alias string, aliases map[string]string) (string, error) {
+// It detects and prevents circular references, including references from
+// the steps of multi-step aliases. A multi-step alias resolves to its steps
+// joined by &&, and is not inlined into aliases that refer to it.
+func ResolveAlias(alias string, aliases map[string]Alias) (string, error) {
 	visited := make(map[string]bool)
 	return resolveAliasRecursive(alias, aliases, visited)
 }
 
-func resolveAliasRecursive(alias string, aliases map[string]string, visited map[string]bool) (string, error) {
+func resolveAliasRecursive(name string, aliases map[string]Alias, visited map[string]bool) (string, error) {
 	// Check for circular reference before processing
-	if visited[alias] {
-		return "", fmt.Errorf("circular reference detected in alias: %s", alias)
+	if visited[name] {
+		return "", fmt.Errorf("circular reference detected in alias: %s", name)
 	}
 
 	// Check if alias exists
-	command, exists := aliases[alias]
+	alias, exists := aliases[name]
 	if !exists {
-		return "", fmt.Errorf("alias not found: %s", alias)
+		return "", fmt.Errorf("alias not found: %s", name)
 	}
 
-	// Mark as visited
-	visited[alias] = true
+	// Mark as visited while resolving the aliases it refers to
+	visited[name] = true
+	defer delete(visited, name)
 
-	// Parse command to check if it contains another alias
-	parts := strings.Fields(command)
-	if len(parts) == 0 {
-		return command, nil
+	if alias.IsMultiStep() {
+		for _, step := range alias.Steps {
+			firstWord, _ := splitFirstWord(step)
+			if _, isAlias := aliases[firstWord]; isAlias {
+				if _, err := resolveAliasRecursive(firstWord, aliases, visited); err != nil {
+					return "", err
+				}
+			}
+		}
+		return alias.String(), nil
 	}
 
-	// Check if the first word is an alias
-	firstWord := parts[0]
-	if _, isAlias := aliases[firstWord]; isAlias {
-		// Recursively resolve the alias with the same visited map
+	// Check if the first word is an alias. Only the first word is replaced so
+	// that quoting in the rest of the command is kept as written.
+	firstWord, rest := splitFirstWord(alias.Command)
+	if inner, isAlias := aliases[firstWord]; isAlias {
+		// Recursively resolve the alias
 		resolvedFirst, err := resolveAliasRecursive(firstWord, aliases, visited)
 		if err != nil {
 			return "", err
 		}
-		// Replace the first word with resolved alias
-		parts[0] = resolvedFirst
-		return strings.Join(parts, " "), nil
+		if inner.IsMultiStep() {
+			return alias.Command, nil
+		}
+		if rest == "" {
+			return resolvedFirst, nil
+		}
+		return resolvedFirst + " " + rest, nil
+	}
+
+	return alias.Command, nil
+}
+
+// splitFirstWord splits a command into its first word and the rest.
+func splitFirstWord(command string) (string, string) {
+	firstWord := strings.TrimLeftFunc(command, unicode.IsSpace)
+	if end := strings.IndexFunc(firstWord, unicode.IsSpace); end >= 0 {
+		return firstWord[:end], strings.TrimLeftFunc(firstWord[end:], unicode.IsSpace)
+	}
+	return firstWord, ""
+}
+
+// ResolveAliasArgs expands an alias called with args into the arguments of
+// the command to run. If the expansion starts with another alias, the rest
+// of the expansion becomes the arguments of that alias. Expansion stops at a
+// multi-step alias, which is returned with its arguments for the caller to
+// run its steps.
+// Example: aliases={"dev": "sync $1", "mine": "dev 'my tasks.txt'"}, alias="mine", args=["--keep-going"]
+// Result: ["sync", "my tasks.txt", "--keep-going"]
+func ResolveAliasArgs(alias string, args []string, aliases map[string]Alias) ([]string, error) {
+	visited := make(map[string]bool)
+	for {
+		if visited[alias] {
+			return nil, fmt.Errorf("circular reference detected in alias: %s", alias)
+		}
+		definition, exists := aliases[alias]
+		if !exists {
+			return nil, fmt.Errorf("alias not found: %s", alias)
+		}
+		visited[alias] = true
+
+		if definition.IsMultiStep() {
+			return append([]string{alias}, args...), nil
+		}
+
+		words, err := ExpandAlias(definition.Command, args)
+		if err != nil && len(visited) > 1 {
+			return nil, fmt.Errorf("in alias %s: %w", alias, err)
+		} else if err != nil {
+			return nil, err
+		}
+		if len(words) == 0 {
+			return words, nil
+		}
+		if _, isAlias := aliases[words[0]]; !isAlias {
+			return words, nil
+		}
+		alias, args = words[0], words[1:]
+	}
+}
+
+// ExpandAliasSteps expands the steps of a multi-step alias called with args.
+// Unlike single-command aliases, arguments are not appended to the steps, so
+// they can only be used through placeholders. Shell steps are returned as
+// written, without the ! prefix; the shell substitutes their placeholders.
+func ExpandAliasSteps(alias Alias, args []string) ([]AliasStep, error) {
+	var steps []AliasStep
+	used, usedAll := 0, false
+	for _, step := range alias.Steps {
+		if shell, ok := strings.CutPrefix(strings.TrimSpace(step), ShellStepPrefix); ok {
+			steps = append(steps, AliasStep{Shell: shell})
+			usedAll = true
+			continue
+		}
+
+		e := &aliasExpander{args: args}
+		if err := e.split(step); err != nil {
+			return nil, err
+		}
+		if len(e.words) == 0 {
+			return nil, fmt.Errorf("empty step in alias")
+		}
+		steps = append(steps, AliasStep{Args: e.words})
+		used, usedAll = max(used, e.used), usedAll || e.usedAll
+	}
+
+	if !usedAll && used < len(args) {
+		return nil, fmt.Errorf("unexpected arguments %q: steps of a multi-step alias only take arguments through placeholders such as $1 or $@", args[used:])
+	}
+	return steps, nil
+}
+
+// AliasStep is an expanded step of a multi-step alias: either the arguments
+// of a sleepship command or a shell command.
+type AliasStep struct {
+	Args  []string
+	Shell string
+}
+
+// ExpandAlias splits an alias command into arguments with shell quoting
+// rules and substitutes the placeholders in it with args:
+//
+//	$1 … $9, ${10}    the argument at that position (required)
+//	${1:-default}     the argument, or default if it is missing or empty
+//	$@                all arguments, each as a separate word
+//	${NAME:-default}  the environment variable NAME, or default if it is unset or empty
+//
+// Single quotes keep everything literal, double quotes allow placeholders
+// and backslash escapes of \, ", $ and `. Substituted values are never split
+// into words. Arguments that no placeholder refers to are appended.
+//
+// Example: command="sync $1 --max-retries=${2:-3}", args=["my tasks.txt"]
+// Result: ["sync", "my tasks.txt", "--max-retries=3"]
+func ExpandAlias(command string, args []string) ([]string, error) {
+	e := &aliasExpander{args: args}
+	if err := e.split(command); err != nil {
+		return nil, err
+	}
+
+	if !e.usedAll && e.used < len(args) {
+		e.words = append(e.words, args[e.used:]...)
+	}
+	return e.words, nil
+}
+
+// aliasExpander holds the state of ExpandAlias.
+type aliasExpander struct {
+	args    []string
+	used    int  // Highest argument position referred to
+	usedAll bool // Whether $@ was used
+
+	words     []string
+	word      strings.Builder
+	inWord    bool // Whether a word has started, possibly empty ("")
+	emptyArgs bool // Whether the current word contains "$@" without arguments
+}
+
+// split splits command into e.words, expanding placeholders.
+func (e *aliasExpander) split(command string) error {
+	var quote byte
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				e.word.WriteByte(c)
+			}
+		case c == '$':
+			n, err := e.expand(command[i:])
+			if err != nil {
+				return err
+			}
+			i += n - 1
+		case quote == '"':
+			switch {
+			case c == '"':
+				quote = 0
+			case c == '\\' && i+1 < len(command) && strings.IndexByte("\\\"$`", command[i+1]) >= 0:
+				i++
+				e.word.WriteByte(command[i])
+			default:
+				e.word.WriteByte(c)
+			}
+		case c == ' ' || c == '\t' || c == '\n':
+			e.endWord()
+		case c == '\'' || c == '"':
+			quote = c
+			e.inWord = true
+		case c == '\\' && i+1 < len(command):
+			i++
+			e.word.WriteByte(command[i])
+			e.inWord = true
+		default:
+			e.word.WriteByte(c)
+			e.inWord = true
+		}
 	}
 
-	return command, nil
+	if quote != 0 {
+		return fmt.Errorf("unterminated %c quote in alias: %s", quote, command)
+	}
+	e.endWord()
+	return nil
 }
 
-// ExpandAliasArgs expands an alias with its arguments
-// Example: alias="sync tasks-dev.txt", args=["--max-retries=5"]
-// Result: "sync tasks-dev.txt --max-retries=5"
-func ExpandAliasArgs(command string, args []string) string {
-	if len(args) == 0 {
-		return command
+// expand substitutes the placeholder at the start of s and returns its
+// length. A $ that does not start a placeholder is kept as is.
+func (e *aliasExpander) expand(s string) (int, error) {
+	if len(s) < 2 {
+		e.writeValue("$")
+		return 1, nil
+	}
+
+	switch c := s[1]; {
+	case c >= '1' && c <= '9':
+		value, err := e.lookup(string(c), nil)
+		if err != nil {
+			return 0, err
+		}
+		e.writeValue(value)
+		return 2, nil
+	case c == '@':
+		e.writeArgs()
+		return 2, nil
+	case c == '{':
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return 0, fmt.Errorf("unterminated placeholder in alias: %s", s)
+		}
+		name, def, hasDefault := strings.Cut(s[2:end], ":-")
+		if name == "@" && !hasDefault {
+			e.writeArgs()
+			return end + 1, nil
+		}
+
+		var defPtr *string
+		if hasDefault {
+			defPtr = &def
+		}
+		value, err := e.lookup(name, defPtr)
+		if err != nil {
+			return 0, err
+		}
+		e.writeValue(value)
+		return end + 1, nil
+	default:
+		e.writeValue("$")
+		return 1, nil
+	}
+}
+
+// lookup returns the value of a positional argument or environment variable,
+// or def if it is missing or empty.
+func (e *aliasExpander) lookup(name string, def *string) (string, error) {
+	var value string
+	switch {
+	case isPositional(name):
+		n, _ := strconv.Atoi(name)
+		e.used = max(e.used, n)
+		if n <= len(e.args) {
+			value = e.args[n-1]
+		}
+		if n > len(e.args) && def == nil {
+			return "", fmt.Errorf("alias requires argument $%s", name)
+		}
+	case isVariableName(name):
+		var ok bool
+		value, ok = os.LookupEnv(name)
+		if !ok && def == nil {
+			return "", fmt.Errorf("environment variable %s used by alias is not set", name)
+		}
+	default:
+		return "", fmt.Errorf("invalid placeholder ${%s} in alias", name)
+	}
+
+	if value == "" && def != nil {
+		return *def, nil
+	}
+	return value, nil
+}
+
+// writeValue appends a substituted value to the current word.
+func (e *aliasExpander) writeValue(value string) {
+	e.word.WriteString(value)
+	e.inWord = true
+}
+
+// writeArgs substitutes $@, starting a new word for each argument after the
+// first.
+func (e *aliasExpander) writeArgs() {
+	e.usedAll = true
+	if len(e.args) == 0 {
+		e.emptyArgs = true
+		return
+	}
+	for i, arg := range e.args {
+		if i > 0 {
+			e.endWord()
+		}
+		e.writeValue(arg)
+	}
+}
+
+// endWord finishes the current word, if any. A word that only consisted of
+// "$@" without arguments is dropped, as in a shell.
+func (e *aliasExpander) endWord() {
+	if e.inWord && !(e.emptyArgs && e.word.Len() == 0) {
+		e.words = append(e.words, e.word.String())
+	}
+	e.word.Reset()
+	e.inWord = false
+	e.emptyArgs = false
+}
+
+// isPositional reports whether name refers to a positional argument.
+func isPositional(name string) bool {
+	if name == "" || name[0] == '0' {
+		return false
+	}
+	for _, c := range name {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isVariableName reports whether name is a valid environment variable name.
+func isVariableName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, c := range name {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
 	}
-	return command + " " + strings.Join(args, " ")
+	return true
 }
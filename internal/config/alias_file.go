@@ -0,0 +1,412 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// aliasTable is the name of the table that holds aliases
+const aliasTable = "aliases"
+
+// bareKeyPattern matches TOML keys that can be written without quotes
+var bareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// AliasFile edits the [aliases] table of a config file. Edits change only the
+// lines of the affected alias, so comments, formatting and other sections of
+// the file are kept.
+type AliasFile struct {
+	Path    string
+	content string
+}
+
+// tomlEntry is the position of a key/value pair in a TOML document.
+type tomlEntry struct {
+	table      string // Table the entry belongs to, "" for the root table
+	key        string // Unquoted key, empty for dotted keys
+	start, end int    // Offsets of the entry's lines, including the newline
+	keyStart   int
+	keyEnd     int
+	valueStart int
+	valueEnd   int
+}
+
+// tomlTable is the position of a table header in a TOML document.
+type tomlTable struct {
+	name       string
+	start, end int // Offsets of the header line, including the newline
+}
+
+// OpenAliasFile reads a config file for editing. A missing file is treated
+// as empty and created by Save.
+func OpenAliasFile(path string) (*AliasFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	f := &AliasFile{Path: path, content: string(data)}
+	if _, _, err := f.scan(); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Aliases returns the aliases currently defined in the file.
+func (f *AliasFile) Aliases() (map[string]Alias, error) {
+	var config AliasConfig
+	if _, err := toml.Decode(f.content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", f.Path, err)
+	}
+	if config.Aliases == nil {
+		config.Aliases = make(map[string]Alias)
+	}
+	return config.Aliases, nil
+}
+
+// Set defines an alias, replacing the value of an existing definition in
+// place or adding it at the end of the [aliases] table. The steps of a
+// multi-step alias are written one per line.
+func (f *AliasFile) Set(name string, alias Alias) error {
+	entries, tables, err := f.scan()
+	if err != nil {
+		return err
+	}
+
+	value := quoteTOMLString(alias.Command)
+	if alias.IsMultiStep() {
+		var b strings.Builder
+		b.WriteString("[\n")
+		for _, step := range alias.Steps {
+			b.WriteString("  " + quoteTOMLString(step) + ",\n")
+		}
+		b.WriteString("]")
+		value = b.String()
+	}
+	if entry := findAliasEntry(entries, name); entry != nil {
+		f.replace(entry.valueStart, entry.valueEnd, value)
+		return nil
+	}
+
+	line := formatTOMLKey(name) + " = " + value + "\n"
+	for _, table := range tables {
+		if table.name != aliasTable {
+			continue
+		}
+		// Insert after the last entry of the table, before any blank lines
+		// or comments that separate it from the next table
+		pos := table.end
+		for _, entry := range entries {
+			if entry.table == aliasTable && entry.start >= table.end {
+				pos = max(pos, entry.end)
+			}
+		}
+		if pos > 0 && f.content[pos-1] != '\n' {
+			line = "\n" + line
+		}
+		f.replace(pos, pos, line)
+		return nil
+	}
+
+	// No [aliases] table yet
+	section := "[" + aliasTable + "]\n" + line
+	switch {
+	case f.content == "":
+	case strings.HasSuffix(f.content, "\n"):
+		section = "\n" + section
+	default:
+		section = "\n\n" + section
+	}
+	f.content += section
+	return nil
+}
+
+// Remove removes the definition of an alias.
+func (f *AliasFile) Remove(name string) error {
+	entries, _, err := f.scan()
+	if err != nil {
+		return err
+	}
+
+	entry := findAliasEntry(entries, name)
+	if entry == nil {
+		return fmt.Errorf("alias not found in %s: %s", f.Path, name)
+	}
+	f.replace(entry.start, entry.end, "")
+	return nil
+}
+
+// Rename renames an alias, keeping its value and position.
+func (f *AliasFile) Rename(oldName, newName string) error {
+	entries, _, err := f.scan()
+	if err != nil {
+		return err
+	}
+
+	entry := findAliasEntry(entries, oldName)
+	if entry == nil {
+		return fmt.Errorf("alias not found in %s: %s", f.Path, oldName)
+	}
+	if findAliasEntry(entries, newName) != nil {
+		return fmt.Errorf("alias already exists in %s: %s", f.Path, newName)
+	}
+	f.replace(entry.keyStart, entry.keyEnd, formatTOMLKey(newName))
+	return nil
+}
+
+// Save writes the file after checking that the edited content is valid TOML.
+func (f *AliasFile) Save() error {
+	if _, err := f.Aliases(); err != nil {
+		return fmt.Errorf("edit would produce an invalid config file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(f.Path, []byte(f.content)); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// CheckAliases returns an error if any alias refers to itself, directly or
+// through other aliases.
+func CheckAliases(aliases map[string]Alias) error {
+	for name := range aliases {
+		if _, err := ResolveAlias(name, aliases); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *AliasFile) replace(start, end int, text string) {
+	f.content = f.content[:start] + text + f.content[end:]
+}
+
+// findAliasEntry returns the entry that defines an alias, or nil.
+func findAliasEntry(entries []tomlEntry, name string) *tomlEntry {
+	for i := range entries {
+		if entries[i].table == aliasTable && entries[i].key == name {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// scan locates the table headers and key/value pairs of the file. It only
+// understands as much TOML as needed to find where each entry starts and
+// ends; Save checks the result with a full parser.
+func (f *AliasFile) scan() ([]tomlEntry, []tomlTable, error) {
+	var entries []tomlEntry
+	var tables []tomlTable
+	s := f.content
+	table := ""
+
+	for pos := 0; pos < len(s); {
+		lineStart := pos
+		pos = skipSpace(s, pos)
+
+		switch {
+		case pos >= len(s):
+		case s[pos] == '\n' || s[pos] == '\r' || s[pos] == '#':
+			pos = lineEnd(s, pos)
+		case s[pos] == '[':
+			end := strings.IndexByte(s[pos:], ']')
+			if end < 0 {
+				return nil, nil, fmt.Errorf("unterminated table header at offset %d", pos)
+			}
+			name := strings.Trim(s[pos+1:pos+end], "[] \t")
+			table = strings.ReplaceAll(strings.ReplaceAll(name, " ", ""), "\t", "")
+			pos = lineEnd(s, pos+end)
+			tables = append(tables, tomlTable{name: table, start: lineStart, end: pos})
+		default:
+			entry := tomlEntry{table: table, start: lineStart, keyStart: pos}
+			key, next, err := scanKey(s, pos)
+			if err != nil {
+				return nil, nil, err
+			}
+			entry.key = key
+			entry.keyEnd = next
+
+			next = skipSpace(s, next)
+			if next >= len(s) || s[next] != '=' {
+				return nil, nil, fmt.Errorf("expected = after key at offset %d", entry.keyStart)
+			}
+			entry.valueStart = skipSpace(s, next+1)
+			if entry.valueEnd, err = scanValue(s, entry.valueStart); err != nil {
+				return nil, nil, err
+			}
+			pos = lineEnd(s, entry.valueEnd)
+			entry.end = pos
+			entries = append(entries, entry)
+		}
+	}
+	return entries, tables, nil
+}
+
+// scanKey scans a possibly dotted key starting at pos. It returns the
+// unquoted key, or an empty key for dotted keys, and the offset after it.
+func scanKey(s string, pos int) (string, int, error) {
+	var parts []string
+	for {
+		pos = skipSpace(s, pos)
+		if pos >= len(s) {
+			return "", pos, fmt.Errorf("unexpected end of file in key")
+		}
+
+		var part string
+		switch s[pos] {
+		case '"', '\'':
+			end, err := scanValue(s, pos)
+			if err != nil {
+				return "", pos, err
+			}
+			var decoded struct{ V string }
+			if _, err := toml.Decode("V = "+s[pos:end], &decoded); err != nil {
+				return "", pos, fmt.Errorf("invalid key at offset %d: %w", pos, err)
+			}
+			part, pos = decoded.V, end
+		default:
+			end := pos
+			for end < len(s) && bareKeyPattern.MatchString(s[end:end+1]) {
+				end++
+			}
+			if end == pos {
+				return "", pos, fmt.Errorf("invalid key at offset %d", pos)
+			}
+			part, pos = s[pos:end], end
+		}
+		parts = append(parts, part)
+
+		next := skipSpace(s, pos)
+		if next >= len(s) || s[next] != '.' {
+			break
+		}
+		pos = next + 1
+	}
+
+	if len(parts) > 1 {
+		return "", pos, nil
+	}
+	return parts[0], pos, nil
+}
+
+// scanValue returns the offset after the TOML value starting at pos.
+func scanValue(s string, pos int) (int, error) {
+	if pos >= len(s) {
+		return pos, fmt.Errorf("missing value at end of file")
+	}
+
+	switch {
+	case strings.HasPrefix(s[pos:], `"""`):
+		return scanString(s, pos+3, `"""`, true)
+	case strings.HasPrefix(s[pos:], `'''`):
+		return scanString(s, pos+3, `'''`, false)
+	case s[pos] == '"':
+		return scanString(s, pos+1, `"`, true)
+	case s[pos] == '\'':
+		return scanString(s, pos+1, `'`, false)
+	case s[pos] == '[' || s[pos] == '{':
+		// Arrays and inline tables: find the matching bracket, skipping
+		// strings and comments
+		depth := 0
+		for i := pos; i < len(s); i++ {
+			switch s[i] {
+			case '[', '{':
+				depth++
+			case ']', '}':
+				depth--
+				if depth == 0 {
+					return i + 1, nil
+				}
+			case '#':
+				i = lineEnd(s, i) - 1
+			case '"', '\'':
+				end, err := scanValue(s, i)
+				if err != nil {
+					return pos, err
+				}
+				i = end - 1
+			}
+		}
+		return pos, fmt.Errorf("unterminated array at offset %d", pos)
+	default:
+		end := pos
+		for end < len(s) && !strings.ContainsRune(" \t\r\n#,]}", rune(s[end])) {
+			end++
+		}
+		return end, nil
+	}
+}
+
+// scanString returns the offset after the closing delimiter of a string whose
+// content starts at pos.
+func scanString(s string, pos int, delim string, escapes bool) (int, error) {
+	for i := pos; i < len(s); i++ {
+		switch {
+		case escapes && s[i] == '\\':
+			i++
+		case strings.HasPrefix(s[i:], delim):
+			return i + len(delim), nil
+		case s[i] == '\n' && len(delim) == 1:
+			return pos, fmt.Errorf("unterminated string at offset %d", pos)
+		}
+	}
+	return pos, fmt.Errorf("unterminated string at offset %d", pos)
+}
+
+// skipSpace returns the offset of the first character at or after pos that
+// is not a space or tab.
+func skipSpace(s string, pos int) int {
+	for pos < len(s) && (s[pos] == ' ' || s[pos] == '\t') {
+		pos++
+	}
+	return pos
+}
+
+// lineEnd returns the offset after the newline that ends the line containing pos.
+func lineEnd(s string, pos int) int {
+	if i := strings.IndexByte(s[pos:], '\n'); i >= 0 {
+		return pos + i + 1
+	}
+	return len(s)
+}
+
+// formatTOMLKey formats a key, quoting it if needed.
+func formatTOMLKey(key string) string {
+	if bareKeyPattern.MatchString(key) {
+		return key
+	}
+	return quoteTOMLString(key)
+}
+
+// quoteTOMLString formats s as a TOML basic string.
+func quoteTOMLString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const aliasFileContent = `# Project settings
+[sync]
+max_retries = 5 # more than the default
+
+[aliases]
+# Daily development
+dev = "sync tasks-dev.txt" # used by CI
+"my alias" = 'sync "my tasks.txt"'
+checks = """sync checks.txt \
+  --keep-going""" # multi-line
+
+# Claude settings
+[claude]
+flags = ["--verbose"]
+`
+
+func TestAliasFileEdit(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(f *AliasFile) error
+		want string
+	}{
+		{
+			name: "replace existing alias",
+			edit: func(f *AliasFile) error { return f.Set("dev", Alias{Command: "sync tasks.txt --max-retries=1"}) },
+			want: strings.Replace(aliasFileContent, `dev = "sync tasks-dev.txt" # used by CI`, `dev = "sync tasks.txt --max-retries=1" # used by CI`, 1),
+		},
+		{
+			name: "add alias at the end of the table",
+			edit: func(f *AliasFile) error { return f.Set("prod", Alias{Command: `sync "prod tasks.txt"`}) },
+			want: strings.Replace(aliasFileContent, "--keep-going\"\"\" # multi-line\n", "--keep-going\"\"\" # multi-line\nprod = \"sync \\\"prod tasks.txt\\\"\"\n", 1),
+		},
+		{
+			name: "add alias with a quoted name",
+			edit: func(f *AliasFile) error { return f.Set("a.b", Alias{Command: "sync"}) },
+			want: strings.Replace(aliasFileContent, "--keep-going\"\"\" # multi-line\n", "--keep-going\"\"\" # multi-line\n\"a.b\" = \"sync\"\n", 1),
+		},
+		{
+			name: "remove alias",
+			edit: func(f *AliasFile) error { return f.Remove("dev") },
+			want: strings.Replace(aliasFileContent, "dev = \"sync tasks-dev.txt\" # used by CI\n", "", 1),
+		},
+		{
+			name: "remove multi-line alias",
+			edit: func(f *AliasFile) error { return f.Remove("checks") },
+			want: strings.Replace(aliasFileContent, "checks = \"\"\"sync checks.txt \\\n  --keep-going\"\"\" # multi-line\n", "", 1),
+		},
+		{
+			name: "rename quoted alias",
+			edit: func(f *AliasFile) error { return f.Rename("my alias", "mine") },
+			want: strings.Replace(aliasFileContent, `"my alias" =`, `mine =`, 1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ConfigFileName)
+			if err := os.WriteFile(path, []byte(aliasFileContent), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := OpenAliasFile(path)
+			if err != nil {
+				t.Fatalf("OpenAliasFile() error = %v", err)
+			}
+			if err := tt.edit(f); err != nil {
+				t.Fatalf("edit error = %v", err)
+			}
+			if err := f.Save(); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("file content =\n%s\nwant\n%s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestAliasFileCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "missing file", want: "[aliases]\ndev = \"sync tasks.txt\"\n"},
+		{name: "file without aliases", content: "[sync]\nmax_retries = 5\n", want: "[sync]\nmax_retries = 5\n\n[aliases]\ndev = \"sync tasks.txt\"\n"},
+		{name: "no trailing newline", content: "[sync]\nmax_retries = 5", want: "[sync]\nmax_retries = 5\n\n[aliases]\ndev = \"sync tasks.txt\"\n"},
+		{name: "empty aliases table", content: "[aliases]\n\n[sync]\n", want: "[aliases]\ndev = \"sync tasks.txt\"\n\n[sync]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "sub", ConfigFileName)
+			if tt.content != "" {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			f, err := OpenAliasFile(path)
+			if err != nil {
+				t.Fatalf("OpenAliasFile() error = %v", err)
+			}
+			if err := f.Set("dev", Alias{Command: "sync tasks.txt"}); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if err := f.Save(); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("file content = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestAliasFileMultiStep(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	if err := os.WriteFile(path, []byte("[aliases]\nnightly = \"sync nightly.txt\" # old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenAliasFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("nightly", Alias{Steps: []string{"sync --wait nightly.txt", "history --last 1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[aliases]\nnightly = [\n  \"sync --wait nightly.txt\",\n  \"history --last 1\",\n] # old\n"
+	if string(data) != want {
+		t.Errorf("file content = %q, want %q", data, want)
+	}
+
+	// Multi-line arrays are replaced and removed as a whole
+	if f, err = OpenAliasFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Remove("nightly"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "[aliases]\n" {
+		t.Errorf("file content after Remove() = %q", data)
+	}
+}
+
+func TestAliasFileErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	if err := os.WriteFile(path, []byte("[aliases]\ndev = \"sync\"\ntest = \"sync test.txt\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenAliasFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Remove("missing"); err == nil {
+		t.Error("Remove() of a missing alias expected error")
+	}
+	if err := f.Rename("missing", "other"); err == nil {
+		t.Error("Rename() of a missing alias expected error")
+	}
+	if err := f.Rename("dev", "test"); err == nil {
+		t.Error("Rename() onto an existing alias expected error")
+	}
+
+	// Aliases defined as an inline table cannot be edited line by line
+	if err := os.WriteFile(path, []byte("aliases = { dev = \"sync\" }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = OpenAliasFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("test", Alias{Command: "sync test.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(); err == nil {
+		t.Error("Save() of an invalid edit expected error")
+	}
+}
+
+func TestCheckAliases(t *testing.T) {
+	if err := CheckAliases(commandAliases(map[string]string{"dev": "sync", "quick": "dev --max-retries=1"})); err != nil {
+		t.Errorf("CheckAliases() error = %v", err)
+	}
+	if err := CheckAliases(commandAliases(map[string]string{"a": "b", "b": "a --keep-going"})); err == nil {
+		t.Error("CheckAliases() expected error for circular aliases")
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 )
 
@@ -44,6 +45,12 @@ func TestResolveAlias(t *testing.T) {
 			aliases:   map[string]string{"dev": "sync tasks-dev.txt"},
 			wantError: true,
 		},
+		{
+			name:    "nested alias keeps quoting",
+			alias:   "mine",
+			aliases: map[string]string{"dev": "sync $1", "mine": "dev  'my  tasks.txt'"},
+			want:    "sync $1 'my  tasks.txt'",
+		},
 		{
 			name:    "three level nesting",
 			alias:   "fast",
@@ -54,7 +61,7 @@ func TestResolveAlias(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ResolveAlias(tt.alias, tt.aliases)
+			got, err := ResolveAlias(tt.alias, commandAliases(tt.aliases))
 			if tt.wantError {
 				if err == nil {
 					t.Errorf("ResolveAlias() expected error but got none")
@@ -72,38 +79,194 @@ func TestResolveAlias(t *testing.T) {
 	}
 }
 
-func TestExpandAliasArgs(t *testing.T) {
+func TestExpandAlias(t *testing.T) {
+	t.Setenv("SLEEPSHIP_TEST_TASKS", "env-tasks.txt")
+
 	tests := []struct {
-		name    string
-		command string
-		args    []string
-		want    string
+		name      string
+		command   string
+		args      []string
+		want      []string
+		wantError bool
 	}{
 		{
 			name:    "no args",
 			command: "sync tasks-dev.txt",
 			args:    []string{},
-			want:    "sync tasks-dev.txt",
+			want:    []string{"sync", "tasks-dev.txt"},
 		},
 		{
 			name:    "with args",
 			command: "sync tasks-dev.txt",
 			args:    []string{"--max-retries=5"},
-			want:    "sync tasks-dev.txt --max-retries=5",
+			want:    []string{"sync", "tasks-dev.txt", "--max-retries=5"},
 		},
 		{
 			name:    "multiple args",
 			command: "sync tasks-dev.txt",
 			args:    []string{"--max-retries=5", "--start-from=2"},
-			want:    "sync tasks-dev.txt --max-retries=5 --start-from=2",
+			want:    []string{"sync", "tasks-dev.txt", "--max-retries=5", "--start-from=2"},
+		},
+		{
+			name:    "argument with spaces is kept as one word",
+			command: "sync",
+			args:    []string{"my tasks.txt"},
+			want:    []string{"sync", "my tasks.txt"},
+		},
+		{
+			name:    "double quotes",
+			command: `sync "my tasks.txt" --dir="/path/with spaces"`,
+			want:    []string{"sync", "my tasks.txt", "--dir=/path/with spaces"},
+		},
+		{
+			name:    "single quotes are literal",
+			command: `sync 'it''s "$1" \n' x`,
+			want:    []string{"sync", `its "$1" \n`, "x"},
+		},
+		{
+			name:    "escapes",
+			command: `sync my\ tasks.txt "say \"hi\" \$1 \n"`,
+			want:    []string{"sync", "my tasks.txt", `say "hi" $1 \n`},
+		},
+		{
+			name:    "empty quoted word",
+			command: `sync tasks.txt --pre-commit-check ""`,
+			want:    []string{"sync", "tasks.txt", "--pre-commit-check", ""},
+		},
+		{
+			name:    "extra whitespace",
+			command: "  sync\ttasks.txt   --keep-going  ",
+			want:    []string{"sync", "tasks.txt", "--keep-going"},
+		},
+		{
+			name:    "positional placeholders",
+			command: "sync $2 --dir=$1",
+			args:    []string{"/proj", "tasks.txt", "--keep-going"},
+			want:    []string{"sync", "tasks.txt", "--dir=/proj", "--keep-going"},
+		},
+		{
+			name:    "quoted placeholder is not split",
+			command: `sync "$1"`,
+			args:    []string{"my tasks.txt"},
+			want:    []string{"sync", "my tasks.txt"},
+		},
+		{
+			name:    "all arguments in the middle",
+			command: "sync tasks.txt $@ --keep-going",
+			args:    []string{"--max-retries=1", "--start-from=2"},
+			want:    []string{"sync", "tasks.txt", "--max-retries=1", "--start-from=2", "--keep-going"},
+		},
+		{
+			name:    "quoted all arguments without arguments",
+			command: `sync tasks.txt "$@"`,
+			want:    []string{"sync", "tasks.txt"},
+		},
+		{
+			name:    "default value",
+			command: "sync ${1:-tasks.txt} --max-retries=${2:-3}",
+			args:    []string{"dev.txt"},
+			want:    []string{"sync", "dev.txt", "--max-retries=3"},
+		},
+		{
+			name:    "empty argument uses default",
+			command: "sync ${1:-tasks.txt}",
+			args:    []string{""},
+			want:    []string{"sync", "tasks.txt"},
+		},
+		{
+			name:    "environment variable",
+			command: "sync ${SLEEPSHIP_TEST_TASKS} ${SLEEPSHIP_TEST_UNSET:-x}",
+			want:    []string{"sync", "env-tasks.txt", "x"},
+		},
+		{
+			name:    "dollar without placeholder",
+			command: "sync tasks.txt --pre-commit-check 'test $HOME' $ $x",
+			want:    []string{"sync", "tasks.txt", "--pre-commit-check", "test $HOME", "$", "$x"},
+		},
+		{
+			name:      "missing required argument",
+			command:   "sync $1",
+			wantError: true,
+		},
+		{
+			name:      "unset environment variable",
+			command:   "sync ${SLEEPSHIP_TEST_UNSET}",
+			wantError: true,
+		},
+		{
+			name:      "unterminated quote",
+			command:   `sync "tasks.txt`,
+			wantError: true,
+		},
+		{
+			name:      "unterminated placeholder",
+			command:   "sync ${1:-tasks.txt",
+			wantError: true,
+		},
+		{
+			name:      "invalid placeholder",
+			command:   "sync ${1-x}",
+			wantError: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ExpandAliasArgs(tt.command, tt.args)
-			if got != tt.want {
-				t.Errorf("ExpandAliasArgs() = %v, want %v", got, tt.want)
+			got, err := ExpandAlias(tt.command, tt.args)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("ExpandAlias() = %q, expected error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandAlias() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ExpandAlias() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveAliasArgs(t *testing.T) {
+	aliases := commandAliases(map[string]string{
+		"dev":   "sync $1 --max-retries=${2:-3}",
+		"mine":  "dev 'my tasks.txt'",
+		"quick": "mine 1 $@",
+		"a":     "b $@",
+		"b":     "a $@",
+	})
+
+	tests := []struct {
+		name      string
+		alias     string
+		args      []string
+		want      []string
+		wantError bool
+	}{
+		{name: "single alias", alias: "dev", args: []string{"tasks.txt"}, want: []string{"sync", "tasks.txt", "--max-retries=3"}},
+		{name: "nested alias gets the rest as arguments", alias: "mine", args: []string{"5", "--keep-going"}, want: []string{"sync", "my tasks.txt", "--max-retries=5", "--keep-going"}},
+		{name: "three levels", alias: "quick", args: []string{"--keep-going"}, want: []string{"sync", "my tasks.txt", "--max-retries=1", "--keep-going"}},
+		{name: "missing argument", alias: "dev", wantError: true},
+		{name: "circular reference", alias: "a", wantError: true},
+		{name: "non-existent alias", alias: "missing", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveAliasArgs(tt.alias, tt.args, aliases)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("ResolveAliasArgs() = %q, expected error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveAliasArgs() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ResolveAliasArgs() = %q, want %q", got, tt.want)
 			}
 		})
 	}
@@ -112,6 +275,7 @@ func TestExpandAliasArgs(t *testing.T) {
 func TestLoadAliases(t *testing.T) {
 	// Create temporary directory
 	tmpDir := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
 
 	// Test 1: No config file
 	t.Run("no config file", func(t *testing.T) {
@@ -161,7 +325,7 @@ prod = "sync tasks-prod.txt --max-retries=10"
 		}
 
 		for name, cmd := range expectedAliases {
-			if aliases[name] != cmd {
+			if aliases[name].Command != cmd {
 				t.Errorf("alias %s = %v, want %v", name, aliases[name], cmd)
 			}
 		}
@@ -190,3 +354,212 @@ prod = "sync tasks-prod.txt --max-retries=10"
 		}
 	})
 }
+
+func TestLoadAliasesMerge(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	// repo/.git, repo/.sleepship.toml and repo/sub/dir/.sleepship.toml
+	repo := t.TempDir()
+	workDir := filepath.Join(repo, "sub", "dir")
+	for _, dir := range []string{filepath.Join(repo, ".git"), workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(home, ConfigFileName):    "[aliases]\ndev = \"sync home.txt\"\nmine = \"sync mine.txt\"\n",
+		filepath.Join(repo, ConfigFileName):    "[aliases]\ndev = \"sync repo.txt\"\nci = \"sync ci.txt\"\n",
+		filepath.Join(workDir, ConfigFileName): "[aliases]\nci = \"sync local-ci.txt\"\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(oldDir) }()
+	_ = os.Chdir(workDir)
+	cwd, _ := os.Getwd()
+	repoDir := filepath.Dir(filepath.Dir(cwd))
+
+	wantPaths := []string{filepath.Join(home, ConfigFileName), filepath.Join(repoDir, ConfigFileName), filepath.Join(cwd, ConfigFileName)}
+	if paths := ConfigPaths(); !slices.Equal(paths, wantPaths) {
+		t.Errorf("ConfigPaths() = %v, want %v", paths, wantPaths)
+	}
+
+	aliases, err := LoadAliases()
+	if err != nil {
+		t.Fatalf("LoadAliases() error = %v", err)
+	}
+	want := map[string]string{"dev": "sync repo.txt", "mine": "sync mine.txt", "ci": "sync local-ci.txt"}
+	if len(aliases) != len(want) {
+		t.Errorf("LoadAliases() = %v, want %v", aliases, want)
+	}
+	for name, command := range want {
+		if aliases[name].Command != command {
+			t.Errorf("alias %s = %q, want %q", name, aliases[name], command)
+		}
+	}
+
+	sources, err := LoadAliasSources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	definitions := AliasDefinitions(sources)
+	if got := definitions["dev"]; len(got) != 2 || got[0].Source != wantPaths[1] || got[1].Alias.Command != "sync home.txt" {
+		t.Errorf("definitions of dev = %+v", got)
+	}
+	if got := definitions["mine"]; len(got) != 1 || got[0].Source != wantPaths[0] {
+		t.Errorf("definitions of mine = %+v", got)
+	}
+
+	// Outside a repository the parent directories are searched too
+	outside := t.TempDir()
+	outsideWork := filepath.Join(outside, "project")
+	if err := os.MkdirAll(outsideWork, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, ConfigFileName), []byte("[aliases]\nci = \"sync outside.txt\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Chdir(outsideWork)
+	if aliases, err := LoadAliases(); err != nil || aliases["ci"].Command != "sync outside.txt" {
+		t.Errorf("LoadAliases() outside a repository = %v, %v", aliases, err)
+	}
+
+	// An explicit config file replaces the files found from the current directory
+	t.Setenv(ConfigEnvVar, filepath.Join(repoDir, ConfigFileName))
+	wantExplicit := []string{wantPaths[0], wantPaths[1]}
+	if paths := ConfigPaths(); !slices.Equal(paths, wantExplicit) {
+		t.Errorf("ConfigPaths() with %s = %v, want %v", ConfigEnvVar, paths, wantExplicit)
+	}
+}
+
+func TestMultiStepAliases(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	content := `[aliases]
+dev = "sync tasks-dev.txt"
+nightly = [
+  "!test -s ${1:-nightly.txt}",
+  "sync --wait ${1:-nightly.txt}",
+  "history --last 1",
+]
+twice = ["dev --start-from=$1", "dev"]
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, ConfigFileName), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(oldDir) }()
+	_ = os.Chdir(tmpDir)
+
+	aliases, err := LoadAliases()
+	if err != nil {
+		t.Fatalf("LoadAliases() error = %v", err)
+	}
+	if aliases["dev"].IsMultiStep() || !aliases["nightly"].IsMultiStep() || len(aliases["nightly"].Steps) != 3 {
+		t.Fatalf("LoadAliases() = %+v", aliases)
+	}
+
+	// An alias that refers to the same alias in several steps is not circular
+	if err := CheckAliases(aliases); err != nil {
+		t.Errorf("CheckAliases() error = %v", err)
+	}
+	if got, err := ResolveAlias("twice", aliases); err != nil || got != "dev --start-from=$1 && dev" {
+		t.Errorf("ResolveAlias(twice) = %q, %v", got, err)
+	}
+
+	// Multi-step aliases are returned to the caller with their arguments
+	if got, err := ResolveAliasArgs("nightly", []string{"a.txt"}, aliases); err != nil || !slices.Equal(got, []string{"nightly", "a.txt"}) {
+		t.Errorf("ResolveAliasArgs(nightly) = %q, %v", got, err)
+	}
+
+	tests := []struct {
+		name      string
+		alias     string
+		args      []string
+		want      []AliasStep
+		wantError bool
+	}{
+		{
+			name:  "default arguments",
+			alias: "nightly",
+			want: []AliasStep{
+				{Shell: "test -s ${1:-nightly.txt}"},
+				{Args: []string{"sync", "--wait", "nightly.txt"}},
+				{Args: []string{"history", "--last", "1"}},
+			},
+		},
+		{
+			name:  "arguments through placeholders",
+			alias: "twice",
+			args:  []string{"3"},
+			want:  []AliasStep{{Args: []string{"dev", "--start-from=3"}}, {Args: []string{"dev"}}},
+		},
+		{
+			name:      "unused arguments",
+			alias:     "twice",
+			args:      []string{"3", "--keep-going"},
+			wantError: true,
+		},
+		{
+			name:      "missing argument",
+			alias:     "twice",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandAliasSteps(aliases[tt.alias], tt.args)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("ExpandAliasSteps() = %+v, expected error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandAliasSteps() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ExpandAliasSteps() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Shell != tt.want[i].Shell || !slices.Equal(got[i].Args, tt.want[i].Args) {
+					t.Errorf("step %d = %+v, want %+v", i+1, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	// Steps that lead back to the alias are circular
+	circular := map[string]Alias{
+		"nightly": {Steps: []string{"sync --wait nightly.txt", "report"}},
+		"report":  {Command: "nightly --quiet"},
+	}
+	if err := CheckAliases(circular); err == nil {
+		t.Error("CheckAliases() expected error for a step that refers back to its alias")
+	}
+
+	// Aliases must be strings or arrays of strings
+	for _, invalid := range []string{"[aliases]\nbad = 1\n", "[aliases]\nbad = []\n", "[aliases]\nbad = [1]\n"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, ConfigFileName), []byte(invalid), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadAliases(); err == nil {
+			t.Errorf("LoadAliases() of %q expected error", invalid)
+		}
+	}
+}
+
+// commandAliases converts single-command alias definitions to aliases.
+func commandAliases(commands map[string]string) map[string]Alias {
+	aliases := make(map[string]Alias, len(commands))
+	for name, command := range commands {
+		aliases[name] = Alias{Command: command}
+	}
+	return aliases
+}
@@ -1,17 +1,111 @@
 package config
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // Config represents the merged configuration from all sources
 type Config struct {
-	ProjectDir      string
-	DefaultTaskFile string
-	MaxRetries      int
-	LogDir          string
-	StartFrom       int
-	ClaudeFlags     []string
+	ProjectDir      string   `json:"project_dir"`
+	DefaultTaskFile string   `json:"default_task_file"`
+	MaxRetries      int      `json:"max_retries"`
+	LogDir          string   `json:"log_dir"`
+	StartFrom       int      `json:"start_from"`
+	ClaudeFlags     []string `json:"claude_flags"`
+	CommitStrategy  string   `json:"commit_strategy"`
+	RollbackPolicy  string   `json:"rollback_policy"`
+	PreCommitChecks []string `json:"pre_commit_checks"`
+	Push            string   `json:"push"`
+	PushRemote      string   `json:"push_remote"`
+	BranchBase      string   `json:"branch_base"` // Ref new branches are created from (default: HEAD)
+
+	// Run budgets: 0 (or a duration of "0") means unlimited
+	MaxDuration   string  `json:"max_duration"`    // Wall-clock time of a run, e.g. "8h"
+	MaxAgentCalls int     `json:"max_agent_calls"` // Agent invocations of a run
+	MaxCost       float64 `json:"max_cost"`        // Agent cost of a run in USD
+
+	// History retention: 0 entries or an age of "0" means unlimited
+	HistoryMaxEntries int    `json:"history_max_entries"`
+	HistoryMaxAge     string `json:"history_max_age"`
+}
+
+// Commit strategies control how sync turns agent changes into git commits.
+const (
+	// CommitPerTask creates one commit after each task passes verification.
+	CommitPerTask = "per-task"
+	// CommitPerAttempt creates a commit after every agent call, including fix attempts.
+	CommitPerAttempt = "per-attempt"
+	// CommitSquash creates a single commit at the end of the run.
+	CommitSquash = "squash"
+	// CommitNone leaves all changes uncommitted for manual review.
+	CommitNone = "none"
+)
+
+// CommitStrategies lists all supported commit strategies.
+var CommitStrategies = []string{CommitPerTask, CommitPerAttempt, CommitSquash, CommitNone}
+
+// IsValidCommitStrategy reports whether s is a supported commit strategy.
+func IsValidCommitStrategy(s string) bool {
+	for _, strategy := range CommitStrategies {
+		if s == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// Rollback policies control what happens to the working tree when a task
+// exhausts its retries.
+const (
+	// RollbackLeave leaves the failed task's changes in the working tree.
+	RollbackLeave = "leave"
+	// RollbackReset restores the working tree to its state before the task.
+	RollbackReset = "reset"
+	// RollbackPreserve saves the failed attempt under a git ref, then resets.
+	RollbackPreserve = "preserve"
+)
+
+// RollbackPolicies lists all supported rollback policies.
+var RollbackPolicies = []string{RollbackLeave, RollbackReset, RollbackPreserve}
+
+// IsValidRollbackPolicy reports whether s is a supported rollback policy.
+func IsValidRollbackPolicy(s string) bool {
+	for _, policy := range RollbackPolicies {
+		if s == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// Push modes control when sync pushes its branch to the remote.
+const (
+	// PushNever keeps all commits local.
+	PushNever = "never"
+	// PushTask pushes after every completed task.
+	PushTask = "task"
+	// PushEnd pushes once at the end of the run.
+	PushEnd = "end"
+)
+
+// PushModes lists all supported push modes.
+var PushModes = []string{PushNever, PushTask, PushEnd}
+
+// IsValidPushMode reports whether s is a supported push mode.
+func IsValidPushMode(s string) bool {
+	for _, mode := range PushModes {
+		if s == mode {
+			return true
+		}
+	}
+	return false
 }
 
 // MergeConfig merges configuration from multiple sources with priority:
-// CLI flags > Environment variables > Project settings > Global settings > Defaults
+// CLI flags > Environment variables > Defaults
 //
 // Parameters:
 // - cliConfig: Configuration from CLI flags (highest priority)
@@ -20,49 +114,88 @@ type Config struct {
 //
 // Returns: Merged configuration
 func MergeConfig(cliConfig, envConfig, defaultConfig *Config) *Config {
+	return MergeLayers(defaultConfig, cliConfig, envConfig)
+}
+
+// MergeLayers merges any number of configuration layers on top of defaults,
+// for sources beyond those of MergeConfig, such as .sleepship.toml.
+//
+// defaults is the lowest priority layer and is always used as the fallback:
+// its values are taken as they are, including those that mean "unset" in the
+// other layers (-1 for numbers). The other layers are passed from highest to
+// lowest priority, for example:
+// - cliConfig: Configuration from CLI flags (highest priority)
+// - envConfig: Configuration from environment variables
+// - memberConfig: Configuration from the workspace member that is run
+// - contextConfig: Configuration from the context in use
+// - fileConfig: Configuration from .sleepship.toml
+//
+// Returns: Merged configuration
+func MergeLayers(defaults *Config, layers ...*Config) *Config {
 	merged := &Config{}
+	if defaults == nil {
+		defaults = &Config{}
+	}
+
+	for i := len(layers); i >= 0; i-- {
+		layer, isDefault := defaults, true
+		if i < len(layers) {
+			layer, isDefault = layers[i], false
+		}
+
+		// Project directory
+		merged.ProjectDir = selectValue(layer.ProjectDir, merged.ProjectDir)
+
+		// Default task file
+		merged.DefaultTaskFile = selectValue(layer.DefaultTaskFile, merged.DefaultTaskFile)
+
+		// Max retries (special handling for integers)
+		if isDefault || layer.MaxRetries >= 0 {
+			merged.MaxRetries = layer.MaxRetries
+		}
+
+		// Log directory
+		merged.LogDir = selectValue(layer.LogDir, merged.LogDir)
+
+		// Start from (special handling for integers)
+		if isDefault || layer.StartFrom >= 1 {
+			merged.StartFrom = layer.StartFrom
+		}
+
+		// Claude flags (arrays are replaced by the first non-empty one)
+		merged.ClaudeFlags = mergeArrays(layer.ClaudeFlags, merged.ClaudeFlags)
+
+		// Commit strategy
+		merged.CommitStrategy = selectValue(layer.CommitStrategy, merged.CommitStrategy)
 
-	// Project directory
-	merged.ProjectDir = selectValue(
-		cliConfig.ProjectDir,
-		envConfig.ProjectDir,
-		defaultConfig.ProjectDir,
-	)
-
-	// Default task file
-	merged.DefaultTaskFile = selectValue(
-		cliConfig.DefaultTaskFile,
-		envConfig.DefaultTaskFile,
-		defaultConfig.DefaultTaskFile,
-	)
-
-	// Max retries (special handling for integers)
-	merged.MaxRetries = selectIntValue(
-		cliConfig.MaxRetries, cliConfig.MaxRetries >= 0,
-		envConfig.MaxRetries, envConfig.MaxRetries >= 0,
-		defaultConfig.MaxRetries, true,
-	)
-
-	// Log directory
-	merged.LogDir = selectValue(
-		cliConfig.LogDir,
-		envConfig.LogDir,
-		defaultConfig.LogDir,
-	)
-
-	// Start from (special handling for integers)
-	merged.StartFrom = selectIntValue(
-		cliConfig.StartFrom, cliConfig.StartFrom >= 1,
-		envConfig.StartFrom, envConfig.StartFrom >= 1,
-		defaultConfig.StartFrom, true,
-	)
-
-	// Claude flags (arrays are merged, not replaced)
-	merged.ClaudeFlags = mergeArrays(
-		cliConfig.ClaudeFlags,
-		envConfig.ClaudeFlags,
-		defaultConfig.ClaudeFlags,
-	)
+		// Rollback policy
+		merged.RollbackPolicy = selectValue(layer.RollbackPolicy, merged.RollbackPolicy)
+
+		// Pre-commit checks (arrays are replaced by the first non-empty one)
+		merged.PreCommitChecks = mergeArrays(layer.PreCommitChecks, merged.PreCommitChecks)
+
+		// Push mode and remote
+		merged.Push = selectValue(layer.Push, merged.Push)
+		merged.PushRemote = selectValue(layer.PushRemote, merged.PushRemote)
+
+		// Branch base
+		merged.BranchBase = selectValue(layer.BranchBase, merged.BranchBase)
+
+		// Run budgets (special handling for numbers)
+		merged.MaxDuration = selectValue(layer.MaxDuration, merged.MaxDuration)
+		if isDefault || layer.MaxAgentCalls >= 0 {
+			merged.MaxAgentCalls = layer.MaxAgentCalls
+		}
+		if isDefault || layer.MaxCost >= 0 {
+			merged.MaxCost = layer.MaxCost
+		}
+
+		// History retention (special handling for integers)
+		if isDefault || layer.HistoryMaxEntries >= 0 {
+			merged.HistoryMaxEntries = layer.HistoryMaxEntries
+		}
+		merged.HistoryMaxAge = selectValue(layer.HistoryMaxAge, merged.HistoryMaxAge)
+	}
 
 	return merged
 }
@@ -77,20 +210,6 @@ func selectValue(values ...string) string {
 	return ""
 }
 
-// selectIntValue selects the first valid integer value
-func selectIntValue(v1 int, hasV1 bool, v2 int, hasV2 bool, v3 int, hasV3 bool) int {
-	if hasV1 {
-		return v1
-	}
-	if hasV2 {
-		return v2
-	}
-	if hasV3 {
-		return v3
-	}
-	return 0
-}
-
 // mergeArrays merges arrays, preferring the first non-empty array
 func mergeArrays(arrays ...[]string) []string {
 	for _, arr := range arrays {
@@ -110,14 +229,29 @@ func NewDefaultConfig() *Config {
 		LogDir:          "logs",
 		StartFrom:       1,
 		ClaudeFlags:     []string{},
+		CommitStrategy:  CommitPerTask,
+		RollbackPolicy:  RollbackLeave,
+		PreCommitChecks: []string{},
+		Push:            PushNever,
+		PushRemote:      "origin",
+
+		MaxDuration:   "0",
+		MaxAgentCalls: 0,
+		MaxCost:       0,
+
+		HistoryMaxEntries: 0,
+		HistoryMaxAge:     "0",
 	}
 }
 
 // FromEnv creates a Config from EnvConfig
 func FromEnv(env *EnvConfig) *Config {
 	cfg := &Config{
-		MaxRetries: -1,
-		StartFrom:  -1,
+		MaxRetries:        -1,
+		StartFrom:         -1,
+		MaxAgentCalls:     -1,
+		MaxCost:           -1,
+		HistoryMaxEntries: -1,
 	}
 
 	if env.HasProjectDir() {
@@ -138,6 +272,79 @@ func FromEnv(env *EnvConfig) *Config {
 	if env.HasClaudeFlags() {
 		cfg.ClaudeFlags = env.ClaudeFlags
 	}
+	if env.HasCommitStrategy() {
+		cfg.CommitStrategy = env.CommitStrategy
+	}
+	if env.HasRollbackPolicy() {
+		cfg.RollbackPolicy = env.RollbackPolicy
+	}
+	if env.HasPreCommitChecks() {
+		cfg.PreCommitChecks = env.PreCommitChecks
+	}
+	if env.HasPush() {
+		cfg.Push = env.Push
+	}
+	if env.HasPushRemote() {
+		cfg.PushRemote = env.PushRemote
+	}
+	if env.HasBranchBase() {
+		cfg.BranchBase = env.BranchBase
+	}
+	if env.HasMaxDuration() {
+		cfg.MaxDuration = env.MaxDuration
+	}
+	if env.HasMaxAgentCalls() {
+		cfg.MaxAgentCalls = env.MaxAgentCalls
+	}
+	if env.HasMaxCost() {
+		cfg.MaxCost = env.MaxCost
+	}
+	if env.HasHistoryMaxEntries() {
+		cfg.HistoryMaxEntries = env.HistoryMaxEntries
+	}
+	if env.HasHistoryMaxAge() {
+		cfg.HistoryMaxAge = env.HistoryMaxAge
+	}
 
 	return cfg
 }
+
+// ParseAge parses a retention age or duration budget such as "30d", "2w"
+// or "720h".
+// In addition to time.ParseDuration units it accepts whole days ("d") and
+// weeks ("w"). "0" means unlimited and is returned as 0.
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("age must not be empty")
+	}
+	if s == "0" {
+		return 0, nil
+	}
+
+	var age time.Duration
+	if unit, ok := ageUnits[s[len(s)-1:]]; ok && len(s) > 1 {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		age = time.Duration(n) * unit
+	} else {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q (examples: 30d, 2w, 12h)", s)
+		}
+		age = d
+	}
+
+	if age < 0 {
+		return 0, fmt.Errorf("invalid age %q (must not be negative)", s)
+	}
+	return age, nil
+}
+
+// ageUnits are the units ParseAge accepts in addition to time.ParseDuration.
+var ageUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
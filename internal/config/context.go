@@ -0,0 +1,275 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ContextEnvVar is the environment variable that selects the context to use.
+// The --context flag sets it so that child processes use the same context.
+const ContextEnvVar = "SLEEPSHIP_CONTEXT"
+
+// contextTable is the name of the table that holds contexts
+const contextTable = "contexts"
+
+// currentContextFile is the file in UserConfigDir that stores the name of
+// the context selected with "sleepship context use"
+const currentContextFile = "context"
+
+// Context is a named set of settings for switching between projects, defined
+// in a [contexts.<name>] table of a config file.
+type Context struct {
+	ProjectDir      string   `toml:"project_dir"`
+	DefaultTaskFile string   `toml:"default_task_file"`
+	ClaudeFlags     []string `toml:"claude_flags"`
+	MaxRetries      *int     `toml:"max_retries"`
+	Rollback        string   `toml:"rollback"`
+	BranchBase      string   `toml:"branch_base"`
+}
+
+// ContextDefinition is a context and the config file it is defined in.
+type ContextDefinition struct {
+	Context Context
+	Source  string
+}
+
+// contextConfig represents the contexts of a config file
+type contextConfig struct {
+	Contexts map[string]Context `toml:"contexts"`
+}
+
+// LoadContexts loads the contexts defined in the config files that apply to
+// the current directory. A context defined in several files is taken from
+// the file with the highest priority. Relative paths are resolved against
+// the directory of the file: project_dir first, then default_task_file
+// against the project directory.
+func LoadContexts() (map[string]ContextDefinition, error) {
+	contexts := make(map[string]ContextDefinition)
+	for _, path := range ConfigPaths() {
+		var config contextConfig
+		if _, err := toml.DecodeFile(path, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		for name, ctx := range config.Contexts {
+			if problems := contextProblems(path, name, &ctx); len(problems) > 0 {
+				return nil, problems[0]
+			}
+			contexts[name] = ContextDefinition{Context: resolveContextPaths(ctx, filepath.Dir(path)), Source: path}
+		}
+	}
+	return contexts, nil
+}
+
+// resolveContextPaths makes the paths of a context absolute. A leading ~/
+// in project_dir is the home directory.
+func resolveContextPaths(ctx Context, dir string) Context {
+	if rest, ok := strings.CutPrefix(ctx.ProjectDir, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			ctx.ProjectDir = filepath.Join(home, rest)
+		}
+	}
+	if ctx.ProjectDir != "" && !filepath.IsAbs(ctx.ProjectDir) {
+		ctx.ProjectDir = filepath.Join(dir, ctx.ProjectDir)
+	}
+	if ctx.ProjectDir != "" {
+		dir = ctx.ProjectDir
+	}
+	if ctx.DefaultTaskFile != "" && !filepath.IsAbs(ctx.DefaultTaskFile) {
+		ctx.DefaultTaskFile = filepath.Join(dir, ctx.DefaultTaskFile)
+	}
+	return ctx
+}
+
+// contextProblems checks the values of the settings of a context.
+func contextProblems(path, name string, ctx *Context) []*ValidationError {
+	var problems []*ValidationError
+	prefix := contextTable + "." + formatTOMLKey(name) + "."
+	if ctx.MaxRetries != nil {
+		if err := checkMin(float64(*ctx.MaxRetries), 0); err != nil {
+			problems = append(problems, newValidationError(path, prefix+"max_retries", err))
+		}
+	}
+	if ctx.Rollback != "" {
+		if err := checkChoice(ctx.Rollback, RollbackPolicies); err != nil {
+			problems = append(problems, newValidationError(path, prefix+"rollback", err))
+		}
+	}
+	return problems
+}
+
+// CurrentContext returns the name of the context in use: the one selected
+// with --context or SLEEPSHIP_CONTEXT, or else the one stored by
+// SetCurrentContext. It returns an empty string if no context is in use.
+func CurrentContext() (string, error) {
+	if name := os.Getenv(ContextEnvVar); name != "" {
+		return name, nil
+	}
+
+	path, err := currentContextPath()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read current context: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// SetCurrentContext stores the context used by later commands. An empty name
+// stops using a context.
+func SetCurrentContext(name string) error {
+	path, err := currentContextPath()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to clear current context: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(path, []byte(name+"\n")); err != nil {
+		return fmt.Errorf("failed to store current context: %w", err)
+	}
+	return nil
+}
+
+func currentContextPath() (string, error) {
+	dir, err := UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, currentContextFile), nil
+}
+
+// LoadContextConfig returns the configuration layer of the context in use
+// and its name. Without a context, the layer is empty and the name is "".
+func LoadContextConfig() (*Config, string, error) {
+	name, err := CurrentContext()
+	if err != nil || name == "" {
+		return FromContext(Context{}), "", err
+	}
+
+	contexts, err := LoadContexts()
+	if err != nil {
+		return nil, "", err
+	}
+	definition, ok := contexts[name]
+	if !ok {
+		return nil, "", fmt.Errorf("context %q is not defined", name)
+	}
+	return FromContext(definition.Context), name, nil
+}
+
+// FromContext creates a Config from a Context
+func FromContext(ctx Context) *Config {
+	cfg := &Config{
+		ProjectDir:      ctx.ProjectDir,
+		DefaultTaskFile: ctx.DefaultTaskFile,
+		MaxRetries:      -1,
+		StartFrom:       -1,
+		ClaudeFlags:     ctx.ClaudeFlags,
+		RollbackPolicy:  ctx.Rollback,
+		BranchBase:      ctx.BranchBase,
+		MaxAgentCalls:   -1,
+		MaxCost:         -1,
+
+		HistoryMaxEntries: -1,
+	}
+	if ctx.MaxRetries != nil {
+		cfg.MaxRetries = *ctx.MaxRetries
+	}
+	return cfg
+}
+
+// Settings returns the settings of a context that are set, as TOML keys and
+// values.
+func (c Context) Settings() [][2]string {
+	var settings [][2]string
+	add := func(key, value string) {
+		settings = append(settings, [2]string{key, value})
+	}
+	if c.ProjectDir != "" {
+		add("project_dir", quoteTOMLString(c.ProjectDir))
+	}
+	if c.DefaultTaskFile != "" {
+		add("default_task_file", quoteTOMLString(c.DefaultTaskFile))
+	}
+	if len(c.ClaudeFlags) > 0 {
+		flags := make([]string, len(c.ClaudeFlags))
+		for i, flag := range c.ClaudeFlags {
+			flags[i] = quoteTOMLString(flag)
+		}
+		add("claude_flags", "["+strings.Join(flags, ", ")+"]")
+	}
+	if c.MaxRetries != nil {
+		add("max_retries", strconv.Itoa(*c.MaxRetries))
+	}
+	if c.Rollback != "" {
+		add("rollback", quoteTOMLString(c.Rollback))
+	}
+	if c.BranchBase != "" {
+		add("branch_base", quoteTOMLString(c.BranchBase))
+	}
+	return settings
+}
+
+// AddContext adds a [contexts.<name>] table to the end of a config file,
+// creating the file if it does not exist. Other content of the file is kept.
+func AddContext(path, name string, ctx Context) error {
+	if problems := contextProblems(path, name, &ctx); len(problems) > 0 {
+		return problems[0]
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var existing contextConfig
+	if _, err := toml.Decode(string(data), &existing); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if _, ok := existing.Contexts[name]; ok {
+		return fmt.Errorf("context already exists in %s: %s", path, name)
+	}
+
+	var b strings.Builder
+	b.Write(data)
+	switch {
+	case len(data) == 0:
+	case strings.HasSuffix(string(data), "\n"):
+		b.WriteString("\n")
+	default:
+		b.WriteString("\n\n")
+	}
+	b.WriteString("[" + contextTable + "." + formatTOMLKey(name) + "]\n")
+	for _, setting := range ctx.Settings() {
+		b.WriteString(setting[0] + " = " + setting[1] + "\n")
+	}
+
+	content := b.String()
+	if _, err := toml.Decode(content, &existing); err != nil {
+		return fmt.Errorf("edit would produce an invalid config file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(path, []byte(content)); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
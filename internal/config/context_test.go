@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadContexts(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv(ConfigEnvVar, "")
+	t.Setenv(ContextEnvVar, "")
+
+	globalConfig := `[contexts.api]
+project_dir = "~/src/api"
+default_task_file = "tasks.txt"
+claude_flags = ["--model", "opus"]
+max_retries = 5
+branch_base = "origin/main"
+
+[contexts.docs]
+project_dir = "docs"
+`
+	projectConfig := `[sync]
+max_retries = 2
+rollback = "reset"
+
+[contexts.docs]
+rollback = "preserve"
+`
+	project := t.TempDir()
+	files := map[string]string{
+		filepath.Join(home, ConfigFileName):    globalConfig,
+		filepath.Join(project, ConfigFileName): projectConfig,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(oldDir) }()
+	_ = os.Chdir(project)
+
+	contexts, err := LoadContexts()
+	if err != nil {
+		t.Fatalf("LoadContexts() error = %v", err)
+	}
+	api := contexts["api"].Context
+	if api.ProjectDir != filepath.Join(home, "src", "api") || api.DefaultTaskFile != filepath.Join(home, "src", "api", "tasks.txt") {
+		t.Errorf("api paths = %q, %q", api.ProjectDir, api.DefaultTaskFile)
+	}
+	// The project's definition of docs replaces the global one
+	if docs := contexts["docs"]; docs.Context.ProjectDir != "" || docs.Context.Rollback != "preserve" || docs.Source != filepath.Join(project, ConfigFileName) {
+		t.Errorf("docs = %+v", docs)
+	}
+
+	// No context in use
+	layer, name, err := LoadContextConfig()
+	if err != nil || name != "" || layer.MaxRetries != -1 {
+		t.Errorf("LoadContextConfig() without a context = %+v, %q, %v", layer, name, err)
+	}
+
+	// The stored context, overridden by SLEEPSHIP_CONTEXT
+	if err := SetCurrentContext("api"); err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := CurrentContext(); current != "api" {
+		t.Errorf("CurrentContext() = %q, want api", current)
+	}
+	fileConfig, err := LoadFileConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer, name, err = LoadContextConfig()
+	if err != nil || name != "api" {
+		t.Fatalf("LoadContextConfig() = %q, %v", name, err)
+	}
+	merged := MergeLayers(NewDefaultConfig(), FromEnv(LoadFromEnv()), layer, FromFile(fileConfig))
+	if merged.MaxRetries != 5 || merged.RollbackPolicy != "reset" || merged.BranchBase != "origin/main" || !slices.Equal(merged.ClaudeFlags, []string{"--model", "opus"}) {
+		t.Errorf("merged config = %+v", merged)
+	}
+
+	t.Setenv("SLEEPSHIP_SYNC_MAX_RETRIES", "7")
+	merged = MergeLayers(NewDefaultConfig(), FromEnv(LoadFromEnv()), layer, FromFile(fileConfig))
+	if merged.MaxRetries != 7 {
+		t.Errorf("MaxRetries = %d, want 7 (environment over context)", merged.MaxRetries)
+	}
+
+	t.Setenv(ContextEnvVar, "missing")
+	if _, _, err := LoadContextConfig(); err == nil {
+		t.Error("LoadContextConfig() expected error for an undefined context")
+	}
+
+	t.Setenv(ContextEnvVar, "")
+	if err := SetCurrentContext(""); err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := CurrentContext(); current != "" {
+		t.Errorf("CurrentContext() after clearing = %q", current)
+	}
+}
+
+func TestAddContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	if err := os.WriteFile(path, []byte("# settings\n[sync]\nmax_retries = 2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	retries := 4
+	if err := AddContext(path, "api", Context{ProjectDir: "/src/api", ClaudeFlags: []string{"--verbose"}, MaxRetries: &retries}); err != nil {
+		t.Fatalf("AddContext() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# settings\n[sync]\nmax_retries = 2\n\n[contexts.api]\nproject_dir = \"/src/api\"\nclaude_flags = [\"--verbose\"]\nmax_retries = 4\n"
+	if string(data) != want {
+		t.Errorf("file content = %q, want %q", data, want)
+	}
+	if problems := ValidateFile(path); len(problems) != 0 {
+		t.Errorf("ValidateFile() = %v", problems)
+	}
+
+	if err := AddContext(path, "api", Context{}); err == nil {
+		t.Error("AddContext() expected error for an existing context")
+	}
+	if err := AddContext(path, "bad", Context{Rollback: "later"}); err == nil {
+		t.Error("AddContext() expected error for an invalid rollback policy")
+	}
+}
@@ -5,7 +5,6 @@ package config
 
 import (
 	"os"
-	"strconv"
 	"strings"
 )
 
@@ -17,9 +16,22 @@ type EnvConfig struct {
 	LogDir          string
 	StartFrom       int
 	ClaudeFlags     []string
+	CommitStrategy  string
+	RollbackPolicy  string
+	PreCommitChecks []string
+	Push            string
+	PushRemote      string
+	BranchBase      string
+	MaxDuration     string
+	MaxAgentCalls   int
+	MaxCost         float64
+
+	HistoryMaxEntries int
+	HistoryMaxAge     string
 }
 
-// LoadFromEnv loads configuration from environment variables
+// LoadFromEnv loads configuration from environment variables. Invalid values
+// are ignored; ValidateEnv reports them.
 // Environment variables should be prefixed with SLEEPSHIP_
 // and follow the naming convention: SLEEPSHIP_<SECTION>_<KEY>
 //
@@ -30,10 +42,24 @@ type EnvConfig struct {
 // - SLEEPSHIP_SYNC_LOG_DIR: Log directory
 // - SLEEPSHIP_SYNC_START_FROM: Start from specified task number
 // - SLEEPSHIP_CLAUDE_FLAGS: Claude Code flags (comma-separated)
+// - SLEEPSHIP_SYNC_COMMIT_STRATEGY: Commit strategy (per-task, per-attempt, squash, none)
+// - SLEEPSHIP_SYNC_ROLLBACK: Rollback policy for failed tasks (leave, reset, preserve)
+// - SLEEPSHIP_SYNC_PRE_COMMIT_CHECKS: Commands run as extra verification steps (comma-separated)
+// - SLEEPSHIP_SYNC_PUSH: When to push the branch (never, task, end)
+// - SLEEPSHIP_SYNC_PUSH_REMOTE: Remote to push to
+// - SLEEPSHIP_SYNC_BRANCH_BASE: Ref new branches are created from
+// - SLEEPSHIP_SYNC_MAX_DURATION: Wall-clock budget of a run, e.g. "8h" (0 = unlimited)
+// - SLEEPSHIP_SYNC_MAX_AGENT_CALLS: Agent invocation budget of a run (0 = unlimited)
+// - SLEEPSHIP_SYNC_MAX_COST: Agent cost budget of a run in USD (0 = unlimited)
+// - SLEEPSHIP_HISTORY_MAX_ENTRIES: Maximum number of history entries to keep (0 = unlimited)
+// - SLEEPSHIP_HISTORY_MAX_AGE: Maximum age of history entries, e.g. "30d" or "720h" (0 = unlimited)
 func LoadFromEnv() *EnvConfig {
 	cfg := &EnvConfig{
-		MaxRetries: -1, // Use -1 to indicate not set
-		StartFrom:  -1, // Use -1 to indicate not set
+		MaxRetries:        -1, // Use -1 to indicate not set
+		StartFrom:         -1, // Use -1 to indicate not set
+		MaxAgentCalls:     -1, // Use -1 to indicate not set
+		MaxCost:           -1, // Use -1 to indicate not set
+		HistoryMaxEntries: -1, // Use -1 to indicate not set
 	}
 
 	// Project directory
@@ -48,7 +74,7 @@ func LoadFromEnv() *EnvConfig {
 
 	// Max retries
 	if val := os.Getenv("SLEEPSHIP_SYNC_MAX_RETRIES"); val != "" {
-		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+		if n, err := parseCount(val, 0); err == nil {
 			cfg.MaxRetries = n
 		}
 	}
@@ -60,7 +86,7 @@ func LoadFromEnv() *EnvConfig {
 
 	// Start from
 	if val := os.Getenv("SLEEPSHIP_SYNC_START_FROM"); val != "" {
-		if n, err := strconv.Atoi(val); err == nil && n >= 1 {
+		if n, err := parseCount(val, 1); err == nil {
 			cfg.StartFrom = n
 		}
 	}
@@ -74,6 +100,77 @@ func LoadFromEnv() *EnvConfig {
 		cfg.ClaudeFlags = flags
 	}
 
+	// Commit strategy
+	if val := os.Getenv("SLEEPSHIP_SYNC_COMMIT_STRATEGY"); val != "" {
+		if IsValidCommitStrategy(val) {
+			cfg.CommitStrategy = val
+		}
+	}
+
+	// Rollback policy
+	if val := os.Getenv("SLEEPSHIP_SYNC_ROLLBACK"); val != "" {
+		if IsValidRollbackPolicy(val) {
+			cfg.RollbackPolicy = val
+		}
+	}
+
+	// Pre-commit checks (comma-separated)
+	if val := os.Getenv("SLEEPSHIP_SYNC_PRE_COMMIT_CHECKS"); val != "" {
+		var checks []string
+		for _, check := range strings.Split(val, ",") {
+			if check = strings.TrimSpace(check); check != "" {
+				checks = append(checks, check)
+			}
+		}
+		cfg.PreCommitChecks = checks
+	}
+
+	// Push mode
+	if val := os.Getenv("SLEEPSHIP_SYNC_PUSH"); val != "" {
+		if IsValidPushMode(val) {
+			cfg.Push = val
+		}
+	}
+
+	// Push remote
+	if val := os.Getenv("SLEEPSHIP_SYNC_PUSH_REMOTE"); val != "" {
+		cfg.PushRemote = val
+	}
+
+	// Branch base
+	if val := os.Getenv("SLEEPSHIP_SYNC_BRANCH_BASE"); val != "" {
+		cfg.BranchBase = val
+	}
+
+	// Run budgets
+	if val := os.Getenv("SLEEPSHIP_SYNC_MAX_DURATION"); val != "" {
+		if _, err := ParseAge(val); err == nil {
+			cfg.MaxDuration = val
+		}
+	}
+	if val := os.Getenv("SLEEPSHIP_SYNC_MAX_AGENT_CALLS"); val != "" {
+		if n, err := parseCount(val, 0); err == nil {
+			cfg.MaxAgentCalls = n
+		}
+	}
+	if val := os.Getenv("SLEEPSHIP_SYNC_MAX_COST"); val != "" {
+		if n, err := parseAmount(val); err == nil {
+			cfg.MaxCost = n
+		}
+	}
+
+	// History retention
+	if val := os.Getenv("SLEEPSHIP_HISTORY_MAX_ENTRIES"); val != "" {
+		if n, err := parseCount(val, 0); err == nil {
+			cfg.HistoryMaxEntries = n
+		}
+	}
+	if val := os.Getenv("SLEEPSHIP_HISTORY_MAX_AGE"); val != "" {
+		if _, err := ParseAge(val); err == nil {
+			cfg.HistoryMaxAge = val
+		}
+	}
+
 	return cfg
 }
 
@@ -106,3 +203,58 @@ func (c *EnvConfig) HasStartFrom() bool {
 func (c *EnvConfig) HasClaudeFlags() bool {
 	return len(c.ClaudeFlags) > 0
 }
+
+// HasCommitStrategy checks if CommitStrategy has been set via environment variable.
+func (c *EnvConfig) HasCommitStrategy() bool {
+	return c.CommitStrategy != ""
+}
+
+// HasRollbackPolicy checks if RollbackPolicy has been set via environment variable.
+func (c *EnvConfig) HasRollbackPolicy() bool {
+	return c.RollbackPolicy != ""
+}
+
+// HasPreCommitChecks checks if PreCommitChecks have been set via environment variable.
+func (c *EnvConfig) HasPreCommitChecks() bool {
+	return len(c.PreCommitChecks) > 0
+}
+
+// HasPush checks if Push has been set via environment variable.
+func (c *EnvConfig) HasPush() bool {
+	return c.Push != ""
+}
+
+// HasPushRemote checks if PushRemote has been set via environment variable.
+func (c *EnvConfig) HasPushRemote() bool {
+	return c.PushRemote != ""
+}
+
+// HasBranchBase checks if BranchBase has been set via environment variable.
+func (c *EnvConfig) HasBranchBase() bool {
+	return c.BranchBase != ""
+}
+
+// HasMaxDuration checks if MaxDuration has been set via environment variable.
+func (c *EnvConfig) HasMaxDuration() bool {
+	return c.MaxDuration != ""
+}
+
+// HasMaxAgentCalls checks if MaxAgentCalls has been set via environment variable.
+func (c *EnvConfig) HasMaxAgentCalls() bool {
+	return c.MaxAgentCalls >= 0
+}
+
+// HasMaxCost checks if MaxCost has been set via environment variable.
+func (c *EnvConfig) HasMaxCost() bool {
+	return c.MaxCost >= 0
+}
+
+// HasHistoryMaxEntries checks if HistoryMaxEntries has been set via environment variable.
+func (c *EnvConfig) HasHistoryMaxEntries() bool {
+	return c.HistoryMaxEntries >= 0
+}
+
+// HasHistoryMaxAge checks if HistoryMaxAge has been set via environment variable.
+func (c *EnvConfig) HasHistoryMaxAge() bool {
+	return c.HistoryMaxAge != ""
+}
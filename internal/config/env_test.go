@@ -386,3 +386,108 @@ func TestFromEnv(t *testing.T) {
 		t.Errorf("ClaudeFlags length = %v, want %v", len(cfg.ClaudeFlags), len(envCfg.ClaudeFlags))
 	}
 }
+
+func TestLoadFromEnvSyncPolicies(t *testing.T) {
+	t.Setenv("SLEEPSHIP_SYNC_COMMIT_STRATEGY", "squash")
+	t.Setenv("SLEEPSHIP_SYNC_ROLLBACK", "preserve")
+	t.Setenv("SLEEPSHIP_SYNC_PRE_COMMIT_CHECKS", "gofmt -l ., go vet ./...,")
+
+	cfg := LoadFromEnv()
+	if cfg.CommitStrategy != CommitSquash {
+		t.Errorf("CommitStrategy = %v, want %v", cfg.CommitStrategy, CommitSquash)
+	}
+	if cfg.RollbackPolicy != RollbackPreserve {
+		t.Errorf("RollbackPolicy = %v, want %v", cfg.RollbackPolicy, RollbackPreserve)
+	}
+	if len(cfg.PreCommitChecks) != 2 || cfg.PreCommitChecks[1] != "go vet ./..." {
+		t.Errorf("PreCommitChecks = %v, want [gofmt -l . go vet ./...]", cfg.PreCommitChecks)
+	}
+
+	// Invalid values are ignored
+	t.Setenv("SLEEPSHIP_SYNC_COMMIT_STRATEGY", "sometimes")
+	t.Setenv("SLEEPSHIP_SYNC_ROLLBACK", "undo")
+
+	cfg = LoadFromEnv()
+	if cfg.HasCommitStrategy() {
+		t.Errorf("CommitStrategy = %v, want unset", cfg.CommitStrategy)
+	}
+	if cfg.HasRollbackPolicy() {
+		t.Errorf("RollbackPolicy = %v, want unset", cfg.RollbackPolicy)
+	}
+}
+
+func TestMaxCost(t *testing.T) {
+	t.Setenv("SLEEPSHIP_SYNC_MAX_COST", "2.5")
+
+	env := LoadFromEnv()
+	if !env.HasMaxCost() || env.MaxCost != 2.5 {
+		t.Errorf("MaxCost = %v, want 2.5", env.MaxCost)
+	}
+
+	// The CLI overrides the environment, including with 0 (unlimited)
+	merged := MergeLayers(NewDefaultConfig(), &Config{MaxRetries: -1, StartFrom: -1, MaxCost: 0}, FromEnv(env))
+	if merged.MaxCost != 0 {
+		t.Errorf("merged MaxCost = %v, want 0", merged.MaxCost)
+	}
+	merged = MergeLayers(NewDefaultConfig(), &Config{MaxRetries: -1, StartFrom: -1, MaxCost: -1}, FromEnv(env))
+	if merged.MaxCost != 2.5 {
+		t.Errorf("merged MaxCost = %v, want 2.5", merged.MaxCost)
+	}
+
+	// Invalid values are ignored
+	t.Setenv("SLEEPSHIP_SYNC_MAX_COST", "-1")
+	if env := LoadFromEnv(); env.HasMaxCost() {
+		t.Errorf("MaxCost = %v, want unset", env.MaxCost)
+	}
+}
+
+func TestRunBudgets(t *testing.T) {
+	t.Setenv("SLEEPSHIP_SYNC_MAX_DURATION", "6h")
+	t.Setenv("SLEEPSHIP_SYNC_MAX_AGENT_CALLS", "40")
+
+	env := LoadFromEnv()
+	if !env.HasMaxDuration() || env.MaxDuration != "6h" {
+		t.Errorf("MaxDuration = %q, want 6h", env.MaxDuration)
+	}
+	if !env.HasMaxAgentCalls() || env.MaxAgentCalls != 40 {
+		t.Errorf("MaxAgentCalls = %d, want 40", env.MaxAgentCalls)
+	}
+
+	merged := MergeLayers(NewDefaultConfig(), &Config{MaxRetries: -1, StartFrom: -1, MaxAgentCalls: 0, MaxCost: -1}, FromEnv(env))
+	if merged.MaxDuration != "6h" || merged.MaxAgentCalls != 0 {
+		t.Errorf("merged budgets = %q, %d, want 6h, 0", merged.MaxDuration, merged.MaxAgentCalls)
+	}
+
+	// Invalid values are ignored
+	t.Setenv("SLEEPSHIP_SYNC_MAX_DURATION", "soon")
+	t.Setenv("SLEEPSHIP_SYNC_MAX_AGENT_CALLS", "-3")
+	env = LoadFromEnv()
+	if env.HasMaxDuration() || env.HasMaxAgentCalls() {
+		t.Errorf("budgets = %q, %d, want unset", env.MaxDuration, env.MaxAgentCalls)
+	}
+	if merged := MergeLayers(NewDefaultConfig(), FromEnv(env)); merged.MaxDuration != "0" || merged.MaxAgentCalls != 0 {
+		t.Errorf("default budgets = %q, %d, want 0, 0", merged.MaxDuration, merged.MaxAgentCalls)
+	}
+}
+
+func TestMergeLayersDefaults(t *testing.T) {
+	unset := &Config{MaxRetries: -1, StartFrom: -1, MaxAgentCalls: -1, MaxCost: -1, HistoryMaxEntries: -1}
+
+	// A copy of the defaults is still the fallback for unset values
+	defaults := *NewDefaultConfig()
+	merged := MergeLayers(&defaults, unset, unset, unset)
+	if merged.MaxRetries != 3 || merged.StartFrom != 1 || merged.MaxAgentCalls != 0 || merged.HistoryMaxEntries != 0 {
+		t.Errorf("merged = %d, %d, %d, %d, want 3, 1, 0, 0", merged.MaxRetries, merged.StartFrom, merged.MaxAgentCalls, merged.HistoryMaxEntries)
+	}
+
+	// The values of the defaults are used as they are, even -1
+	merged = MergeLayers(unset, unset)
+	if merged.MaxRetries != -1 || merged.StartFrom != -1 {
+		t.Errorf("merged = %d, %d, want -1, -1", merged.MaxRetries, merged.StartFrom)
+	}
+
+	// Without layers the defaults are used as they are
+	if merged := MergeLayers(NewDefaultConfig()); merged.MaxRetries != 3 || merged.LogDir != "logs" {
+		t.Errorf("merged = %d, %q, want 3, logs", merged.MaxRetries, merged.LogDir)
+	}
+}
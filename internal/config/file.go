@@ -0,0 +1,352 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ConfigFileName is the name of the sleepship configuration file.
+const ConfigFileName = ".sleepship.toml"
+
+// templateDir is the directory of user task file templates, relative to a
+// project directory or the home directory
+var templateDir = filepath.Join(".sleepship", "templates")
+
+// ConfigEnvVar is the environment variable that selects the config file to
+// use. The --config flag sets it so that child processes use the same file.
+const ConfigEnvVar = "SLEEPSHIP_CONFIG"
+
+// FileConfig represents the settings sections of .sleepship.toml
+type FileConfig struct {
+	Sync    SyncFileConfig    `toml:"sync"`
+	Claude  ClaudeFileConfig  `toml:"claude"`
+	History HistoryFileConfig `toml:"history"`
+}
+
+// SyncFileConfig represents the [sync] section of .sleepship.toml
+type SyncFileConfig struct {
+	DefaultTaskFile string   `toml:"default_task_file"`
+	MaxRetries      *int     `toml:"max_retries"`
+	LogDir          string   `toml:"log_dir"`
+	CommitStrategy  string   `toml:"commit_strategy"`
+	Rollback        string   `toml:"rollback"`
+	PreCommitChecks []string `toml:"pre_commit_checks"`
+	Push            string   `toml:"push"`
+	PushRemote      string   `toml:"push_remote"`
+	BranchBase      string   `toml:"branch_base"`
+	MaxDuration     string   `toml:"max_duration"`
+	MaxAgentCalls   *int     `toml:"max_agent_calls"`
+	MaxCost         *float64 `toml:"max_cost"`
+}
+
+// ClaudeFileConfig represents the [claude] section of .sleepship.toml
+type ClaudeFileConfig struct {
+	Flags []string `toml:"flags"`
+}
+
+// HistoryFileConfig represents the [history] section of .sleepship.toml
+type HistoryFileConfig struct {
+	MaxEntries *int   `toml:"max_entries"`
+	MaxAge     string `toml:"max_age"`
+}
+
+// FindConfigPath returns the path of the .sleepship.toml to use:
+//  1. the file selected with SLEEPSHIP_CONFIG or --config
+//  2. the nearest config file from the current directory up to the
+//     repository root, or up to the filesystem root outside a repository
+//  3. ~/.sleepship.toml
+//
+// It returns an empty string if no config file is found.
+func FindConfigPath() string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = ""
+	}
+	return findConfigPathFrom(cwd)
+}
+
+// findConfigPathFrom is FindConfigPath searching from dir instead of the
+// current directory. An empty dir only checks the explicit and home config
+// files.
+func findConfigPathFrom(dir string) string {
+	if explicit := ExplicitConfigPath(); explicit != "" {
+		return explicit
+	}
+
+	// Check the directory and its parents
+	if dir != "" {
+		if configPath := FindProjectConfigPath(dir); configPath != "" {
+			return configPath
+		}
+	}
+
+	// Check home directory
+	if configPath, err := GlobalConfigPath(); err == nil {
+		if _, err := os.Stat(configPath); err == nil {
+			return configPath
+		}
+	}
+
+	return ""
+}
+
+// FindProjectConfigPath returns the nearest .sleepship.toml from dir up to
+// the repository root, or up to the filesystem root if dir is not in a
+// repository. It returns an empty string if there is none.
+func FindProjectConfigPath(dir string) string {
+	for _, dir := range configSearchDirs(dir) {
+		configPath := filepath.Join(dir, ConfigFileName)
+		if _, err := os.Stat(configPath); err == nil {
+			return configPath
+		}
+	}
+	return ""
+}
+
+// ConfigPaths returns the existing config files that aliases and contexts
+// are loaded from, lowest priority first: ~/.sleepship.toml, then the config
+// files from the repository root (or the filesystem root outside a
+// repository) down to the current directory. If a config file is selected
+// with SLEEPSHIP_CONFIG or --config, it takes the place of the files found
+// from the current directory.
+func ConfigPaths() []string {
+	var paths []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if seen[path] {
+			return
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			paths = append(paths, path)
+			seen[path] = true
+		}
+	}
+
+	if globalPath, err := GlobalConfigPath(); err == nil {
+		add(globalPath)
+	}
+
+	// An explicit config file is included even if it is missing, so that
+	// loading it reports the error
+	if explicit := ExplicitConfigPath(); explicit != "" {
+		if !seen[explicit] {
+			paths = append(paths, explicit)
+		}
+		return paths
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return paths
+	}
+	dirs := configSearchDirs(cwd)
+	for i := len(dirs) - 1; i >= 0; i-- {
+		add(filepath.Join(dirs[i], ConfigFileName))
+	}
+	return paths
+}
+
+// TemplateDirs returns the directories task file templates are loaded from,
+// highest priority first: .sleepship/templates in the current directory and
+// its parents up to the repository root, then ~/.sleepship/templates. The
+// directories need not exist.
+func TemplateDirs() []string {
+	var dirs []string
+	add := func(dir string) {
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		for _, dir := range configSearchDirs(cwd) {
+			add(filepath.Join(dir, templateDir))
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		add(filepath.Join(home, templateDir))
+	}
+	return dirs
+}
+
+// ExplicitConfigPath returns the absolute path of the config file selected
+// with SLEEPSHIP_CONFIG or --config, or an empty string if none is selected.
+func ExplicitConfigPath() string {
+	path := os.Getenv(ConfigEnvVar)
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// FindRepoRoot returns the root of the git repository containing dir, or an
+// empty string if dir is not in a repository.
+func FindRepoRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// configSearchDirs returns the directories searched for config files, from
+// dir up to the repository root, or up to the filesystem root if dir is not
+// in a repository.
+func configSearchDirs(dir string) []string {
+	root := FindRepoRoot(dir)
+	dirs := []string{dir}
+	for dir != root {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// GlobalConfigPath returns the path of the per-user .sleepship.toml in the
+// home directory, whether or not it exists.
+func GlobalConfigPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ConfigFileName), nil
+}
+
+// UserConfigDir returns the directory for sleepship's per-user state, such
+// as the global history index.
+func UserConfigDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user config directory: %w", err)
+	}
+	return filepath.Join(dir, "sleepship"), nil
+}
+
+// LoadFileConfig loads the settings sections from .sleepship.toml.
+// It returns an empty FileConfig if no config file is found.
+func LoadFileConfig() (*FileConfig, error) {
+	return LoadFileConfigIn("")
+}
+
+// LoadFileConfigIn loads the settings sections from the .sleepship.toml that
+// applies to the project in dir, searched as FindConfigPath does but from dir.
+// An empty dir means the current directory.
+func LoadFileConfigIn(dir string) (*FileConfig, error) {
+	if dir == "" {
+		dir = "."
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project directory: %w", err)
+	}
+
+	configPath := findConfigPathFrom(absDir)
+	if configPath == "" {
+		return &FileConfig{}, nil
+	}
+	return LoadFileConfigFrom(configPath)
+}
+
+// LoadFileConfigFrom loads the settings sections from the given config file.
+func LoadFileConfigFrom(configPath string) (*FileConfig, error) {
+	var cfg FileConfig
+	if _, err := toml.DecodeFile(configPath, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	if problems := fileConfigProblems(configPath, &cfg); len(problems) > 0 {
+		return nil, problems[0]
+	}
+
+	return &cfg, nil
+}
+
+// FromFile creates a Config from FileConfig
+func FromFile(file *FileConfig) *Config {
+	cfg := &Config{
+		DefaultTaskFile: file.Sync.DefaultTaskFile,
+		MaxRetries:      -1,
+		LogDir:          file.Sync.LogDir,
+		StartFrom:       -1,
+		ClaudeFlags:     file.Claude.Flags,
+		CommitStrategy:  file.Sync.CommitStrategy,
+		RollbackPolicy:  file.Sync.Rollback,
+		PreCommitChecks: file.Sync.PreCommitChecks,
+		Push:            file.Sync.Push,
+		PushRemote:      file.Sync.PushRemote,
+		BranchBase:      file.Sync.BranchBase,
+		MaxDuration:     file.Sync.MaxDuration,
+		MaxAgentCalls:   -1,
+		MaxCost:         -1,
+
+		HistoryMaxEntries: -1,
+		HistoryMaxAge:     file.History.MaxAge,
+	}
+
+	if file.Sync.MaxRetries != nil {
+		cfg.MaxRetries = *file.Sync.MaxRetries
+	}
+	if file.Sync.MaxAgentCalls != nil {
+		cfg.MaxAgentCalls = *file.Sync.MaxAgentCalls
+	}
+	if file.Sync.MaxCost != nil {
+		cfg.MaxCost = *file.Sync.MaxCost
+	}
+	if file.History.MaxEntries != nil {
+		cfg.HistoryMaxEntries = *file.History.MaxEntries
+	}
+
+	return cfg
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// over path, so that an interrupted write never leaves a truncated config
+// file. The permissions of an existing file are kept, and a symlinked file
+// is written through the link.
+func writeFileAtomic(path string, data []byte) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
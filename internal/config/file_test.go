@@ -0,0 +1,362 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFileConfigFrom(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	t.Run("sync and claude sections", func(t *testing.T) {
+		configContent := `[aliases]
+dev = "sync tasks-dev.txt"
+
+[sync]
+default_task_file = "tasks.txt"
+max_retries = 0
+log_dir = "sync-logs"
+commit_strategy = "squash"
+
+[claude]
+flags = ["--verbose"]
+`
+		configPath := filepath.Join(tmpDir, "valid.toml")
+		if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		fileConfig, err := LoadFileConfigFrom(configPath)
+		if err != nil {
+			t.Fatalf("LoadFileConfigFrom() error = %v", err)
+		}
+
+		cfg := FromFile(fileConfig)
+		if cfg.DefaultTaskFile != "tasks.txt" {
+			t.Errorf("DefaultTaskFile = %v, want tasks.txt", cfg.DefaultTaskFile)
+		}
+		if cfg.MaxRetries != 0 {
+			t.Errorf("MaxRetries = %v, want 0", cfg.MaxRetries)
+		}
+		if cfg.LogDir != "sync-logs" {
+			t.Errorf("LogDir = %v, want sync-logs", cfg.LogDir)
+		}
+		if cfg.CommitStrategy != CommitSquash {
+			t.Errorf("CommitStrategy = %v, want %v", cfg.CommitStrategy, CommitSquash)
+		}
+		if len(cfg.ClaudeFlags) != 1 || cfg.ClaudeFlags[0] != "--verbose" {
+			t.Errorf("ClaudeFlags = %v, want [--verbose]", cfg.ClaudeFlags)
+		}
+	})
+
+	t.Run("unset max_retries is not applied", func(t *testing.T) {
+		configPath := filepath.Join(tmpDir, "empty.toml")
+		if err := os.WriteFile(configPath, []byte("[sync]\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		fileConfig, err := LoadFileConfigFrom(configPath)
+		if err != nil {
+			t.Fatalf("LoadFileConfigFrom() error = %v", err)
+		}
+
+		merged := MergeLayers(NewDefaultConfig(), &Config{MaxRetries: -1, StartFrom: -1}, FromFile(fileConfig))
+		if merged.MaxRetries != 3 {
+			t.Errorf("MaxRetries = %v, want 3", merged.MaxRetries)
+		}
+		if merged.CommitStrategy != CommitPerTask {
+			t.Errorf("CommitStrategy = %v, want %v", merged.CommitStrategy, CommitPerTask)
+		}
+	})
+
+	t.Run("max cost", func(t *testing.T) {
+		configPath := filepath.Join(tmpDir, "cost.toml")
+		if err := os.WriteFile(configPath, []byte("[sync]\nmax_cost = 5\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		fileConfig, err := LoadFileConfigFrom(configPath)
+		if err != nil {
+			t.Fatalf("LoadFileConfigFrom() error = %v", err)
+		}
+		if cfg := FromFile(fileConfig); cfg.MaxCost != 5 {
+			t.Errorf("MaxCost = %v, want 5", cfg.MaxCost)
+		}
+
+		invalidPath := filepath.Join(tmpDir, "invalid-cost.toml")
+		if err := os.WriteFile(invalidPath, []byte("[sync]\nmax_cost = -1.0\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadFileConfigFrom(invalidPath); err == nil {
+			t.Error("LoadFileConfigFrom() expected error for negative max_cost, got nil")
+		}
+	})
+
+	t.Run("run budgets", func(t *testing.T) {
+		configPath := filepath.Join(tmpDir, "budgets.toml")
+		if err := os.WriteFile(configPath, []byte("[sync]\nmax_duration = \"8h\"\nmax_agent_calls = 60\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		fileConfig, err := LoadFileConfigFrom(configPath)
+		if err != nil {
+			t.Fatalf("LoadFileConfigFrom() error = %v", err)
+		}
+		if cfg := FromFile(fileConfig); cfg.MaxDuration != "8h" || cfg.MaxAgentCalls != 60 {
+			t.Errorf("budgets = %q, %d, want 8h, 60", cfg.MaxDuration, cfg.MaxAgentCalls)
+		}
+
+		for name, content := range map[string]string{
+			"invalid-duration.toml": "[sync]\nmax_duration = \"tonight\"\n",
+			"invalid-calls.toml":    "[sync]\nmax_agent_calls = -1\n",
+		} {
+			invalidPath := filepath.Join(tmpDir, name)
+			if err := os.WriteFile(invalidPath, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadFileConfigFrom(invalidPath); err == nil {
+				t.Errorf("LoadFileConfigFrom(%s) expected error, got nil", name)
+			}
+		}
+	})
+
+	t.Run("invalid commit strategy", func(t *testing.T) {
+		configPath := filepath.Join(tmpDir, "invalid.toml")
+		if err := os.WriteFile(configPath, []byte("[sync]\ncommit_strategy = \"sometimes\"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadFileConfigFrom(configPath); err == nil {
+			t.Error("LoadFileConfigFrom() expected error for invalid commit_strategy, got nil")
+		}
+	})
+}
+
+func TestLoadFileConfigHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	configPath := filepath.Join(tmpDir, "history.toml")
+	if err := os.WriteFile(configPath, []byte("[history]\nmax_entries = 100\nmax_age = \"30d\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fileConfig, err := LoadFileConfigFrom(configPath)
+	if err != nil {
+		t.Fatalf("LoadFileConfigFrom() error = %v", err)
+	}
+
+	merged := MergeLayers(NewDefaultConfig(), &Config{MaxRetries: -1, StartFrom: -1, HistoryMaxEntries: -1}, FromFile(fileConfig))
+	if merged.HistoryMaxEntries != 100 {
+		t.Errorf("HistoryMaxEntries = %v, want 100", merged.HistoryMaxEntries)
+	}
+	if merged.HistoryMaxAge != "30d" {
+		t.Errorf("HistoryMaxAge = %v, want 30d", merged.HistoryMaxAge)
+	}
+
+	invalidPath := filepath.Join(tmpDir, "invalid-history.toml")
+	if err := os.WriteFile(invalidPath, []byte("[history]\nmax_age = \"soon\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFileConfigFrom(invalidPath); err == nil {
+		t.Error("LoadFileConfigFrom() expected error for invalid max_age, got nil")
+	}
+}
+
+func TestFindConfigPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(ConfigEnvVar, "")
+
+	// repo/.git, repo/.sleepship.toml and an empty repo/sub/dir
+	repo := t.TempDir()
+	workDir := filepath.Join(repo, "sub", "dir")
+	for _, dir := range []string{filepath.Join(repo, ".git"), workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(oldDir) }()
+	_ = os.Chdir(workDir)
+	cwd, _ := os.Getwd()
+	repoDir := filepath.Dir(filepath.Dir(cwd))
+
+	if got := FindConfigPath(); got != "" {
+		t.Errorf("FindConfigPath() without config files = %q, want empty", got)
+	}
+
+	homeConfig := filepath.Join(home, ConfigFileName)
+	if err := os.WriteFile(homeConfig, []byte("[sync]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindConfigPath(); got != homeConfig {
+		t.Errorf("FindConfigPath() = %q, want %q", got, homeConfig)
+	}
+
+	// The nearest config file up to the repository root is used
+	repoConfig := filepath.Join(repoDir, ConfigFileName)
+	if err := os.WriteFile(repoConfig, []byte("[sync]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindConfigPath(); got != repoConfig {
+		t.Errorf("FindConfigPath() from a subdirectory = %q, want %q", got, repoConfig)
+	}
+	localConfig := filepath.Join(cwd, ConfigFileName)
+	if err := os.WriteFile(localConfig, []byte("[sync]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindConfigPath(); got != localConfig {
+		t.Errorf("FindConfigPath() = %q, want %q", got, localConfig)
+	}
+
+	// SLEEPSHIP_CONFIG wins, and relative paths are made absolute
+	t.Setenv(ConfigEnvVar, filepath.Join("..", "custom.toml"))
+	want := filepath.Join(filepath.Dir(cwd), "custom.toml")
+	if got := FindConfigPath(); got != want {
+		t.Errorf("FindConfigPath() with %s = %q, want %q", ConfigEnvVar, got, want)
+	}
+	if _, err := LoadFileConfig(); err == nil {
+		t.Error("LoadFileConfig() expected error for a missing explicit config file")
+	}
+}
+
+func TestLoadFileConfigIn(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(ConfigEnvVar, "")
+
+	// The current directory and the project have their own config files
+	workDir := t.TempDir()
+	project := t.TempDir()
+	for dir, taskFile := range map[string]string{workDir: "here.txt", project: "project.txt"} {
+		content := "[sync]\ndefault_task_file = \"" + taskFile + "\"\n"
+		if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(oldDir) }()
+	_ = os.Chdir(workDir)
+
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{dir: "", want: "here.txt"},
+		{dir: project, want: "project.txt"},
+		{dir: filepath.Join(project, "sub"), want: "project.txt"},
+	}
+	for _, tt := range tests {
+		cfg, err := LoadFileConfigIn(tt.dir)
+		if err != nil {
+			t.Fatalf("LoadFileConfigIn(%q) error = %v", tt.dir, err)
+		}
+		if cfg.Sync.DefaultTaskFile != tt.want {
+			t.Errorf("LoadFileConfigIn(%q) default task file = %q, want %q", tt.dir, cfg.Sync.DefaultTaskFile, tt.want)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "0", want: 0},
+		{input: "30d", want: 30 * 24 * time.Hour},
+		{input: "2w", want: 14 * 24 * time.Hour},
+		{input: "12h", want: 12 * time.Hour},
+		{input: "1h30m", want: 90 * time.Minute},
+		{input: "", wantErr: true},
+		{input: "d", wantErr: true},
+		{input: "1.5d", wantErr: true},
+		{input: "-1h", wantErr: true},
+		{input: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAge(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAge(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAge(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestMergeConfigFileLayer(t *testing.T) {
+	cliConfig := &Config{MaxRetries: -1, StartFrom: -1}
+	envConfig := &Config{MaxRetries: -1, StartFrom: -1, CommitStrategy: CommitNone}
+	fileConfig := &Config{MaxRetries: 8, StartFrom: -1, LogDir: "file-logs", CommitStrategy: CommitSquash}
+
+	merged := MergeLayers(NewDefaultConfig(), cliConfig, envConfig, fileConfig)
+
+	if merged.MaxRetries != 8 {
+		t.Errorf("MaxRetries = %v, want 8", merged.MaxRetries)
+	}
+	if merged.LogDir != "file-logs" {
+		t.Errorf("LogDir = %v, want file-logs", merged.LogDir)
+	}
+	if merged.StartFrom != 1 {
+		t.Errorf("StartFrom = %v, want 1", merged.StartFrom)
+	}
+	if merged.CommitStrategy != CommitNone {
+		t.Errorf("CommitStrategy = %v, want %v", merged.CommitStrategy, CommitNone)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("keeps permissions", func(t *testing.T) {
+		path := filepath.Join(dir, "private.toml")
+		if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeFileAtomic(path, []byte("new")); err != nil {
+			t.Fatalf("writeFileAtomic() error = %v", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("permissions = %v, want 0600", info.Mode().Perm())
+		}
+	})
+
+	t.Run("writes through a symlink", func(t *testing.T) {
+		target := filepath.Join(dir, "dotfiles.toml")
+		link := filepath.Join(dir, "linked.toml")
+		if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+		if err := writeFileAtomic(link, []byte("new")); err != nil {
+			t.Fatalf("writeFileAtomic() error = %v", err)
+		}
+		if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("link was replaced: %v, %v", info, err)
+		}
+		if data, _ := os.ReadFile(target); string(data) != "new" {
+			t.Errorf("target content = %q, want %q", data, "new")
+		}
+	})
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Errorf("temporary file left behind: %s", entry.Name())
+		}
+	}
+}
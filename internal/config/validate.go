@@ -0,0 +1,486 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Kinds of configuration problems. A ValidationError unwraps to one of them.
+var (
+	ErrUnknownKey   = errors.New("unknown key")
+	ErrTypeMismatch = errors.New("type mismatch")
+	ErrOutOfRange   = errors.New("out of range")
+	ErrInvalidValue = errors.New("invalid value")
+	ErrSyntax       = errors.New("invalid TOML")
+	ErrUnreadable   = errors.New("cannot read file")
+)
+
+// EnvSource is the Source of problems found in environment variables.
+const EnvSource = "environment"
+
+// StrictEnvVar is the environment variable that turns configuration
+// problems into errors instead of warnings.
+const StrictEnvVar = "SLEEPSHIP_STRICT_CONFIG"
+
+// ValidationError is a problem with one setting of a config file or
+// environment variable.
+type ValidationError struct {
+	Source     string // Config file path, or EnvSource
+	Key        string // Dotted TOML key or environment variable name, empty for problems with a whole file
+	Kind       error  // One of the Err* problem kinds above
+	Detail     string // What is wrong with the value
+	Suggestion string // Known key with a similar name, for unknown keys
+}
+
+func (e *ValidationError) Error() string {
+	msg := fmt.Sprintf("%s: %v", e.Source, e.Kind)
+	if e.Key != "" {
+		msg = fmt.Sprintf("%s: %s: %v", e.Source, e.Key, e.Kind)
+	}
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(" (did you mean %s?)", e.Suggestion)
+	}
+	return msg
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Kind
+}
+
+// IsWarning reports whether the problem is ignored outside strict mode.
+// Invalid environment variables and unknown keys are ignored; other problems
+// with config files stop the commands that load them.
+func (e *ValidationError) IsWarning() bool {
+	return e.Source == EnvSource || errors.Is(e.Kind, ErrUnknownKey)
+}
+
+// valueError is the problem with a single value, before it is attributed to
+// a key.
+type valueError struct {
+	kind   error
+	detail string
+}
+
+func (e *valueError) Error() string {
+	return fmt.Sprintf("%v: %s", e.kind, e.detail)
+}
+
+func (e *valueError) Unwrap() error {
+	return e.kind
+}
+
+// parseCount parses an integer setting that must be at least min.
+func parseCount(value string, min int) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, &valueError{ErrTypeMismatch, fmt.Sprintf("%q is not an integer", value)}
+	}
+	if err := checkMin(float64(n), float64(min)); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// parseAmount parses a number setting that must be 0 or greater.
+func parseAmount(value string) (float64, error) {
+	n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, &valueError{ErrTypeMismatch, fmt.Sprintf("%q is not a number", value)}
+	}
+	if err := checkMin(n, 0); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func checkMin(n, min float64) error {
+	if n < min {
+		return &valueError{ErrOutOfRange, fmt.Sprintf("%v is less than %v", n, min)}
+	}
+	return nil
+}
+
+func checkChoice(value string, choices []string) error {
+	if !slices.Contains(choices, value) {
+		return &valueError{ErrInvalidValue, fmt.Sprintf("%q is not one of %s", value, strings.Join(choices, ", "))}
+	}
+	return nil
+}
+
+func checkAge(value string) error {
+	if _, err := ParseAge(value); err != nil {
+		return &valueError{ErrInvalidValue, err.Error()}
+	}
+	return nil
+}
+
+// envChecks validates the environment variables read by LoadFromEnv. Variables
+// without a check accept any value.
+var envChecks = map[string]func(string) error{
+	"SLEEPSHIP_PROJECT_DIR":            nil,
+	"SLEEPSHIP_SYNC_DEFAULT_TASK_FILE": nil,
+	"SLEEPSHIP_SYNC_MAX_RETRIES":       func(v string) error { _, err := parseCount(v, 0); return err },
+	"SLEEPSHIP_SYNC_LOG_DIR":           nil,
+	"SLEEPSHIP_SYNC_START_FROM":        func(v string) error { _, err := parseCount(v, 1); return err },
+	"SLEEPSHIP_CLAUDE_FLAGS":           nil,
+	"SLEEPSHIP_SYNC_COMMIT_STRATEGY":   func(v string) error { return checkChoice(v, CommitStrategies) },
+	"SLEEPSHIP_SYNC_ROLLBACK":          func(v string) error { return checkChoice(v, RollbackPolicies) },
+	"SLEEPSHIP_SYNC_PRE_COMMIT_CHECKS": nil,
+	"SLEEPSHIP_SYNC_PUSH":              func(v string) error { return checkChoice(v, PushModes) },
+	"SLEEPSHIP_SYNC_PUSH_REMOTE":       nil,
+	"SLEEPSHIP_SYNC_BRANCH_BASE":       nil,
+	"SLEEPSHIP_SYNC_MAX_DURATION":      checkAge,
+	"SLEEPSHIP_SYNC_MAX_AGENT_CALLS":   func(v string) error { _, err := parseCount(v, 0); return err },
+	"SLEEPSHIP_SYNC_MAX_COST":          func(v string) error { _, err := parseAmount(v); return err },
+	"SLEEPSHIP_HISTORY_MAX_ENTRIES":    func(v string) error { _, err := parseCount(v, 0); return err },
+	"SLEEPSHIP_HISTORY_MAX_AGE":        checkAge,
+	ConfigEnvVar:                       nil,
+	ContextEnvVar:                      nil,
+	StrictEnvVar:                       func(v string) error { _, err := ParseStrict(v); return err },
+	"SLEEPSHIP_DEPTH":                  nil, // Set for recursive sleepship calls
+}
+
+// ParseStrict parses the value of SLEEPSHIP_STRICT_CONFIG.
+func ParseStrict(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	strict, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, &valueError{ErrTypeMismatch, fmt.Sprintf("%q is not a boolean", value)}
+	}
+	return strict, nil
+}
+
+// ValidateEnv returns the problems with SLEEPSHIP_* environment variables:
+// invalid values, which LoadFromEnv ignores, and unknown variables.
+func ValidateEnv() []*ValidationError {
+	known := make([]string, 0, len(envChecks))
+	for name := range envChecks {
+		known = append(known, name)
+	}
+
+	var problems []*ValidationError
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, "SLEEPSHIP_") {
+			continue
+		}
+		check, ok := envChecks[name]
+		switch {
+		case !ok:
+			problems = append(problems, &ValidationError{Source: EnvSource, Key: name, Kind: ErrUnknownKey, Suggestion: suggestKey(name, known)})
+		case check != nil && value != "":
+			if err := check(value); err != nil {
+				problems = append(problems, newValidationError(EnvSource, name, err))
+			}
+		}
+	}
+	sortProblems(problems)
+	return problems
+}
+
+// Validate checks the SLEEPSHIP_* environment variables and every config
+// file that applies to the current directory. It returns the files checked
+// and the problems found.
+func Validate() ([]string, []*ValidationError) {
+	var paths []string
+	if path := FindConfigPath(); path != "" {
+		paths = append(paths, path)
+	}
+	for _, path := range ConfigPaths() {
+		if !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+
+	problems := ValidateEnv()
+	for _, path := range paths {
+		problems = append(problems, ValidateFile(path)...)
+	}
+	return paths, problems
+}
+
+// ValidateFile returns the problems with a config file: syntax errors,
+// unknown keys, values of the wrong type and invalid values.
+func ValidateFile(path string) []*ValidationError {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []*ValidationError{{Source: path, Kind: ErrUnreadable, Detail: err.Error()}}
+	}
+	var doc map[string]toml.Primitive
+	md, err := toml.Decode(string(data), &doc)
+	if err != nil {
+		return []*ValidationError{{Source: path, Kind: ErrSyntax, Detail: err.Error()}}
+	}
+
+	var problems []*ValidationError
+	add := func(key string, err error) {
+		problems = append(problems, newValidationError(path, key, err))
+	}
+	unknown := func(table, key string, candidates []string) {
+		problem := &ValidationError{Source: path, Key: formatTOMLKey(key), Kind: ErrUnknownKey, Suggestion: suggestKey(key, candidates)}
+		if table != "" {
+			problem.Key = table + "." + problem.Key
+			if problem.Suggestion != "" {
+				problem.Suggestion = table + "." + problem.Suggestion
+			}
+		}
+		problems = append(problems, problem)
+	}
+
+	// decodeKeys decodes the keys of a table into the fields of a struct one
+	// by one, so that one bad value does not hide the problems with the others
+	decodeKeys := func(table string, keys map[string]toml.Primitive, dst reflect.Value) {
+		for _, key := range sortedKeys(keys) {
+			field, ok := fieldByTag(dst, key)
+			if !ok {
+				unknown(table, key, tomlKeys(dst.Type()))
+				continue
+			}
+			if err := md.PrimitiveDecode(keys[key], field.Addr().Interface()); err != nil {
+				field.SetZero()
+				add(table+"."+key, &valueError{ErrTypeMismatch, "expected " + describeType(field.Type())})
+			}
+		}
+	}
+
+	var cfg FileConfig
+	sections := reflect.ValueOf(&cfg).Elem()
+	tables := append(tomlKeys(sections.Type()), aliasTable, contextTable, workspaceTable)
+	for _, name := range sortedKeys(doc) {
+		var keys map[string]toml.Primitive
+		if err := decodeTable(md, doc[name], &keys); err != nil {
+			if slices.Contains(tables, name) {
+				add(name, &valueError{ErrTypeMismatch, "expected a table"})
+			} else {
+				unknown("", name, tables)
+			}
+			continue
+		}
+
+		switch name {
+		case aliasTable:
+			for _, alias := range sortedKeys(keys) {
+				var a Alias
+				if err := md.PrimitiveDecode(keys[alias], &a); err != nil {
+					add(name+"."+formatTOMLKey(alias), &valueError{ErrTypeMismatch, "expected a string or an array of strings"})
+				}
+			}
+		case contextTable:
+			for _, contextName := range sortedKeys(keys) {
+				table := name + "." + formatTOMLKey(contextName)
+				var settings map[string]toml.Primitive
+				if err := decodeTable(md, keys[contextName], &settings); err != nil {
+					add(table, &valueError{ErrTypeMismatch, "expected a table"})
+					continue
+				}
+				var ctx Context
+				decodeKeys(table, settings, reflect.ValueOf(&ctx).Elem())
+				problems = append(problems, contextProblems(path, contextName, &ctx)...)
+			}
+		case workspaceTable:
+			for _, key := range sortedKeys(keys) {
+				switch key {
+				case "parallel":
+					var parallel bool
+					if err := md.PrimitiveDecode(keys[key], &parallel); err != nil {
+						add(name+"."+key, &valueError{ErrTypeMismatch, "expected a boolean"})
+					}
+				case "members":
+					var members map[string]toml.Primitive
+					if err := decodeTable(md, keys[key], &members); err != nil {
+						add(name+"."+key, &valueError{ErrTypeMismatch, "expected a table"})
+						continue
+					}
+					for _, memberName := range sortedKeys(members) {
+						table := name + "." + key + "." + formatTOMLKey(memberName)
+						var settings map[string]toml.Primitive
+						if err := decodeTable(md, members[memberName], &settings); err != nil {
+							add(table, &valueError{ErrTypeMismatch, "expected a table"})
+							continue
+						}
+						var member Member
+						decodeKeys(table, settings, reflect.ValueOf(&member).Elem())
+						problems = append(problems, memberProblems(path, memberName, &member)...)
+					}
+				default:
+					unknown(name, key, []string{"parallel", "members"})
+				}
+			}
+		default:
+			section, ok := fieldByTag(sections, name)
+			if !ok {
+				unknown("", name, tables)
+				continue
+			}
+			decodeKeys(name, keys, section)
+		}
+	}
+
+	problems = append(problems, fileConfigProblems(path, &cfg)...)
+	sortProblems(problems)
+	return problems
+}
+
+// fileConfigProblems checks the values of the settings in a config file.
+func fileConfigProblems(path string, cfg *FileConfig) []*ValidationError {
+	var problems []*ValidationError
+	check := func(key string, err error) {
+		if err != nil {
+			problems = append(problems, newValidationError(path, key, err))
+		}
+	}
+
+	if cfg.Sync.MaxRetries != nil {
+		check("sync.max_retries", checkMin(float64(*cfg.Sync.MaxRetries), 0))
+	}
+	if cfg.Sync.CommitStrategy != "" {
+		check("sync.commit_strategy", checkChoice(cfg.Sync.CommitStrategy, CommitStrategies))
+	}
+	if cfg.Sync.Rollback != "" {
+		check("sync.rollback", checkChoice(cfg.Sync.Rollback, RollbackPolicies))
+	}
+	if cfg.Sync.Push != "" {
+		check("sync.push", checkChoice(cfg.Sync.Push, PushModes))
+	}
+	if cfg.Sync.MaxDuration != "" {
+		check("sync.max_duration", checkAge(cfg.Sync.MaxDuration))
+	}
+	if cfg.Sync.MaxAgentCalls != nil {
+		check("sync.max_agent_calls", checkMin(float64(*cfg.Sync.MaxAgentCalls), 0))
+	}
+	if cfg.Sync.MaxCost != nil {
+		check("sync.max_cost", checkMin(*cfg.Sync.MaxCost, 0))
+	}
+	if cfg.History.MaxEntries != nil {
+		check("history.max_entries", checkMin(float64(*cfg.History.MaxEntries), 0))
+	}
+	if cfg.History.MaxAge != "" {
+		check("history.max_age", checkAge(cfg.History.MaxAge))
+	}
+	return problems
+}
+
+func newValidationError(source, key string, err error) *ValidationError {
+	problem := &ValidationError{Source: source, Key: key, Kind: ErrInvalidValue, Detail: err.Error()}
+	var valueErr *valueError
+	if errors.As(err, &valueErr) {
+		problem.Kind, problem.Detail = valueErr.kind, valueErr.detail
+	}
+	return problem
+}
+
+// decodeTable decodes the keys of a table. PrimitiveDecode does not reject
+// values that are not tables, so the type is checked first.
+func decodeTable(md toml.MetaData, value toml.Primitive, keys *map[string]toml.Primitive) error {
+	var v any
+	if err := md.PrimitiveDecode(value, &v); err != nil {
+		return err
+	}
+	if _, ok := v.(map[string]any); !ok {
+		return fmt.Errorf("not a table")
+	}
+	return md.PrimitiveDecode(value, keys)
+}
+
+// fieldByTag returns the field of a struct with the given toml tag.
+func fieldByTag(v reflect.Value, tag string) (reflect.Value, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Tag.Get("toml") == tag {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// tomlKeys returns the toml tags of the fields of a struct type.
+func tomlKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		if tag := t.Field(i).Tag.Get("toml"); tag != "" {
+			keys = append(keys, tag)
+		}
+	}
+	return keys
+}
+
+// describeType describes the TOML values a setting of type t accepts.
+func describeType(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Int:
+		return "an integer"
+	case reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice:
+		return "an array of " + strings.TrimPrefix(strings.TrimPrefix(describeType(t.Elem()), "a "), "an ") + "s"
+	default:
+		return t.String()
+	}
+}
+
+// suggestKey returns the candidate closest to an unknown key, or an empty
+// string if none is close enough to be a likely typo.
+func suggestKey(key string, candidates []string) string {
+	best, bestDistance := "", max(2, len(key)/3)+1
+	for _, candidate := range candidates {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(candidate)); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortProblems(problems []*ValidationError) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Key < problems[j].Key
+	})
+}
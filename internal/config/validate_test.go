@@ -0,0 +1,144 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]error // Problem kinds by key
+		suggest map[string]string
+	}{
+		{
+			name:    "valid",
+			content: "[sync]\nmax_retries = 5\nmax_cost = 2\npre_commit_checks = [\"go vet ./...\"]\n\n[aliases]\ndev = \"sync\"\nci = [\"sync\", \"history\"]\n",
+			want:    map[string]error{},
+		},
+		{
+			name:    "unknown keys",
+			content: "[sync]\nmax_retry = 5\n\n[histroy]\nmax_age = \"30d\"\n\n[claude]\nflags = []\ncolor = true\n",
+			want:    map[string]error{"sync.max_retry": ErrUnknownKey, "histroy": ErrUnknownKey, "claude.color": ErrUnknownKey},
+			suggest: map[string]string{"sync.max_retry": "sync.max_retries", "histroy": "history", "claude.color": ""},
+		},
+		{
+			name:    "type mismatches",
+			content: "sync = 3\n\n[history]\nmax_entries = \"100\"\nmax_age = 30\n\n[aliases]\ndev = 1\n",
+			want:    map[string]error{"sync": ErrTypeMismatch, "history.max_entries": ErrTypeMismatch, "history.max_age": ErrTypeMismatch, "aliases.dev": ErrTypeMismatch},
+		},
+		{
+			name:    "invalid values",
+			content: "[sync]\nmax_retries = -1\nmax_cost = -0.5\ncommit_strategy = \"later\"\nmax_duration = \"soon\"\n",
+			want:    map[string]error{"sync.max_retries": ErrOutOfRange, "sync.max_cost": ErrOutOfRange, "sync.commit_strategy": ErrInvalidValue, "sync.max_duration": ErrInvalidValue},
+		},
+		{
+			name:    "contexts",
+			content: "[contexts.api]\nproject_dir = \"~/src/api\"\nmax_retry = 3\nrollback = \"later\"\n\n[contexts]\nweb = 3\n",
+			want:    map[string]error{"contexts.api.max_retry": ErrUnknownKey, "contexts.api.rollback": ErrInvalidValue, "contexts.web": ErrTypeMismatch},
+			suggest: map[string]string{"contexts.api.max_retry": "contexts.api.max_retries"},
+		},
+		{
+			name:    "workspace",
+			content: "[workspace]\nparallel = \"yes\"\nmember = []\n\n[workspace.members.api]\ntask_file = \"tasks.txt\"\nmax_retries = -1\n\n[workspace.members.web]\npath = \"web\"\nbrnch_base = \"main\"\n",
+			want: map[string]error{
+				"workspace.parallel":                ErrTypeMismatch,
+				"workspace.member":                  ErrUnknownKey,
+				"workspace.members.api.path":        ErrInvalidValue,
+				"workspace.members.api.max_retries": ErrOutOfRange,
+				"workspace.members.web.brnch_base":  ErrUnknownKey,
+			},
+			suggest: map[string]string{"workspace.member": "workspace.members", "workspace.members.web.brnch_base": "workspace.members.web.branch_base"},
+		},
+		{
+			name:    "syntax error",
+			content: "[sync\n",
+			want:    map[string]error{"": ErrSyntax},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ConfigFileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			problems := ValidateFile(path)
+			if len(problems) != len(tt.want) {
+				t.Errorf("ValidateFile() = %v, want %d problems", problems, len(tt.want))
+			}
+			for _, problem := range problems {
+				kind, ok := tt.want[problem.Key]
+				if !ok || !errors.Is(problem, kind) {
+					t.Errorf("unexpected problem %v", problem)
+				}
+				if want, ok := tt.suggest[problem.Key]; ok && problem.Suggestion != want {
+					t.Errorf("suggestion for %s = %q, want %q", problem.Key, problem.Suggestion, want)
+				}
+			}
+		})
+	}
+
+	if problems := ValidateFile(filepath.Join(t.TempDir(), "missing.toml")); len(problems) != 1 || !errors.Is(problems[0], ErrUnreadable) {
+		t.Errorf("ValidateFile() of a missing file = %v", problems)
+	}
+}
+
+func TestValidateEnv(t *testing.T) {
+	t.Setenv("SLEEPSHIP_SYNC_MAX_RETRIES", "abc")
+	t.Setenv("SLEEPSHIP_SYNC_START_FROM", "0")
+	t.Setenv("SLEEPSHIP_SYNC_PUSH", "always")
+	t.Setenv("SLEEPSHIP_SYNC_MAX_COST", "1.5")
+	t.Setenv("SLEEPSHIP_SYNC_MAXRETRIES", "3")
+	t.Setenv("SLEEPSHIP_SOMETHING_ELSE", "1")
+
+	want := map[string]error{
+		"SLEEPSHIP_SYNC_MAX_RETRIES": ErrTypeMismatch,
+		"SLEEPSHIP_SYNC_START_FROM":  ErrOutOfRange,
+		"SLEEPSHIP_SYNC_PUSH":        ErrInvalidValue,
+		"SLEEPSHIP_SYNC_MAXRETRIES":  ErrUnknownKey,
+		"SLEEPSHIP_SOMETHING_ELSE":   ErrUnknownKey,
+	}
+	problems := ValidateEnv()
+	got := make(map[string]*ValidationError)
+	for _, problem := range problems {
+		// Ignore variables set outside the test
+		if _, ok := want[problem.Key]; ok {
+			got[problem.Key] = problem
+		}
+	}
+	for key, kind := range want {
+		if problem := got[key]; problem == nil || !errors.Is(problem, kind) || !problem.IsWarning() {
+			t.Errorf("problem for %s = %v, want %v", key, problem, kind)
+		}
+	}
+	if got := got["SLEEPSHIP_SYNC_MAXRETRIES"]; got != nil && got.Suggestion != "SLEEPSHIP_SYNC_MAX_RETRIES" {
+		t.Errorf("suggestion = %q, want SLEEPSHIP_SYNC_MAX_RETRIES", got.Suggestion)
+	}
+
+	// LoadFromEnv ignores the invalid values
+	env := LoadFromEnv()
+	if env.HasMaxRetries() || env.HasStartFrom() || env.Push != "" || env.MaxCost != 1.5 {
+		t.Errorf("LoadFromEnv() = %+v", env)
+	}
+}
+
+func TestSuggestKey(t *testing.T) {
+	candidates := []string{"max_retries", "max_cost", "log_dir"}
+	tests := map[string]string{
+		"max_retries": "max_retries",
+		"max_retires": "max_retries",
+		"MAX_COST":    "max_cost",
+		"logdir":      "log_dir",
+		"timeout":     "",
+	}
+	for key, want := range tests {
+		if got := suggestKey(key, candidates); got != want {
+			t.Errorf("suggestKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
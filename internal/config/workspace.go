@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+)
+
+// workspaceTable is the name of the table that defines a workspace
+const workspaceTable = "workspace"
+
+// Workspace is a set of member projects, such as the modules of a monorepo,
+// defined in the [workspace] table of a config file.
+type Workspace struct {
+	Parallel bool              // Run the members at the same time by default
+	Members  map[string]Member // Members by name
+	Source   string            // Config file the workspace is defined in
+}
+
+// Member is a project of a workspace, defined in a
+// [workspace.members.<name>] table. Its settings take priority over the
+// settings of the config files, like those of a context.
+type Member struct {
+	Path        string   `toml:"path"`
+	TaskFile    string   `toml:"task_file"`
+	ClaudeFlags []string `toml:"claude_flags"`
+	MaxRetries  *int     `toml:"max_retries"`
+	Rollback    string   `toml:"rollback"`
+	BranchBase  string   `toml:"branch_base"`
+}
+
+// workspaceConfig represents the workspace of a config file
+type workspaceConfig struct {
+	Workspace *struct {
+		Parallel bool              `toml:"parallel"`
+		Members  map[string]Member `toml:"members"`
+	} `toml:"workspace"`
+}
+
+// LoadWorkspace loads the workspace that applies to the current directory:
+// the one defined in the config file with the highest priority that has a
+// [workspace] table. Member paths are resolved against the directory of that
+// file, and task files against the member path. It returns nil if no
+// workspace is defined.
+func LoadWorkspace() (*Workspace, error) {
+	paths := ConfigPaths()
+	for i := len(paths) - 1; i >= 0; i-- {
+		path := paths[i]
+		var config workspaceConfig
+		if _, err := toml.DecodeFile(path, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if config.Workspace == nil {
+			continue
+		}
+
+		workspace := &Workspace{
+			Parallel: config.Workspace.Parallel,
+			Members:  make(map[string]Member, len(config.Workspace.Members)),
+			Source:   path,
+		}
+		for name, member := range config.Workspace.Members {
+			if problems := memberProblems(path, name, &member); len(problems) > 0 {
+				return nil, problems[0]
+			}
+			workspace.Members[name] = resolveMemberPaths(member, filepath.Dir(path))
+		}
+		return workspace, nil
+	}
+	return nil, nil
+}
+
+// resolveMemberPaths makes the paths of a member absolute.
+func resolveMemberPaths(member Member, dir string) Member {
+	if !filepath.IsAbs(member.Path) {
+		member.Path = filepath.Join(dir, member.Path)
+	}
+	if member.TaskFile != "" && !filepath.IsAbs(member.TaskFile) {
+		member.TaskFile = filepath.Join(member.Path, member.TaskFile)
+	}
+	return member
+}
+
+// memberProblems checks the values of the settings of a workspace member.
+func memberProblems(path, name string, member *Member) []*ValidationError {
+	var problems []*ValidationError
+	prefix := workspaceTable + ".members." + formatTOMLKey(name) + "."
+	if member.Path == "" {
+		problems = append(problems, newValidationError(path, prefix+"path", &valueError{ErrInvalidValue, "a member needs a path"}))
+	}
+	if member.MaxRetries != nil {
+		if err := checkMin(float64(*member.MaxRetries), 0); err != nil {
+			problems = append(problems, newValidationError(path, prefix+"max_retries", err))
+		}
+	}
+	if member.Rollback != "" {
+		if err := checkChoice(member.Rollback, RollbackPolicies); err != nil {
+			problems = append(problems, newValidationError(path, prefix+"rollback", err))
+		}
+	}
+	return problems
+}
+
+// MemberNames returns the names of the members of the workspace, sorted.
+func (w *Workspace) MemberNames() []string {
+	names := make([]string, 0, len(w.Members))
+	for name := range w.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FromMember creates a Config from a workspace member. The project
+// directory is the member path.
+func FromMember(member Member) *Config {
+	cfg := &Config{
+		ProjectDir:      member.Path,
+		DefaultTaskFile: member.TaskFile,
+		MaxRetries:      -1,
+		StartFrom:       -1,
+		ClaudeFlags:     member.ClaudeFlags,
+		RollbackPolicy:  member.Rollback,
+		BranchBase:      member.BranchBase,
+		MaxAgentCalls:   -1,
+		MaxCost:         -1,
+
+		HistoryMaxEntries: -1,
+	}
+	if member.MaxRetries != nil {
+		cfg.MaxRetries = *member.MaxRetries
+	}
+	return cfg
+}
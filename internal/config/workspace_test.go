@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadWorkspace(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv(ConfigEnvVar, "")
+
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	service := filepath.Join(root, "services", "api")
+	if err := os.MkdirAll(service, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	rootConfig := `[workspace]
+parallel = true
+
+[workspace.members.api]
+path = "services/api"
+task_file = "tasks-api.txt"
+max_retries = 5
+branch_base = "origin/main"
+
+[workspace.members.web]
+path = "/srv/web"
+`
+	files := map[string]string{
+		filepath.Join(home, ConfigFileName):    "[workspace.members.global]\npath = \"global\"\n",
+		filepath.Join(root, ConfigFileName):    rootConfig,
+		filepath.Join(service, ConfigFileName): "[sync]\nmax_retries = 2\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(oldDir) }()
+	_ = os.Chdir(service)
+
+	// The nearest file with a [workspace] table defines the workspace, even
+	// from inside a member
+	workspace, err := LoadWorkspace()
+	if err != nil {
+		t.Fatalf("LoadWorkspace() error = %v", err)
+	}
+	if workspace == nil {
+		t.Fatal("LoadWorkspace() = nil")
+	}
+	if !workspace.Parallel || workspace.Source != filepath.Join(root, ConfigFileName) {
+		t.Errorf("workspace = %+v", workspace)
+	}
+	if names := workspace.MemberNames(); !slices.Equal(names, []string{"api", "web"}) {
+		t.Errorf("MemberNames() = %v", names)
+	}
+	api := workspace.Members["api"]
+	if api.Path != service || api.TaskFile != filepath.Join(service, "tasks-api.txt") {
+		t.Errorf("api paths = %q, %q", api.Path, api.TaskFile)
+	}
+	if web := workspace.Members["web"]; web.Path != "/srv/web" || web.TaskFile != "" {
+		t.Errorf("web = %+v", web)
+	}
+
+	layer := FromMember(api)
+	if layer.ProjectDir != service || layer.MaxRetries != 5 || layer.BranchBase != "origin/main" || layer.StartFrom != -1 {
+		t.Errorf("FromMember() = %+v", layer)
+	}
+
+	// A member without a path is an error
+	if err := os.WriteFile(filepath.Join(service, ConfigFileName), []byte("[workspace.members.x]\ntask_file = \"t.txt\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadWorkspace(); err == nil {
+		t.Error("LoadWorkspace() with a member without a path succeeded")
+	}
+
+	// No workspace outside the repository
+	_ = os.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	if workspace, err := LoadWorkspace(); err != nil || workspace != nil {
+		t.Errorf("LoadWorkspace() without a workspace = %+v, %v", workspace, err)
+	}
+}
@@ -0,0 +1,144 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// Change is a value that differs between two run archives.
+type Change struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// DiffOp marks a line of a line diff.
+type DiffOp byte
+
+// Line diff operations
+const (
+	DiffEqual  DiffOp = ' '
+	DiffDelete DiffOp = '-'
+	DiffInsert DiffOp = '+'
+)
+
+// DiffLine is one line of a line diff.
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// CompareArchives returns the run information and configuration values that
+// differ between two archives. Configuration values are reported as
+// config.<key>, in key order.
+func CompareArchives(a, b *Archive) ([]Change, error) {
+	var changes []Change
+	for _, field := range []struct {
+		name     string
+		old, new string
+	}{
+		{"task_file", a.TaskFile, b.TaskFile},
+		{"sleepship_version", a.SleepshipVersion, b.SleepshipVersion},
+		{"agent_version", a.AgentVersion, b.AgentVersion},
+		{"git_head", a.GitHead, b.GitHead},
+	} {
+		if field.old != field.new {
+			changes = append(changes, Change{Field: field.name, Old: field.old, New: field.new})
+		}
+	}
+
+	oldConfig, err := configValues(a.Config)
+	if err != nil {
+		return nil, err
+	}
+	newConfig, err := configValues(b.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool)
+	for key := range oldConfig {
+		keys[key] = true
+	}
+	for key := range newConfig {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		if oldConfig[key] != newConfig[key] {
+			changes = append(changes, Change{Field: "config." + key, Old: oldConfig[key], New: newConfig[key]})
+		}
+	}
+	return changes, nil
+}
+
+// configValues returns the top-level values of an archived configuration as
+// compact JSON.
+func configValues(data json.RawMessage) (map[string]string, error) {
+	values := make(map[string]string)
+	if len(data) == 0 {
+		return values, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range fields {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, value); err != nil {
+			return nil, err
+		}
+		values[key] = compact.String()
+	}
+	return values, nil
+}
+
+// DiffLines returns a line diff that turns a into b, based on the longest
+// common subsequence.
+func DiffLines(a, b []string) []DiffLine {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+	return diff
+}
@@ -0,0 +1,69 @@
+package history
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCompareArchives(t *testing.T) {
+	a := &Archive{
+		TaskFile:         "tasks.txt",
+		TaskFileHash:     "111",
+		Config:           json.RawMessage(`{"max_retries":3,"log_dir":"logs","claude_flags":null}`),
+		SleepshipVersion: "v1.0.0",
+		GitHead:          "abc",
+	}
+	b := &Archive{
+		TaskFile:         "tasks.txt",
+		TaskFileHash:     "111",
+		Config:           json.RawMessage(`{"max_retries":5,"log_dir":"logs","push":"end"}`),
+		SleepshipVersion: "v1.0.0",
+		GitHead:          "def",
+	}
+
+	changes, err := CompareArchives(a, b)
+	if err != nil {
+		t.Fatalf("CompareArchives() error = %v", err)
+	}
+	want := []Change{
+		{Field: "git_head", Old: "abc", New: "def"},
+		{Field: "config.claude_flags", Old: "null"},
+		{Field: "config.max_retries", Old: "3", New: "5"},
+		{Field: "config.push", New: `"end"`},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("CompareArchives() = %+v, want %+v", changes, want)
+	}
+
+	if changes, err := CompareArchives(a, a); err != nil || len(changes) != 0 {
+		t.Errorf("CompareArchives() of equal archives = %+v, %v", changes, err)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []DiffLine
+	}{
+		{name: "equal", a: []string{"x", "y"}, b: []string{"x", "y"}, want: []DiffLine{{DiffEqual, "x"}, {DiffEqual, "y"}}},
+		{name: "insert", a: []string{"x"}, b: []string{"x", "y"}, want: []DiffLine{{DiffEqual, "x"}, {DiffInsert, "y"}}},
+		{name: "delete", a: []string{"x", "y"}, b: []string{"y"}, want: []DiffLine{{DiffDelete, "x"}, {DiffEqual, "y"}}},
+		{
+			name: "replace",
+			a:    []string{"## Task 1", "old", "## Task 2"},
+			b:    []string{"## Task 1", "new", "## Task 2"},
+			want: []DiffLine{{DiffEqual, "## Task 1"}, {DiffDelete, "old"}, {DiffInsert, "new"}, {DiffEqual, "## Task 2"}},
+		},
+		{name: "empty", a: nil, b: []string{"x"}, want: []DiffLine{{DiffInsert, "x"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffLines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffLines() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats accepted by Export
+const (
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+)
+
+// exportColumns are the columns written by the CSV and Markdown formats.
+var exportColumns = []string{
+	"id", "executed_at", "status", "task_file", "duration_seconds",
+	"task_count", "failed_tasks", "max_retries", "branch", "pushed_ref", "error", "project_dir",
+	"cost_usd", "input_tokens", "output_tokens",
+}
+
+// Export writes entries to w in the given format. JSON contains every field,
+// including per-task records; CSV and Markdown contain one row per run.
+func Export(w io.Writer, entries []Entry, format string) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(exportColumns); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := writer.Write(exportRow(entry)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case FormatMarkdown:
+		return writeMarkdown(w, entries)
+	default:
+		return fmt.Errorf("invalid format %q (valid: %s, %s, %s)", format, FormatJSON, FormatCSV, FormatMarkdown)
+	}
+}
+
+// exportRow returns the values of exportColumns for an entry.
+func exportRow(entry Entry) []string {
+	status := StatusSuccess
+	if !entry.Success {
+		status = StatusFailed
+	}
+
+	failedTasks := 0
+	for _, task := range entry.Tasks {
+		if task.Status == TaskFailed {
+			failedTasks++
+		}
+	}
+
+	// Usage columns are empty for runs without reported usage
+	var cost, inputTokens, outputTokens string
+	if entry.Usage != nil {
+		cost = strconv.FormatFloat(entry.Usage.CostUSD, 'f', 4, 64)
+		inputTokens = strconv.FormatInt(entry.Usage.InputTokens+entry.Usage.CacheCreationInputTokens+entry.Usage.CacheReadInputTokens, 10)
+		outputTokens = strconv.FormatInt(entry.Usage.OutputTokens, 10)
+	}
+
+	return []string{
+		entry.ID,
+		entry.ExecutedAt.Format(time.RFC3339),
+		status,
+		entry.TaskFile,
+		strconv.FormatFloat(entry.Duration.Seconds(), 'f', 0, 64),
+		strconv.Itoa(entry.TaskCount),
+		strconv.Itoa(failedTasks),
+		strconv.Itoa(entry.MaxRetries),
+		entry.BranchName,
+		entry.PushedRef,
+		entry.ErrorMessage,
+		entry.ProjectDir,
+		cost,
+		inputTokens,
+		outputTokens,
+	}
+}
+
+// writeMarkdown writes entries as a Markdown table.
+func writeMarkdown(w io.Writer, entries []Entry) error {
+	escape := strings.NewReplacer("|", "\\|", "\r\n", " ", "\n", " ")
+
+	var b strings.Builder
+	b.WriteString("| " + strings.Join(exportColumns, " | ") + " |\n")
+	b.WriteString("|" + strings.Repeat("---|", len(exportColumns)) + "\n")
+	for _, entry := range entries {
+		row := exportRow(entry)
+		for i := range row {
+			row[i] = escape.Replace(row[i])
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
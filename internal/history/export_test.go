@@ -0,0 +1,89 @@
+package history
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExport(t *testing.T) {
+	entries := []Entry{{
+		ID:           "20250101-020000",
+		TaskFile:     "tasks.txt",
+		ExecutedAt:   time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC),
+		Duration:     90 * time.Second,
+		TaskCount:    2,
+		ErrorMessage: "exit | status\n1",
+		Tasks:        []TaskRecord{{Number: 1, Status: TaskSucceeded}, {Number: 2, Status: TaskFailed}},
+	}}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Export(&buf, entries, FormatJSON); err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+		var decoded []Entry
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if len(decoded) != 1 || len(decoded[0].Tasks) != 2 {
+			t.Errorf("decoded = %+v", decoded)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Export(&buf, entries, FormatCSV); err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("invalid CSV: %v", err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("rows = %d, want 2", len(records))
+		}
+		row := strings.Join(records[1], ",")
+		if want := "20250101-020000,2025-01-01T02:00:00Z,failed,tasks.txt,90,2,1,0,,,exit | status\n1,,,,"; row != want {
+			t.Errorf("row = %q, want %q", row, want)
+		}
+
+		// Usage columns, with cache tokens counted as input
+		withUsage := entries[0]
+		withUsage.Usage = &Usage{InputTokens: 10, CacheReadInputTokens: 90, OutputTokens: 5, CostUSD: 0.125}
+		if got := exportRow(withUsage)[len(exportColumns)-3:]; strings.Join(got, ",") != "0.1250,100,5" {
+			t.Errorf("usage columns = %v, want [0.1250 100 5]", got)
+		}
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Export(&buf, entries, FormatMarkdown); err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("lines = %d, want 3:\n%s", len(lines), buf.String())
+		}
+		if !strings.Contains(lines[2], `exit \| status 1`) {
+			t.Errorf("row not escaped: %s", lines[2])
+		}
+	})
+
+	t.Run("empty json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Export(&buf, nil, FormatJSON); err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+		if strings.TrimSpace(buf.String()) != "[]" {
+			t.Errorf("empty export = %q, want []", buf.String())
+		}
+	})
+
+	if err := Export(&bytes.Buffer{}, entries, "xml"); err == nil {
+		t.Error("Export() expected error for unknown format")
+	}
+}
@@ -2,10 +2,10 @@
 package history
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 )
 
@@ -14,9 +14,25 @@ const (
 	historyFile = "history.json"
 )
 
+// Task statuses recorded in TaskRecord
+const (
+	TaskSucceeded = "succeeded"
+	TaskFailed    = "failed"
+	TaskSkipped   = "skipped"
+)
+
+// Reasons recorded in Entry.StopReason when a run stops on a budget
+const (
+	StopMaxDuration   = "max_duration"
+	StopMaxAgentCalls = "max_agent_calls"
+	StopMaxCost       = "max_cost"
+)
+
 // Entry represents a single task execution history entry
 type Entry struct {
+	ID           string        `json:"id,omitempty"`
 	TaskFile     string        `json:"task_file"`
+	RunHash      string        `json:"run_hash,omitempty"` // Run archive, see SaveArchive
 	ExecutedAt   time.Time     `json:"executed_at"`
 	Success      bool          `json:"success"`
 	Duration     time.Duration `json:"duration"`
@@ -25,6 +41,53 @@ type Entry struct {
 	StartFrom    int           `json:"start_from,omitempty"`
 	MaxRetries   int           `json:"max_retries,omitempty"`
 	BranchName   string        `json:"branch_name,omitempty"`
+	PushedRef    string        `json:"pushed_ref,omitempty"`
+	LogFile      string        `json:"log_file,omitempty"`    // Relative to the project directory
+	ProjectDir   string        `json:"project_dir,omitempty"` // Set when loaded from the global index
+	Member       string        `json:"member,omitempty"`      // Workspace member the run was for
+	Tasks        []TaskRecord  `json:"tasks,omitempty"`
+	Options      *RunOptions   `json:"options,omitempty"`
+	Usage        *Usage        `json:"usage,omitempty"`       // Agent usage of the whole run
+	StopReason   string        `json:"stop_reason,omitempty"` // Budget that stopped the run
+	NextTask     int           `json:"next_task,omitempty"`   // Task to resume from if the run stopped early
+}
+
+// RunOptions records the sync options of a run that are not recorded
+// elsewhere in Entry, so that the run can be repeated
+type RunOptions struct {
+	LogDir          string   `json:"log_dir,omitempty"`
+	CommitStrategy  string   `json:"commit_strategy,omitempty"`
+	RollbackPolicy  string   `json:"rollback,omitempty"`
+	KeepGoing       bool     `json:"keep_going,omitempty"`
+	PreCommitChecks []string `json:"pre_commit_checks,omitempty"`
+	Push            string   `json:"push,omitempty"`
+	PushRemote      string   `json:"push_remote,omitempty"`
+	BranchBase      string   `json:"branch_base,omitempty"`
+	MaxDuration     string   `json:"max_duration,omitempty"`
+	MaxAgentCalls   int      `json:"max_agent_calls,omitempty"`
+	MaxCost         float64  `json:"max_cost,omitempty"`
+}
+
+// TaskRecord represents the outcome of a single task within a run
+type TaskRecord struct {
+	Number         int                  `json:"number"`
+	Title          string               `json:"title"`
+	Status         string               `json:"status"`
+	Attempts       int                  `json:"attempts,omitempty"`
+	Verifications  []VerificationRecord `json:"verifications,omitempty"`
+	Duration       time.Duration        `json:"duration,omitempty"`
+	CommitSHA      string               `json:"commit_sha,omitempty"`
+	AgentExitCodes []int                `json:"agent_exit_codes,omitempty"`
+	Error          string               `json:"error,omitempty"`
+	Usage          *Usage               `json:"usage,omitempty"`         // Total of AttemptUsage
+	AttemptUsage   []Usage              `json:"attempt_usage,omitempty"` // Usage of each agent call
+}
+
+// VerificationRecord represents a single run of a verification command
+type VerificationRecord struct {
+	Command string `json:"command"`
+	Passed  bool   `json:"passed"`
+	Error   string `json:"error,omitempty"`
 }
 
 // History manages task execution history
@@ -32,48 +95,35 @@ type History struct {
 	Entries []Entry `json:"entries"`
 }
 
-// Load loads history from the history file
+// Load loads history from the history snapshot and journal
 func Load(projectDir string) (*History, error) {
-	historyPath := getHistoryPath(projectDir)
-
-	// If history file doesn't exist, return empty history
-	if _, err := os.Stat(historyPath); os.IsNotExist(err) {
+	// If the history directory doesn't exist, return empty history
+	if _, err := os.Stat(filepath.Join(projectDir, historyDir)); os.IsNotExist(err) {
 		return &History{Entries: []Entry{}}, nil
 	}
 
-	data, err := os.ReadFile(historyPath)
+	history := &History{Entries: []Entry{}}
+	err := withLock(projectDir, false, func() error {
+		snapshot, journal, err := readEntries(projectDir)
+		if err != nil {
+			return err
+		}
+		history.Entries = append(append(history.Entries, snapshot...), journal...)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read history file: %w", err)
+		return nil, err
 	}
 
-	var history History
-	if err := json.Unmarshal(data, &history); err != nil {
-		return nil, fmt.Errorf("failed to parse history file: %w", err)
-	}
-
-	return &history, nil
+	return history, nil
 }
 
-// Save saves history to the history file
+// Save replaces the stored history with h. Entries recorded by other
+// processes since h was loaded are lost; use RecordEntry to add entries.
 func (h *History) Save(projectDir string) error {
-	historyPath := getHistoryPath(projectDir)
-
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(historyPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create history directory: %w", err)
-	}
-
-	data, err := json.MarshalIndent(h, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal history: %w", err)
-	}
-
-	if err := os.WriteFile(historyPath, data, 0600); err != nil {
-		return fmt.Errorf("failed to write history file: %w", err)
-	}
-
-	return nil
+	return withLock(projectDir, true, func() error {
+		return writeSnapshot(projectDir, h.Entries)
+	})
 }
 
 // Add adds a new entry to the history
@@ -116,6 +166,25 @@ func (h *History) GetSucceeded() []Entry {
 	return succeeded
 }
 
+// Find returns the entry with the given run ID. Entries recorded before run
+// IDs existed can be addressed by their 1-based position in the history.
+func (h *History) Find(idOrIndex string) (*Entry, error) {
+	for i := range h.Entries {
+		if h.Entries[i].ID != "" && h.Entries[i].ID == idOrIndex {
+			return &h.Entries[i], nil
+		}
+	}
+
+	if index, err := strconv.Atoi(idOrIndex); err == nil {
+		if index < 1 || index > len(h.Entries) {
+			return nil, fmt.Errorf("history index out of range: %d (1-%d)", index, len(h.Entries))
+		}
+		return &h.Entries[index-1], nil
+	}
+
+	return nil, fmt.Errorf("history entry not found: %s", idOrIndex)
+}
+
 // getHistoryPath returns the absolute path to the history file
 func getHistoryPath(projectDir string) string {
 	return filepath.Join(projectDir, historyDir, historyFile)
@@ -123,11 +192,6 @@ func getHistoryPath(projectDir string) string {
 
 // Record is a convenience function to record a task execution
 func Record(projectDir, taskFile, branchName string, success bool, duration time.Duration, taskCount, startFrom, maxRetries int, errorMsg string) error {
-	history, err := Load(projectDir)
-	if err != nil {
-		return err
-	}
-
 	entry := Entry{
 		TaskFile:     taskFile,
 		ExecutedAt:   time.Now(),
@@ -140,7 +204,38 @@ func Record(projectDir, taskFile, branchName string, success bool, duration time
 		BranchName:   branchName,
 	}
 
-	history.Add(entry)
+	return RecordEntry(projectDir, entry)
+}
+
+// RecordEntry records a fully populated entry. ExecutedAt defaults to now.
+// It is safe to call from concurrent processes: the entry is appended to the
+// journal under an exclusive lock, and the journal is merged into the
+// snapshot once it grows large enough.
+func RecordEntry(projectDir string, entry Entry) error {
+	if entry.ExecutedAt.IsZero() {
+		entry.ExecutedAt = time.Now()
+	}
+
+	return withLock(projectDir, true, func() error {
+		snapshot, journal, err := readEntries(projectDir)
+		if err != nil {
+			return err
+		}
+		// Start a new journal instead of appending to one the snapshot
+		// already contains
+		if len(journal) == 0 {
+			if err := os.Remove(getJournalPath(projectDir)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to clear history journal: %w", err)
+			}
+		}
 
-	return history.Save(projectDir)
+		if err := appendJournal(projectDir, entry); err != nil {
+			return err
+		}
+		journal = append(journal, entry)
+		if len(journal) >= compactAfter {
+			return compact(projectDir, snapshot, journal)
+		}
+		return nil
+	})
 }
@@ -216,3 +216,139 @@ func TestRecord(t *testing.T) {
 		t.Errorf("ErrorMessage mismatch: expected 'test error', got '%s'", entry2.ErrorMessage)
 	}
 }
+
+func TestRecordEntry(t *testing.T) {
+	tempDir := t.TempDir()
+
+	err := RecordEntry(tempDir, Entry{
+		TaskFile:   "tasks-push.txt",
+		Success:    true,
+		BranchName: "feature/push",
+		PushedRef:  "origin/feature/push",
+	})
+	if err != nil {
+		t.Fatalf("Failed to record entry: %v", err)
+	}
+
+	hist, err := Load(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	if len(hist.Entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(hist.Entries))
+	}
+
+	entry := hist.Entries[0]
+	if entry.PushedRef != "origin/feature/push" {
+		t.Errorf("PushedRef mismatch: expected origin/feature/push, got %s", entry.PushedRef)
+	}
+	if entry.ExecutedAt.IsZero() {
+		t.Error("ExecutedAt should default to the current time")
+	}
+}
+
+func TestLoadLegacyHistory(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// History written before run IDs and per-task records existed
+	legacy := `{
+  "entries": [
+    {
+      "task_file": "tasks.txt",
+      "executed_at": "2025-01-01T02:00:00Z",
+      "success": false,
+      "duration": 60000000000,
+      "task_count": 3,
+      "error_message": "task 2 failed"
+    }
+  ]
+}`
+	if err := os.MkdirAll(filepath.Join(tempDir, historyDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(getHistoryPath(tempDir), []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	hist, err := Load(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load legacy history: %v", err)
+	}
+	if len(hist.Entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(hist.Entries))
+	}
+	if hist.Entries[0].ID != "" || hist.Entries[0].Tasks != nil {
+		t.Errorf("Legacy entry should have no ID or tasks, got %q, %v", hist.Entries[0].ID, hist.Entries[0].Tasks)
+	}
+}
+
+func TestRecordEntryTasks(t *testing.T) {
+	tempDir := t.TempDir()
+
+	err := RecordEntry(tempDir, Entry{
+		ID:       "20250101-020000",
+		TaskFile: "tasks.txt",
+		Tasks: []TaskRecord{
+			{Number: 1, Title: "Setup", Status: TaskSucceeded, Attempts: 1, CommitSHA: "abc1234", AgentExitCodes: []int{0}},
+			{
+				Number:         2,
+				Title:          "Build",
+				Status:         TaskFailed,
+				Attempts:       2,
+				AgentExitCodes: []int{1, 0},
+				Verifications:  []VerificationRecord{{Command: "go build", Passed: false, Error: "exit status 1"}},
+				Error:          "verification failed",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to record entry: %v", err)
+	}
+
+	hist, err := Load(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+
+	tasks := hist.Entries[0].Tasks
+	if len(tasks) != 2 {
+		t.Fatalf("Expected 2 task records, got %d", len(tasks))
+	}
+	if tasks[1].Status != TaskFailed || tasks[1].Attempts != 2 || len(tasks[1].AgentExitCodes) != 2 {
+		t.Errorf("Task record mismatch: %+v", tasks[1])
+	}
+	if len(tasks[1].Verifications) != 1 || tasks[1].Verifications[0].Passed {
+		t.Errorf("Verification record mismatch: %+v", tasks[1].Verifications)
+	}
+}
+
+func TestFind(t *testing.T) {
+	hist := &History{Entries: []Entry{
+		{TaskFile: "legacy.txt"},
+		{ID: "20250101-020000", TaskFile: "first.txt"},
+		{ID: "20250102-020000", TaskFile: "second.txt"},
+	}}
+
+	tests := []struct {
+		query    string
+		wantFile string
+		wantErr  bool
+	}{
+		{query: "20250102-020000", wantFile: "second.txt"},
+		{query: "1", wantFile: "legacy.txt"},
+		{query: "2", wantFile: "first.txt"},
+		{query: "4", wantErr: true},
+		{query: "20240101-000000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		entry, err := hist.Find(tt.query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Find(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if err == nil && entry.TaskFile != tt.wantFile {
+			t.Errorf("Find(%q) = %s, want %s", tt.query, entry.TaskFile, tt.wantFile)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	indexFile     = "projects.json"
+	indexLockFile = "projects.lock"
+)
+
+// ProjectRecord is a project registered in the global index.
+type ProjectRecord struct {
+	Path      string    `json:"path"`
+	LastRunAt time.Time `json:"last_run_at"`
+	Runs      int       `json:"runs"`
+}
+
+// Index is the global index of projects that sleepship has run in. The
+// history of each run stays in its project; the index only records where
+// to find it.
+type Index struct {
+	Projects []ProjectRecord `json:"projects"`
+}
+
+// RegisterProject records a run in projectDir in the index stored in indexDir.
+func RegisterProject(indexDir, projectDir string, executedAt time.Time) error {
+	if err := os.MkdirAll(indexDir, 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	lock, err := acquireLock(filepath.Join(indexDir, indexLockFile), true)
+	if err != nil {
+		return fmt.Errorf("failed to lock index: %w", err)
+	}
+	defer func() { _ = lock.release() }()
+
+	index, err := readIndex(indexDir)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i := range index.Projects {
+		if index.Projects[i].Path == projectDir {
+			index.Projects[i].Runs++
+			if executedAt.After(index.Projects[i].LastRunAt) {
+				index.Projects[i].LastRunAt = executedAt
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		index.Projects = append(index.Projects, ProjectRecord{Path: projectDir, LastRunAt: executedAt, Runs: 1})
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(indexDir, indexFile), data, 0600); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+// LoadIndex loads the index stored in indexDir.
+func LoadIndex(indexDir string) (*Index, error) {
+	if _, err := os.Stat(indexDir); os.IsNotExist(err) {
+		return &Index{Projects: []ProjectRecord{}}, nil
+	}
+
+	lock, err := acquireLock(filepath.Join(indexDir, indexLockFile), false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock index: %w", err)
+	}
+	defer func() { _ = lock.release() }()
+
+	return readIndex(indexDir)
+}
+
+// LoadAll loads the history of every project in the index, oldest first.
+// Each entry's ProjectDir is set to its project. Projects that no longer
+// exist are skipped.
+func LoadAll(indexDir string) ([]Entry, error) {
+	index, err := LoadIndex(indexDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, project := range index.Projects {
+		if _, err := os.Stat(project.Path); err != nil {
+			continue
+		}
+		hist, err := Load(project.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load history of %s: %w", project.Path, err)
+		}
+		for _, entry := range hist.Entries {
+			entry.ProjectDir = project.Path
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ExecutedAt.Before(entries[j].ExecutedAt) })
+	return entries, nil
+}
+
+// readIndex reads the index file, if it exists.
+func readIndex(indexDir string) (*Index, error) {
+	index := &Index{Projects: []ProjectRecord{}}
+
+	data, err := os.ReadFile(filepath.Join(indexDir, indexFile))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse index: %w", err)
+	}
+	return index, nil
+}
@@ -0,0 +1,72 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRegisterProjectAndLoadAll(t *testing.T) {
+	indexDir := filepath.Join(t.TempDir(), "sleepship")
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	projectA := t.TempDir()
+	projectB := t.TempDir()
+	removed := filepath.Join(t.TempDir(), "removed")
+
+	records := []struct {
+		dir string
+		id  string
+		at  time.Time
+	}{
+		{projectA, "a1", base},
+		{projectB, "b1", base.Add(time.Hour)},
+		{projectA, "a2", base.Add(2 * time.Hour)},
+		{removed, "r1", base.Add(3 * time.Hour)},
+	}
+	for _, r := range records {
+		if err := os.MkdirAll(r.dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := RecordEntry(r.dir, Entry{ID: r.id, ExecutedAt: r.at}); err != nil {
+			t.Fatalf("Failed to record entry: %v", err)
+		}
+		if err := RegisterProject(indexDir, r.dir, r.at); err != nil {
+			t.Fatalf("RegisterProject() error = %v", err)
+		}
+	}
+	if err := os.RemoveAll(removed); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := LoadIndex(indexDir)
+	if err != nil {
+		t.Fatalf("LoadIndex() error = %v", err)
+	}
+	if len(index.Projects) != 3 {
+		t.Fatalf("Projects = %+v, want 3 projects", index.Projects)
+	}
+	if index.Projects[0].Path != projectA || index.Projects[0].Runs != 2 || !index.Projects[0].LastRunAt.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("project A record = %+v", index.Projects[0])
+	}
+
+	entries, err := LoadAll(indexDir)
+	if err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+	if got := entryIDs(entries); !slices.Equal(got, []string{"a1", "b1", "a2"}) {
+		t.Errorf("LoadAll() = %v, want [a1 b1 a2]", got)
+	}
+	if entries[1].ProjectDir != projectB {
+		t.Errorf("ProjectDir = %s, want %s", entries[1].ProjectDir, projectB)
+	}
+}
+
+func TestLoadAllWithoutIndex(t *testing.T) {
+	entries, err := LoadAll(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || len(entries) != 0 {
+		t.Errorf("LoadAll() = %v, %v; want no entries", entries, err)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package history
+
+// fileLock is a no-op on platforms without flock. Writes are still atomic,
+// but concurrent runs may lose entries.
+type fileLock struct{}
+
+// acquireLock returns a no-op lock.
+func acquireLock(_ string, _ bool) (*fileLock, error) {
+	return &fileLock{}, nil
+}
+
+// release releases the lock.
+func (l *fileLock) release() error {
+	return nil
+}
@@ -0,0 +1,133 @@
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Run statuses used by Filter.Status
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// Sort keys accepted by SortEntries
+const (
+	SortExecutedAt = "executed_at"
+	SortDuration   = "duration"
+	SortTaskFile   = "task_file"
+	SortStatus     = "status"
+)
+
+// SortKeys lists all supported sort keys.
+var SortKeys = []string{SortExecutedAt, SortDuration, SortTaskFile, SortStatus}
+
+// Filter selects history entries. Zero-valued fields match every entry.
+type Filter struct {
+	Since       time.Time     // Executed at or after
+	Until       time.Time     // Executed before
+	TaskFile    string        // Glob on the file name, or substring of the path
+	Branch      string        // Substring of the branch name
+	Status      string        // StatusSuccess or StatusFailed
+	MinDuration time.Duration // Minimum run duration
+	Search      string        // Case-insensitive text in error messages
+}
+
+// Match reports whether the entry satisfies every condition of the filter.
+func (f Filter) Match(entry Entry) bool {
+	if !f.Since.IsZero() && entry.ExecutedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.ExecutedAt.Before(f.Until) {
+		return false
+	}
+	if f.TaskFile != "" && !matchTaskFile(f.TaskFile, entry.TaskFile) {
+		return false
+	}
+	if f.Branch != "" && !strings.Contains(entry.BranchName, f.Branch) {
+		return false
+	}
+	if f.Status == StatusSuccess && !entry.Success || f.Status == StatusFailed && entry.Success {
+		return false
+	}
+	if entry.Duration < f.MinDuration {
+		return false
+	}
+	if f.Search != "" && !containsFold(errorTexts(entry), f.Search) {
+		return false
+	}
+	return true
+}
+
+// Query returns the entries matching the filter, in history order.
+func (h *History) Query(filter Filter) []Entry {
+	var matched []Entry
+	for _, entry := range h.Entries {
+		if filter.Match(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+// SortEntries sorts entries in place by the given key. Entries with equal
+// keys keep their history order.
+func SortEntries(entries []Entry, key string, descending bool) error {
+	var less func(a, b Entry) bool
+	switch key {
+	case SortExecutedAt, "":
+		less = func(a, b Entry) bool { return a.ExecutedAt.Before(b.ExecutedAt) }
+	case SortDuration:
+		less = func(a, b Entry) bool { return a.Duration < b.Duration }
+	case SortTaskFile:
+		less = func(a, b Entry) bool { return a.TaskFile < b.TaskFile }
+	case SortStatus:
+		less = func(a, b Entry) bool { return !a.Success && b.Success }
+	default:
+		return fmt.Errorf("invalid sort key %q (valid: %s)", key, strings.Join(SortKeys, ", "))
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if descending {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+	return nil
+}
+
+// matchTaskFile matches a glob against the file name, or a plain pattern
+// against any part of the path.
+func matchTaskFile(pattern, taskFile string) bool {
+	if strings.ContainsAny(pattern, "*?[") {
+		matched, err := filepath.Match(pattern, filepath.Base(taskFile))
+		return err == nil && matched
+	}
+	return strings.Contains(taskFile, pattern)
+}
+
+// errorTexts returns the run error and the errors of its tasks and verifications.
+func errorTexts(entry Entry) []string {
+	texts := []string{entry.ErrorMessage}
+	for _, task := range entry.Tasks {
+		texts = append(texts, task.Error)
+		for _, v := range task.Verifications {
+			texts = append(texts, v.Error)
+		}
+	}
+	return texts
+}
+
+// containsFold reports whether any text contains substr, ignoring case.
+func containsFold(texts []string, substr string) bool {
+	substr = strings.ToLower(substr)
+	for _, text := range texts {
+		if strings.Contains(strings.ToLower(text), substr) {
+			return true
+		}
+	}
+	return false
+}
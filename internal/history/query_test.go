@@ -0,0 +1,76 @@
+package history
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestQuery(t *testing.T) {
+	base := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	hist := &History{Entries: []Entry{
+		{ID: "1", TaskFile: "tasks-api.txt", ExecutedAt: base, Success: true, Duration: 5 * time.Minute, BranchName: "feature/api"},
+		{ID: "2", TaskFile: "tasks-ui.txt", ExecutedAt: base.AddDate(0, 0, 1), Success: false, Duration: 20 * time.Minute, BranchName: "feature/ui", ErrorMessage: "task 2 failed"},
+		{ID: "3", TaskFile: "docs/tasks-api.txt", ExecutedAt: base.AddDate(0, 0, 2), Success: false, Duration: 1 * time.Minute,
+			Tasks: []TaskRecord{{Verifications: []VerificationRecord{{Command: "go test", Error: "TIMEOUT after 10m"}}}}},
+	}}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{name: "no filter", filter: Filter{}, want: []string{"1", "2", "3"}},
+		{name: "since", filter: Filter{Since: base.AddDate(0, 0, 1)}, want: []string{"2", "3"}},
+		{name: "until", filter: Filter{Until: base.AddDate(0, 0, 1)}, want: []string{"1"}},
+		{name: "task file glob", filter: Filter{TaskFile: "tasks-api*"}, want: []string{"1", "3"}},
+		{name: "task file path", filter: Filter{TaskFile: "docs/"}, want: []string{"3"}},
+		{name: "branch", filter: Filter{Branch: "ui"}, want: []string{"2"}},
+		{name: "status", filter: Filter{Status: StatusFailed}, want: []string{"2", "3"}},
+		{name: "min duration", filter: Filter{MinDuration: 5 * time.Minute}, want: []string{"1", "2"}},
+		{name: "search in task records", filter: Filter{Search: "timeout"}, want: []string{"3"}},
+		{name: "combined", filter: Filter{Status: StatusFailed, TaskFile: "api"}, want: []string{"3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := entryIDs(hist.Query(tt.filter)); !slices.Equal(got, tt.want) {
+				t.Errorf("Query() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortEntries(t *testing.T) {
+	base := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{ID: "1", TaskFile: "b.txt", ExecutedAt: base, Duration: 3 * time.Minute, Success: true},
+		{ID: "2", TaskFile: "a.txt", ExecutedAt: base.Add(time.Hour), Duration: 1 * time.Minute, Success: false},
+		{ID: "3", TaskFile: "c.txt", ExecutedAt: base.Add(2 * time.Hour), Duration: 2 * time.Minute, Success: true},
+	}
+
+	tests := []struct {
+		key  string
+		desc bool
+		want []string
+	}{
+		{key: SortExecutedAt, desc: true, want: []string{"3", "2", "1"}},
+		{key: SortDuration, want: []string{"2", "3", "1"}},
+		{key: SortTaskFile, want: []string{"2", "1", "3"}},
+		{key: SortStatus, want: []string{"2", "1", "3"}},
+	}
+
+	for _, tt := range tests {
+		sorted := slices.Clone(entries)
+		if err := SortEntries(sorted, tt.key, tt.desc); err != nil {
+			t.Fatalf("SortEntries(%s) error = %v", tt.key, err)
+		}
+		if got := entryIDs(sorted); !slices.Equal(got, tt.want) {
+			t.Errorf("SortEntries(%s, desc=%v) = %v, want %v", tt.key, tt.desc, got, tt.want)
+		}
+	}
+
+	if err := SortEntries(entries, "size", false); err == nil {
+		t.Error("SortEntries() expected error for unknown key")
+	}
+}
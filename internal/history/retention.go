@@ -0,0 +1,120 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Retention limits which history entries are kept. Zero values mean unlimited.
+type Retention struct {
+	MaxEntries int           // Maximum number of entries to keep
+	MaxAge     time.Duration // Maximum age of an entry
+}
+
+// IsUnlimited reports whether the retention keeps every entry.
+func (r Retention) IsUnlimited() bool {
+	return r.MaxEntries <= 0 && r.MaxAge <= 0
+}
+
+// Apply splits entries into the ones to keep and the ones to prune. Entries
+// older than MaxAge are pruned first, then the oldest entries beyond
+// MaxEntries. Both lists keep the original order.
+func (r Retention) Apply(entries []Entry, now time.Time) (kept, pruned []Entry) {
+	for _, entry := range entries {
+		if r.MaxAge > 0 && now.Sub(entry.ExecutedAt) > r.MaxAge {
+			pruned = append(pruned, entry)
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	if r.MaxEntries > 0 && len(kept) > r.MaxEntries {
+		excess := len(kept) - r.MaxEntries
+		pruned = append(pruned, kept[:excess]...)
+		kept = kept[excess:]
+	}
+
+	return kept, pruned
+}
+
+// Prune removes the entries that exceed the retention limits, together with
+// their log files and run archives, and returns the pruned entries.
+// With dryRun, nothing is removed.
+func Prune(projectDir string, retention Retention, dryRun bool) ([]Entry, error) {
+	if retention.IsUnlimited() {
+		return nil, nil
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, historyDir)); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var pruned []Entry
+	err := withLock(projectDir, true, func() error {
+		snapshot, journal, err := readEntries(projectDir)
+		if err != nil {
+			return err
+		}
+
+		var kept []Entry
+		kept, pruned = retention.Apply(append(snapshot, journal...), time.Now())
+		if dryRun || len(pruned) == 0 {
+			return nil
+		}
+
+		if err := writeSnapshot(projectDir, kept); err != nil {
+			return err
+		}
+		if err := removeUnusedArchives(projectDir, pruned, kept); err != nil {
+			return err
+		}
+		return removeLogFiles(projectDir, pruned, kept)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pruned, nil
+}
+
+// removeLogFiles removes the log files of pruned entries that no kept entry
+// refers to. Only files inside the project directory are removed.
+func removeLogFiles(projectDir string, pruned, kept []Entry) error {
+	inUse := make(map[string]bool)
+	for _, entry := range kept {
+		inUse[entry.LogFile] = true
+	}
+
+	var errs []error
+	for _, entry := range pruned {
+		if entry.LogFile == "" || inUse[entry.LogFile] {
+			continue
+		}
+
+		path := LogPath(projectDir, entry)
+		rel, err := filepath.Rel(projectDir, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, fmt.Errorf("failed to remove log file: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LogPath returns the absolute path of the entry's log file, or an empty
+// string if the entry has none.
+func LogPath(projectDir string, entry Entry) string {
+	if entry.LogFile == "" {
+		return ""
+	}
+	if filepath.IsAbs(entry.LogFile) {
+		return entry.LogFile
+	}
+	return filepath.Join(projectDir, entry.LogFile)
+}
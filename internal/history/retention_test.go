@@ -0,0 +1,129 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRetentionApply(t *testing.T) {
+	now := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{ID: "old", ExecutedAt: now.AddDate(0, 0, -40)},
+		{ID: "a", ExecutedAt: now.AddDate(0, 0, -3)},
+		{ID: "b", ExecutedAt: now.AddDate(0, 0, -2)},
+		{ID: "c", ExecutedAt: now.AddDate(0, 0, -1)},
+	}
+
+	tests := []struct {
+		name       string
+		retention  Retention
+		wantKept   []string
+		wantPruned []string
+	}{
+		{name: "unlimited", retention: Retention{}, wantKept: []string{"old", "a", "b", "c"}},
+		{name: "max age", retention: Retention{MaxAge: 30 * 24 * time.Hour}, wantKept: []string{"a", "b", "c"}, wantPruned: []string{"old"}},
+		{name: "max entries", retention: Retention{MaxEntries: 2}, wantKept: []string{"b", "c"}, wantPruned: []string{"old", "a"}},
+		{name: "both", retention: Retention{MaxEntries: 3, MaxAge: 30 * 24 * time.Hour}, wantKept: []string{"a", "b", "c"}, wantPruned: []string{"old"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, pruned := tt.retention.Apply(entries, now)
+			if got := entryIDs(kept); !slices.Equal(got, tt.wantKept) {
+				t.Errorf("kept = %v, want %v", got, tt.wantKept)
+			}
+			if got := entryIDs(pruned); !slices.Equal(got, tt.wantPruned) {
+				t.Errorf("pruned = %v, want %v", got, tt.wantPruned)
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	tempDir := t.TempDir()
+	logsDir := filepath.Join(tempDir, "logs")
+	if err := os.MkdirAll(logsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"1", "2", "3"} {
+		logFile := filepath.Join("logs", "sync-"+id+".log")
+		if err := os.WriteFile(filepath.Join(tempDir, logFile), []byte("log"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := RecordEntry(tempDir, Entry{ID: id, LogFile: logFile}); err != nil {
+			t.Fatalf("Failed to record entry: %v", err)
+		}
+	}
+
+	// A dry run reports but keeps everything
+	pruned, err := Prune(tempDir, Retention{MaxEntries: 1}, true)
+	if err != nil {
+		t.Fatalf("Prune() dry run error = %v", err)
+	}
+	if got := entryIDs(pruned); !slices.Equal(got, []string{"1", "2"}) {
+		t.Errorf("dry run pruned = %v, want [1 2]", got)
+	}
+	hist, err := Load(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	if len(hist.Entries) != 3 {
+		t.Errorf("dry run removed entries: %d left", len(hist.Entries))
+	}
+	if _, err := os.Stat(filepath.Join(logsDir, "sync-1.log")); err != nil {
+		t.Errorf("dry run removed a log file: %v", err)
+	}
+
+	if _, err := Prune(tempDir, Retention{MaxEntries: 1}, false); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	hist, err = Load(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	if got := entryIDs(hist.Entries); !slices.Equal(got, []string{"3"}) {
+		t.Errorf("entries after prune = %v, want [3]", got)
+	}
+	for id, wantExists := range map[string]bool{"1": false, "2": false, "3": true} {
+		_, err := os.Stat(filepath.Join(logsDir, "sync-"+id+".log"))
+		if exists := err == nil; exists != wantExists {
+			t.Errorf("log file of run %s exists = %v, want %v", id, exists, wantExists)
+		}
+	}
+}
+
+func TestPruneKeepsLogsOutsideProject(t *testing.T) {
+	tempDir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "outside.log")
+	if err := os.WriteFile(outside, []byte("log"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"1", "2"} {
+		if err := RecordEntry(tempDir, Entry{ID: id, LogFile: outside}); err != nil {
+			t.Fatalf("Failed to record entry: %v", err)
+		}
+	}
+	if err := RecordEntry(tempDir, Entry{ID: "3", LogFile: "../escape.log"}); err != nil {
+		t.Fatalf("Failed to record entry: %v", err)
+	}
+
+	if _, err := Prune(tempDir, Retention{MaxEntries: 1}, false); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("log file outside the project was removed: %v", err)
+	}
+}
+
+func entryIDs(entries []Entry) []string {
+	var ids []string
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	return ids
+}
@@ -0,0 +1,120 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// runDir is the directory under .sleepship that holds run archives
+	runDir = "runs"
+	// archiveFile is the name of the archive description in a run directory
+	archiveFile = "run.json"
+)
+
+// Archive describes what a run was started with. It is stored as run.json
+// next to a copy of the task file, in a run directory named after its hash,
+// so that identical runs share one directory.
+type Archive struct {
+	TaskFile         string          `json:"task_file"`
+	TaskFileHash     string          `json:"task_file_hash"`
+	Config           json.RawMessage `json:"config,omitempty"` // Merged configuration
+	SleepshipVersion string          `json:"sleepship_version,omitempty"`
+	AgentVersion     string          `json:"agent_version,omitempty"`
+	GitHead          string          `json:"git_head,omitempty"` // Commit checked out at start
+}
+
+// SaveArchive stores archive and the task file content in a run directory
+// and returns the hash that identifies it. TaskFileHash is filled in from
+// the content.
+func SaveArchive(projectDir string, archive Archive, taskContent []byte) (string, error) {
+	archive.TaskFileHash = hashContent(taskContent)
+
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal run archive: %w", err)
+	}
+	hash := hashContent(data)
+
+	dir := ArchiveDir(projectDir, hash)
+	if _, err := os.Stat(filepath.Join(dir, archiveFile)); err == nil {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create run directory: %w", err)
+	}
+	// The task file goes first so that run.json marks a complete archive
+	if err := writeFileAtomic(filepath.Join(dir, filepath.Base(archive.TaskFile)), taskContent, 0600); err != nil {
+		return "", fmt.Errorf("failed to write task file snapshot: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, archiveFile), data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write run archive: %w", err)
+	}
+	return hash, nil
+}
+
+// LoadArchive reads the run archive an entry refers to.
+func LoadArchive(projectDir string, entry Entry) (*Archive, error) {
+	if entry.RunHash == "" {
+		return nil, fmt.Errorf("no run archive recorded for this execution")
+	}
+
+	data, err := os.ReadFile(filepath.Join(ArchiveDir(projectDir, entry.RunHash), archiveFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run archive: %w", err)
+	}
+
+	var archive Archive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, fmt.Errorf("failed to parse run archive: %w", err)
+	}
+	return &archive, nil
+}
+
+// ArchiveDir returns the run directory of an archive.
+func ArchiveDir(projectDir, hash string) string {
+	return filepath.Join(projectDir, historyDir, runDir, hash)
+}
+
+// FindSnapshot returns the path of the task file an entry was run with.
+func FindSnapshot(projectDir string, entry Entry) (string, error) {
+	if entry.RunHash == "" {
+		return "", fmt.Errorf("no task file snapshot recorded for this execution")
+	}
+
+	path := filepath.Join(ArchiveDir(projectDir, entry.RunHash), filepath.Base(entry.TaskFile))
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("task file snapshot not found: %s", path)
+	}
+	return path, nil
+}
+
+// removeUnusedArchives removes the run directories of pruned entries that no
+// kept entry refers to.
+func removeUnusedArchives(projectDir string, pruned, kept []Entry) error {
+	inUse := make(map[string]bool)
+	for _, entry := range kept {
+		inUse[entry.RunHash] = true
+	}
+
+	for _, entry := range pruned {
+		if entry.RunHash == "" || inUse[entry.RunHash] {
+			continue
+		}
+		if err := os.RemoveAll(ArchiveDir(projectDir, entry.RunHash)); err != nil {
+			return fmt.Errorf("failed to remove run archive: %w", err)
+		}
+	}
+	return nil
+}
+
+// hashContent returns the hex-encoded SHA-256 hash of data.
+func hashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,105 @@
+package history
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveArchive(t *testing.T) {
+	tempDir := t.TempDir()
+	archive := Archive{
+		TaskFile:         "docs/tasks.txt",
+		Config:           json.RawMessage(`{"max_retries":3}`),
+		SleepshipVersion: "v1.0.0",
+		GitHead:          "abc123",
+	}
+
+	hash, err := SaveArchive(tempDir, archive, []byte("## Task 1\n"))
+	if err != nil {
+		t.Fatalf("SaveArchive() error = %v", err)
+	}
+
+	// The same run is stored only once
+	again, err := SaveArchive(tempDir, archive, []byte("## Task 1\n"))
+	if err != nil || again != hash {
+		t.Errorf("SaveArchive() again = %q, %v, want %q", again, err, hash)
+	}
+	changed, err := SaveArchive(tempDir, archive, []byte("## Task 1 (edited)\n"))
+	if err != nil || changed == hash {
+		t.Errorf("SaveArchive() with new content = %q, %v, want a new hash", changed, err)
+	}
+
+	entry := Entry{TaskFile: "docs/tasks.txt", RunHash: hash}
+	loaded, err := LoadArchive(tempDir, entry)
+	if err != nil {
+		t.Fatalf("LoadArchive() error = %v", err)
+	}
+	if loaded.GitHead != "abc123" || loaded.TaskFileHash == "" {
+		t.Errorf("LoadArchive() = %+v", loaded)
+	}
+	if values, err := configValues(loaded.Config); err != nil || values["max_retries"] != "3" {
+		t.Errorf("archived config = %v, %v", values, err)
+	}
+
+	path, err := FindSnapshot(tempDir, entry)
+	if err != nil {
+		t.Fatalf("FindSnapshot() error = %v", err)
+	}
+	if filepath.Base(path) != "tasks.txt" {
+		t.Errorf("FindSnapshot() = %s, want a file named tasks.txt", path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != "## Task 1\n" {
+		t.Errorf("snapshot content = %q, %v", content, err)
+	}
+
+	if _, err := FindSnapshot(tempDir, Entry{TaskFile: "tasks.txt"}); err == nil {
+		t.Error("FindSnapshot() without an archive should fail")
+	}
+	if _, err := LoadArchive(tempDir, Entry{TaskFile: "tasks.txt"}); err == nil {
+		t.Error("LoadArchive() without an archive should fail")
+	}
+}
+
+func TestPruneRemovesArchives(t *testing.T) {
+	tempDir := t.TempDir()
+
+	oldHash, err := SaveArchive(tempDir, Archive{TaskFile: "tasks.txt"}, []byte("old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newHash, err := SaveArchive(tempDir, Archive{TaskFile: "tasks.txt"}, []byte("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The old archive is still used by entry 2 after entry 1 is pruned
+	for _, entry := range []Entry{
+		{ID: "1", TaskFile: "tasks.txt", RunHash: oldHash},
+		{ID: "2", TaskFile: "tasks.txt", RunHash: oldHash},
+		{ID: "3", TaskFile: "tasks.txt", RunHash: newHash},
+	} {
+		if err := RecordEntry(tempDir, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := Prune(tempDir, Retention{MaxEntries: 2}, false); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if _, err := os.Stat(ArchiveDir(tempDir, oldHash)); err != nil {
+		t.Errorf("archive in use was removed: %v", err)
+	}
+
+	if _, err := Prune(tempDir, Retention{MaxEntries: 1}, false); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if _, err := os.Stat(ArchiveDir(tempDir, oldHash)); !os.IsNotExist(err) {
+		t.Errorf("unused archive was kept: %v", err)
+	}
+	if _, err := os.Stat(ArchiveDir(tempDir, newHash)); err != nil {
+		t.Errorf("archive in use was removed: %v", err)
+	}
+}
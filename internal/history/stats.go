@@ -0,0 +1,296 @@
+package history
+
+import (
+	"math"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// trendBuckets is the maximum number of points in a success rate trend
+const trendBuckets = 10
+
+// trendRuns is the maximum number of runs in a duration trend
+const trendRuns = 20
+
+// Stats summarizes a set of history entries.
+type Stats struct {
+	Runs                 int                   `json:"runs"`
+	Succeeded            int                   `json:"succeeded"`
+	SuccessRate          float64               `json:"success_rate"`
+	TotalDuration        time.Duration         `json:"total_duration"`
+	TotalCost            float64               `json:"total_cost_usd"`
+	TotalTokens          int64                 `json:"total_tokens"`
+	TaskFiles            []TaskFileStats       `json:"task_files"`
+	Tasks                []TaskStats           `json:"tasks"`
+	VerificationFailures []VerificationFailure `json:"verification_failures"`
+	FlakyTasks           []TaskStats           `json:"flaky_tasks"`
+}
+
+// TaskFileStats summarizes the runs of one task file.
+type TaskFileStats struct {
+	TaskFile      string          `json:"task_file"`
+	Runs          int             `json:"runs"`
+	Succeeded     int             `json:"succeeded"`
+	SuccessRate   float64         `json:"success_rate"`
+	MeanDuration  time.Duration   `json:"mean_duration"`
+	P95Duration   time.Duration   `json:"p95_duration"`
+	MeanCost      float64         `json:"mean_cost_usd"`  // Over runs with reported usage
+	SuccessTrend  []float64       `json:"success_trend"`  // Success rate per period, oldest first
+	DurationTrend []time.Duration `json:"duration_trend"` // Duration of the latest runs, oldest first
+}
+
+// TaskStats summarizes the runs of one task, identified by its title.
+type TaskStats struct {
+	Title          string        `json:"title"`
+	Runs           int           `json:"runs"` // Runs in which the task was attempted
+	Succeeded      int           `json:"succeeded"`
+	SuccessRate    float64       `json:"success_rate"`
+	MeanDuration   time.Duration `json:"mean_duration"`
+	P95Duration    time.Duration `json:"p95_duration"`
+	AverageRetries float64       `json:"average_retries"`
+	MeanCost       float64       `json:"mean_cost_usd"` // Over runs with reported usage
+	FlakyRuns      int           `json:"flaky_runs"`    // Successful runs that needed fixes
+	SuccessTrend   []float64     `json:"success_trend"`
+}
+
+// VerificationFailure counts the failures of one verification command.
+type VerificationFailure struct {
+	Command  string `json:"command"`
+	Failures int    `json:"failures"`
+}
+
+// ComputeStats computes statistics over entries. Per-task statistics only
+// cover entries that contain task records.
+func ComputeStats(entries []Entry) Stats {
+	sorted := make([]Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ExecutedAt.Before(sorted[j].ExecutedAt) })
+
+	stats := Stats{
+		TaskFiles:            []TaskFileStats{},
+		Tasks:                []TaskStats{},
+		VerificationFailures: []VerificationFailure{},
+		FlakyTasks:           []TaskStats{},
+	}
+
+	type fileRuns struct {
+		outcomes  []bool
+		durations []time.Duration
+		costs     []float64
+	}
+	type taskRuns struct {
+		outcomes  []bool
+		durations []time.Duration
+		costs     []float64
+		retries   int
+		flaky     int
+	}
+	var fileOrder, taskOrder []string
+	files := make(map[string]*fileRuns)
+	tasks := make(map[string]*taskRuns)
+	failures := make(map[string]int)
+
+	for _, entry := range sorted {
+		stats.Runs++
+		if entry.Success {
+			stats.Succeeded++
+		}
+		stats.TotalDuration += entry.Duration
+		if entry.Usage != nil {
+			stats.TotalCost += entry.Usage.CostUSD
+			stats.TotalTokens += entry.Usage.TotalTokens()
+		}
+
+		name := filepath.Base(entry.TaskFile)
+		if files[name] == nil {
+			files[name] = &fileRuns{}
+			fileOrder = append(fileOrder, name)
+		}
+		files[name].outcomes = append(files[name].outcomes, entry.Success)
+		files[name].durations = append(files[name].durations, entry.Duration)
+		if entry.Usage != nil {
+			files[name].costs = append(files[name].costs, entry.Usage.CostUSD)
+		}
+
+		for _, task := range entry.Tasks {
+			for _, v := range task.Verifications {
+				if !v.Passed {
+					failures[v.Command]++
+				}
+			}
+			if task.Status == TaskSkipped {
+				continue
+			}
+
+			if tasks[task.Title] == nil {
+				tasks[task.Title] = &taskRuns{}
+				taskOrder = append(taskOrder, task.Title)
+			}
+			runs := tasks[task.Title]
+			succeeded := task.Status == TaskSucceeded
+			runs.outcomes = append(runs.outcomes, succeeded)
+			runs.durations = append(runs.durations, task.Duration)
+			if task.Usage != nil {
+				runs.costs = append(runs.costs, task.Usage.CostUSD)
+			}
+			if task.Attempts > 1 {
+				runs.retries += task.Attempts - 1
+			}
+			if succeeded && needsFixes(task) {
+				runs.flaky++
+			}
+		}
+	}
+	stats.SuccessRate = rate(stats.Succeeded, stats.Runs)
+
+	for _, name := range fileOrder {
+		runs := files[name]
+		succeeded := countTrue(runs.outcomes)
+		trend := runs.durations
+		if len(trend) > trendRuns {
+			trend = trend[len(trend)-trendRuns:]
+		}
+		stats.TaskFiles = append(stats.TaskFiles, TaskFileStats{
+			TaskFile:      name,
+			Runs:          len(runs.outcomes),
+			Succeeded:     succeeded,
+			SuccessRate:   rate(succeeded, len(runs.outcomes)),
+			MeanDuration:  meanDuration(runs.durations),
+			P95Duration:   percentileDuration(runs.durations, 95),
+			MeanCost:      mean(runs.costs),
+			SuccessTrend:  successTrend(runs.outcomes, trendBuckets),
+			DurationTrend: trend,
+		})
+	}
+
+	for _, title := range taskOrder {
+		runs := tasks[title]
+		succeeded := countTrue(runs.outcomes)
+		task := TaskStats{
+			Title:          title,
+			Runs:           len(runs.outcomes),
+			Succeeded:      succeeded,
+			SuccessRate:    rate(succeeded, len(runs.outcomes)),
+			MeanDuration:   meanDuration(runs.durations),
+			P95Duration:    percentileDuration(runs.durations, 95),
+			AverageRetries: float64(runs.retries) / float64(len(runs.outcomes)),
+			MeanCost:       mean(runs.costs),
+			FlakyRuns:      runs.flaky,
+			SuccessTrend:   successTrend(runs.outcomes, trendBuckets),
+		}
+		stats.Tasks = append(stats.Tasks, task)
+		if task.FlakyRuns > 0 {
+			stats.FlakyTasks = append(stats.FlakyTasks, task)
+		}
+	}
+
+	for command, count := range failures {
+		stats.VerificationFailures = append(stats.VerificationFailures, VerificationFailure{Command: command, Failures: count})
+	}
+	sort.Slice(stats.VerificationFailures, func(i, j int) bool {
+		a, b := stats.VerificationFailures[i], stats.VerificationFailures[j]
+		if a.Failures != b.Failures {
+			return a.Failures > b.Failures
+		}
+		return a.Command < b.Command
+	})
+
+	// Least reliable first
+	sort.SliceStable(stats.TaskFiles, func(i, j int) bool { return stats.TaskFiles[i].SuccessRate < stats.TaskFiles[j].SuccessRate })
+	sort.SliceStable(stats.Tasks, func(i, j int) bool { return stats.Tasks[i].SuccessRate < stats.Tasks[j].SuccessRate })
+	sort.SliceStable(stats.FlakyTasks, func(i, j int) bool {
+		return rate(stats.FlakyTasks[i].FlakyRuns, stats.FlakyTasks[i].Succeeded) > rate(stats.FlakyTasks[j].FlakyRuns, stats.FlakyTasks[j].Succeeded)
+	})
+
+	return stats
+}
+
+// needsFixes reports whether a task needed more than one agent call or
+// failed a verification before it succeeded.
+func needsFixes(task TaskRecord) bool {
+	if task.Attempts > 1 {
+		return true
+	}
+	for _, v := range task.Verifications {
+		if !v.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+// successTrend splits chronological outcomes into at most buckets periods
+// of similar size and returns the success rate of each.
+func successTrend(outcomes []bool, buckets int) []float64 {
+	if len(outcomes) < buckets {
+		buckets = len(outcomes)
+	}
+	trend := make([]float64, 0, buckets)
+	for i := 0; i < buckets; i++ {
+		start := i * len(outcomes) / buckets
+		end := (i + 1) * len(outcomes) / buckets
+		period := outcomes[start:end]
+		trend = append(trend, rate(countTrue(period), len(period)))
+	}
+	return trend
+}
+
+// percentileDuration returns the p-th percentile using the nearest-rank method.
+func percentileDuration(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// meanDuration returns the average of durations.
+func meanDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations))
+}
+
+// mean returns the average of values, or 0 if there are none.
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+// rate returns n/total, or 0 if total is 0.
+func rate(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// countTrue returns the number of true values.
+func countTrue(values []bool) int {
+	count := 0
+	for _, v := range values {
+		if v {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,124 @@
+package history
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestComputeStats(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	setup := func(status string, attempts int, verifications ...VerificationRecord) TaskRecord {
+		return TaskRecord{Number: 1, Title: "1: Setup", Status: status, Attempts: attempts, Duration: time.Minute, Verifications: verifications}
+	}
+	failedTest := VerificationRecord{Command: "go test ./...", Passed: false}
+	passedTest := VerificationRecord{Command: "go test ./...", Passed: true}
+
+	entries := []Entry{
+		{TaskFile: "tasks.txt", ExecutedAt: base, Success: true, Duration: 1 * time.Minute,
+			Tasks: []TaskRecord{setup(TaskSucceeded, 1, passedTest)}},
+		{TaskFile: "tasks.txt", ExecutedAt: base.Add(time.Hour), Success: true, Duration: 3 * time.Minute,
+			Tasks: []TaskRecord{setup(TaskSucceeded, 2, failedTest, passedTest)}},
+		{TaskFile: "tasks.txt", ExecutedAt: base.Add(2 * time.Hour), Success: false, Duration: 2 * time.Minute,
+			Tasks: []TaskRecord{
+				setup(TaskFailed, 4, failedTest, failedTest),
+				{Number: 2, Title: "2: Deploy", Status: TaskSkipped},
+			}},
+		// Legacy entry without task records
+		{TaskFile: "other/legacy.txt", ExecutedAt: base.Add(-time.Hour), Success: false, Duration: 10 * time.Minute},
+	}
+
+	stats := ComputeStats(entries)
+
+	if stats.Runs != 4 || stats.Succeeded != 2 || stats.SuccessRate != 0.5 {
+		t.Errorf("run totals = %d/%d (%v)", stats.Succeeded, stats.Runs, stats.SuccessRate)
+	}
+
+	if len(stats.TaskFiles) != 2 || stats.TaskFiles[0].TaskFile != "legacy.txt" {
+		t.Fatalf("TaskFiles = %+v, want legacy.txt first", stats.TaskFiles)
+	}
+	tasksFile := stats.TaskFiles[1]
+	if tasksFile.Runs != 3 || tasksFile.MeanDuration != 2*time.Minute || tasksFile.P95Duration != 3*time.Minute {
+		t.Errorf("tasks.txt stats = %+v", tasksFile)
+	}
+	if want := []time.Duration{time.Minute, 3 * time.Minute, 2 * time.Minute}; !slices.Equal(tasksFile.DurationTrend, want) {
+		t.Errorf("DurationTrend = %v, want %v", tasksFile.DurationTrend, want)
+	}
+	if want := []float64{1, 1, 0}; !slices.Equal(tasksFile.SuccessTrend, want) {
+		t.Errorf("SuccessTrend = %v, want %v", tasksFile.SuccessTrend, want)
+	}
+
+	// Skipped tasks are not counted as runs
+	if len(stats.Tasks) != 1 {
+		t.Fatalf("Tasks = %+v, want only the setup task", stats.Tasks)
+	}
+	task := stats.Tasks[0]
+	if task.Runs != 3 || task.Succeeded != 2 || task.AverageRetries != 4.0/3 || task.FlakyRuns != 1 {
+		t.Errorf("task stats = %+v", task)
+	}
+
+	if len(stats.FlakyTasks) != 1 || stats.FlakyTasks[0].Title != "1: Setup" {
+		t.Errorf("FlakyTasks = %+v", stats.FlakyTasks)
+	}
+	if len(stats.VerificationFailures) != 1 || stats.VerificationFailures[0].Failures != 3 {
+		t.Errorf("VerificationFailures = %+v", stats.VerificationFailures)
+	}
+}
+
+func TestComputeStatsEmpty(t *testing.T) {
+	stats := ComputeStats(nil)
+	if stats.Runs != 0 || stats.SuccessRate != 0 || stats.Tasks == nil || stats.TaskFiles == nil {
+		t.Errorf("empty stats = %+v", stats)
+	}
+}
+
+func TestSuccessTrend(t *testing.T) {
+	outcomes := []bool{false, false, true, true, true, true}
+	if got, want := successTrend(outcomes, 3), []float64{0, 1, 1}; !slices.Equal(got, want) {
+		t.Errorf("successTrend() = %v, want %v", got, want)
+	}
+	if got := successTrend(outcomes[:2], 10); len(got) != 2 {
+		t.Errorf("successTrend() with fewer outcomes than buckets = %v", got)
+	}
+}
+
+func TestPercentileDuration(t *testing.T) {
+	var durations []time.Duration
+	for i := 1; i <= 20; i++ {
+		durations = append(durations, time.Duration(i)*time.Second)
+	}
+	if got := percentileDuration(durations, 95); got != 19*time.Second {
+		t.Errorf("p95 = %v, want 19s", got)
+	}
+	if got := percentileDuration(nil, 95); got != 0 {
+		t.Errorf("p95 of nothing = %v, want 0", got)
+	}
+}
+
+func TestComputeStatsCost(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	task := func(cost float64) TaskRecord {
+		return TaskRecord{Number: 1, Title: "1: Build", Status: TaskSucceeded, Attempts: 1, Usage: &Usage{CostUSD: cost}}
+	}
+
+	entries := []Entry{
+		{TaskFile: "tasks.txt", ExecutedAt: base, Success: true,
+			Usage: &Usage{InputTokens: 1000, OutputTokens: 100, CostUSD: 1}, Tasks: []TaskRecord{task(1)}},
+		{TaskFile: "tasks.txt", ExecutedAt: base.Add(time.Hour), Success: true,
+			Usage: &Usage{InputTokens: 2000, OutputTokens: 200, CostUSD: 3}, Tasks: []TaskRecord{task(3)}},
+		// Runs without reported usage do not lower the mean
+		{TaskFile: "tasks.txt", ExecutedAt: base.Add(2 * time.Hour), Success: true,
+			Tasks: []TaskRecord{{Number: 1, Title: "1: Build", Status: TaskSucceeded, Attempts: 1}}},
+	}
+
+	stats := ComputeStats(entries)
+	if stats.TotalCost != 4 || stats.TotalTokens != 3300 {
+		t.Errorf("totals = $%v, %d tokens, want $4, 3300 tokens", stats.TotalCost, stats.TotalTokens)
+	}
+	if stats.TaskFiles[0].MeanCost != 2 {
+		t.Errorf("task file MeanCost = %v, want 2", stats.TaskFiles[0].MeanCost)
+	}
+	if stats.Tasks[0].MeanCost != 2 {
+		t.Errorf("task MeanCost = %v, want 2", stats.Tasks[0].MeanCost)
+	}
+}
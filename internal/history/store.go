@@ -0,0 +1,266 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	journalFile = "history.jsonl"
+	lockFile    = "history.lock"
+)
+
+// compactAfter is the number of journal entries after which the journal is
+// merged into the snapshot file.
+var compactAfter = 50
+
+// The history is stored in two files under .sleepship:
+//
+//   - history.json is a snapshot of all entries, replaced atomically
+//   - history.jsonl is an append-only journal of entries recorded since the
+//     snapshot was last written, one JSON object per line
+//
+// Recording an entry only appends a line to the journal, so a crash can at
+// worst leave a truncated last line, which is skipped when loading. All
+// access is serialized with an advisory lock on history.lock.
+//
+// The first line of a journal is a header with a generation that is unique
+// to that journal. The snapshot records the generation of the journal it was
+// last merged with, so that a journal left behind by a compaction that was
+// interrupted before removing it is not applied a second time.
+
+// snapshotFile is the content of the snapshot file
+type snapshotFile struct {
+	Entries []Entry `json:"entries"`
+	// Generation of the journal whose entries the snapshot contains
+	CompactedJournal string `json:"compacted_journal,omitempty"`
+}
+
+// journalHeader is the first line of the journal file
+type journalHeader struct {
+	Generation string `json:"journal_generation"`
+}
+
+// readEntries reads the entries of the snapshot and the journal. A journal
+// the snapshot already contains is ignored.
+func readEntries(projectDir string) (snapshot, journal []Entry, err error) {
+	file, err := readSnapshot(projectDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	journal, generation, err := readJournal(projectDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if generation != "" && generation == file.CompactedJournal {
+		journal = nil
+	}
+	return file.Entries, journal, nil
+}
+
+// readSnapshot reads the snapshot file, if it exists.
+func readSnapshot(projectDir string) (*snapshotFile, error) {
+	var snapshot snapshotFile
+	data, err := os.ReadFile(getHistoryPath(projectDir))
+	if os.IsNotExist(err) {
+		return &snapshot, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse history file: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// readJournal reads the entries and the generation of the journal file, if
+// it exists. Lines that cannot be parsed, such as a line truncated by a
+// crash, are skipped. Journals written before generations were added have
+// none.
+func readJournal(projectDir string) ([]Entry, string, error) {
+	file, err := os.Open(getJournalPath(projectDir))
+	if os.IsNotExist(err) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read history journal: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var entries []Entry
+	var generation string
+	reader := bufio.NewReader(file)
+	for first := true; ; first = false {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var header journalHeader
+			var entry Entry
+			if first && json.Unmarshal(line, &header) == nil && header.Generation != "" {
+				generation = header.Generation
+			} else if json.Unmarshal(line, &entry) == nil {
+				entries = append(entries, entry)
+			}
+		}
+		if err == io.EOF {
+			return entries, generation, nil
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read history journal: %w", err)
+		}
+	}
+}
+
+// journalGeneration returns the generation of the journal file, or an empty
+// string if there is none.
+func journalGeneration(projectDir string) (string, error) {
+	_, generation, err := readJournal(projectDir)
+	return generation, err
+}
+
+// newJournalGeneration returns a new unique journal generation.
+func newJournalGeneration() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%s", time.Now().UnixNano(), hex.EncodeToString(b)), nil
+}
+
+// appendJournal appends an entry to the journal file.
+func appendJournal(projectDir string, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+
+	file, err := os.OpenFile(getJournalPath(projectDir), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history journal: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read history journal: %w", err)
+	}
+	if info.Size() == 0 {
+		// Start a new journal with its generation
+		generation, err := newJournalGeneration()
+		if err != nil {
+			return fmt.Errorf("failed to create history journal generation: %w", err)
+		}
+		header, err := json.Marshal(journalHeader{Generation: generation})
+		if err != nil {
+			return fmt.Errorf("failed to marshal history journal header: %w", err)
+		}
+		line = append(append(header, '\n'), line...)
+	} else {
+		// Terminate a line left incomplete by a crash so the new entry stays readable
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			line = append([]byte("\n"), line...)
+		}
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write history journal: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to write history journal: %w", err)
+	}
+	return nil
+}
+
+// writeSnapshot atomically replaces the snapshot with entries, which must
+// include the entries of the journal, and clears the journal. The snapshot
+// records the generation of the journal, so that the journal is ignored if
+// the process stops before it is removed.
+func writeSnapshot(projectDir string, entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	generation, err := journalGeneration(projectDir)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(snapshotFile{Entries: entries, CompactedJournal: generation}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
+
+	if err := writeFileAtomic(getHistoryPath(projectDir), data, 0600); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+
+	// The snapshot now contains every journal entry
+	if err := os.Remove(getJournalPath(projectDir)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear history journal: %w", err)
+	}
+	return nil
+}
+
+// compact merges the journal into the snapshot.
+func compact(projectDir string, snapshot, journal []Entry) error {
+	return writeSnapshot(projectDir, append(snapshot, journal...))
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it over path, so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// withLock runs fn while holding the history lock. Writers take an exclusive
+// lock and create the history directory; readers take a shared lock.
+func withLock(projectDir string, exclusive bool, fn func() error) error {
+	dir := filepath.Join(projectDir, historyDir)
+	if exclusive {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create history directory: %w", err)
+		}
+	}
+
+	lock, err := acquireLock(filepath.Join(dir, lockFile), exclusive)
+	if err != nil {
+		return fmt.Errorf("failed to lock history: %w", err)
+	}
+	defer func() { _ = lock.release() }()
+
+	return fn()
+}
+
+// getJournalPath returns the absolute path to the journal file
+func getJournalPath(projectDir string) string {
+	return filepath.Join(projectDir, historyDir, journalFile)
+}
@@ -0,0 +1,267 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// helperDirEnv tells TestHelperRecordProcess which project directory to write to
+const helperDirEnv = "SLEEPSHIP_HISTORY_HELPER_DIR"
+
+// helperEntries is the number of entries each helper process records
+const helperEntries = 25
+
+func TestRecordEntryConcurrentGoroutines(t *testing.T) {
+	tempDir := t.TempDir()
+	setCompactAfter(t, 7)
+
+	const writers, perWriter = 10, 10
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perWriter)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				errs <- RecordEntry(tempDir, Entry{TaskFile: fmt.Sprintf("tasks-%d-%d.txt", w, i), Success: true})
+			}
+		}(w)
+	}
+
+	// Readers must never see a partially written history
+	for r := 0; r < 3; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if _, err := Load(tempDir); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Concurrent access failed: %v", err)
+		}
+	}
+
+	assertUniqueEntries(t, tempDir, writers*perWriter)
+}
+
+func TestRecordEntryConcurrentProcesses(t *testing.T) {
+	tempDir := t.TempDir()
+
+	const processes = 4
+	var cmds []*exec.Cmd
+	for p := 0; p < processes; p++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperRecordProcess$")
+		cmd.Env = append(os.Environ(), helperDirEnv+"="+tempDir, "SLEEPSHIP_HISTORY_HELPER_ID="+strconv.Itoa(p))
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("Failed to start helper process: %v", err)
+		}
+		cmds = append(cmds, cmd)
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("Helper process failed: %v", err)
+		}
+	}
+
+	assertUniqueEntries(t, tempDir, processes*helperEntries)
+}
+
+// TestHelperRecordProcess is run as a subprocess by TestRecordEntryConcurrentProcesses.
+func TestHelperRecordProcess(t *testing.T) {
+	dir := os.Getenv(helperDirEnv)
+	if dir == "" {
+		t.Skip("helper process for TestRecordEntryConcurrentProcesses")
+	}
+	compactAfter = 10
+
+	id := os.Getenv("SLEEPSHIP_HISTORY_HELPER_ID")
+	for i := 0; i < helperEntries; i++ {
+		if err := RecordEntry(dir, Entry{TaskFile: fmt.Sprintf("tasks-%s-%d.txt", id, i)}); err != nil {
+			t.Fatalf("Failed to record entry: %v", err)
+		}
+	}
+}
+
+func TestRecordEntryCompaction(t *testing.T) {
+	tempDir := t.TempDir()
+	setCompactAfter(t, 3)
+
+	for i := 0; i < 4; i++ {
+		if err := RecordEntry(tempDir, Entry{TaskFile: fmt.Sprintf("tasks-%d.txt", i)}); err != nil {
+			t.Fatalf("Failed to record entry: %v", err)
+		}
+	}
+
+	snapshot, journal, err := readEntries(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to read history: %v", err)
+	}
+	if len(snapshot) != 3 || len(journal) != 1 {
+		t.Errorf("Expected 3 compacted and 1 journal entries, got %d and %d", len(snapshot), len(journal))
+	}
+
+	hist, err := Load(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	for i, entry := range hist.Entries {
+		if want := fmt.Sprintf("tasks-%d.txt", i); entry.TaskFile != want {
+			t.Errorf("Entry %d = %s, want %s", i, entry.TaskFile, want)
+		}
+	}
+}
+
+func TestRecordEntryAfterTruncatedJournal(t *testing.T) {
+	tempDir := t.TempDir()
+
+	if err := RecordEntry(tempDir, Entry{TaskFile: "before.txt"}); err != nil {
+		t.Fatalf("Failed to record entry: %v", err)
+	}
+
+	// Simulate a crash in the middle of appending an entry
+	file, err := os.OpenFile(getJournalPath(tempDir), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(`{"task_file":"crash`); err != nil {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+
+	if err := RecordEntry(tempDir, Entry{TaskFile: "after.txt"}); err != nil {
+		t.Fatalf("Failed to record entry: %v", err)
+	}
+
+	hist, err := Load(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	if len(hist.Entries) != 2 || hist.Entries[0].TaskFile != "before.txt" || hist.Entries[1].TaskFile != "after.txt" {
+		t.Errorf("Unexpected entries after truncated journal: %+v", hist.Entries)
+	}
+}
+
+func TestCompactionInterruptedBeforeClearingJournal(t *testing.T) {
+	tempDir := t.TempDir()
+
+	for i := 0; i < 2; i++ {
+		if err := RecordEntry(tempDir, Entry{ID: "20250101-120000", TaskFile: fmt.Sprintf("tasks-%d.txt", i)}); err != nil {
+			t.Fatalf("Failed to record entry: %v", err)
+		}
+	}
+
+	// Compact, but keep the journal as if the process stopped before
+	// removing it
+	journalPath := getJournalPath(tempDir)
+	journalData, err := os.ReadFile(journalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, journal, err := readEntries(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to read history: %v", err)
+	}
+	if err := compact(tempDir, snapshot, journal); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+	if err := os.WriteFile(journalPath, journalData, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	assertTaskFiles := func(want ...string) {
+		t.Helper()
+		hist, err := Load(tempDir)
+		if err != nil {
+			t.Fatalf("Failed to load history: %v", err)
+		}
+		var got []string
+		for _, entry := range hist.Entries {
+			got = append(got, entry.TaskFile)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("entries = %v, want %v", got, want)
+		}
+	}
+	assertTaskFiles("tasks-0.txt", "tasks-1.txt")
+
+	// Entries recorded afterwards go to a new journal
+	if err := RecordEntry(tempDir, Entry{TaskFile: "tasks-2.txt"}); err != nil {
+		t.Fatalf("Failed to record entry: %v", err)
+	}
+	assertTaskFiles("tasks-0.txt", "tasks-1.txt", "tasks-2.txt")
+
+	// The same holds for a snapshot written by pruning
+	journalData, err = os.ReadFile(journalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Prune(tempDir, Retention{MaxEntries: 2}, false); err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if err := os.WriteFile(journalPath, journalData, 0600); err != nil {
+		t.Fatal(err)
+	}
+	assertTaskFiles("tasks-1.txt", "tasks-2.txt")
+}
+
+func TestLegacyJournalWithoutGeneration(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, historyDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(getJournalPath(tempDir), []byte(`{"task_file":"legacy.txt"}`+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RecordEntry(tempDir, Entry{TaskFile: "new.txt"}); err != nil {
+		t.Fatalf("Failed to record entry: %v", err)
+	}
+	hist, err := Load(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	if len(hist.Entries) != 2 || hist.Entries[0].TaskFile != "legacy.txt" || hist.Entries[1].TaskFile != "new.txt" {
+		t.Errorf("Unexpected entries: %+v", hist.Entries)
+	}
+}
+
+func setCompactAfter(t *testing.T, n int) {
+	t.Helper()
+
+	original := compactAfter
+	compactAfter = n
+	t.Cleanup(func() { compactAfter = original })
+}
+
+func assertUniqueEntries(t *testing.T, dir string, want int) {
+	t.Helper()
+
+	hist, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	if len(hist.Entries) != want {
+		t.Fatalf("Expected %d entries, got %d", want, len(hist.Entries))
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range hist.Entries {
+		if seen[entry.TaskFile] {
+			t.Errorf("Duplicate entry: %s", entry.TaskFile)
+		}
+		seen[entry.TaskFile] = true
+	}
+}
@@ -0,0 +1,44 @@
+package history
+
+import (
+	"slices"
+	"sort"
+)
+
+// Usage is the token usage and cost reported by the agent CLI.
+type Usage struct {
+	InputTokens              int64    `json:"input_tokens"`
+	OutputTokens             int64    `json:"output_tokens"`
+	CacheCreationInputTokens int64    `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int64    `json:"cache_read_input_tokens,omitempty"`
+	CostUSD                  float64  `json:"cost_usd"`
+	Models                   []string `json:"models,omitempty"`
+}
+
+// Add adds other to u. Models are merged into a sorted list.
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheCreationInputTokens += other.CacheCreationInputTokens
+	u.CacheReadInputTokens += other.CacheReadInputTokens
+	u.CostUSD += other.CostUSD
+
+	for _, model := range other.Models {
+		if !slices.Contains(u.Models, model) {
+			// Clip so that a Usage copied from u keeps its own models
+			u.Models = append(slices.Clip(u.Models), model)
+			sort.Strings(u.Models)
+		}
+	}
+}
+
+// IsZero reports whether no usage was recorded.
+func (u Usage) IsZero() bool {
+	return u.InputTokens == 0 && u.OutputTokens == 0 && u.CacheCreationInputTokens == 0 &&
+		u.CacheReadInputTokens == 0 && u.CostUSD == 0 && len(u.Models) == 0
+}
+
+// TotalTokens returns the number of input, cache and output tokens.
+func (u Usage) TotalTokens() int64 {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
@@ -0,0 +1,29 @@
+package history
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestUsageAdd(t *testing.T) {
+	var total Usage
+	if !total.IsZero() {
+		t.Error("zero Usage should report IsZero")
+	}
+
+	total.Add(Usage{InputTokens: 10, OutputTokens: 1, CostUSD: 0.5, Models: []string{"sonnet"}})
+	total.Add(Usage{InputTokens: 5, CacheReadInputTokens: 100, CostUSD: 0.25, Models: []string{"opus", "sonnet", "haiku"}})
+
+	if total.InputTokens != 15 || total.OutputTokens != 1 || total.CacheReadInputTokens != 100 || total.CostUSD != 0.75 {
+		t.Errorf("Add() = %+v", total)
+	}
+	if !slices.Equal(total.Models, []string{"haiku", "opus", "sonnet"}) {
+		t.Errorf("Add() models = %v, want [haiku opus sonnet]", total.Models)
+	}
+	if total.TotalTokens() != 116 {
+		t.Errorf("TotalTokens() = %d, want 116", total.TotalTokens())
+	}
+	if total.IsZero() {
+		t.Error("Usage with tokens should not report IsZero")
+	}
+}
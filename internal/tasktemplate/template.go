@@ -0,0 +1,306 @@
+// Package tasktemplate provides the templates that "sleepship init" creates
+// task files from.
+package tasktemplate
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//go:embed builtin/*.txt
+var builtinFS embed.FS
+
+// DefaultName is the template used when none is selected.
+const DefaultName = "go-service"
+
+// BuiltinSource is the Source of the templates built into sleepship.
+const BuiltinSource = "built-in"
+
+// extensions are the file extensions of template files, in order of priority
+var extensions = []string{".txt", ".md"}
+
+// Template is a task file template. Its content may contain variables:
+//
+//	${name}           the value of name (required)
+//	${name:-default}  the value of name, or default if it is not given
+//	$$                a literal $
+type Template struct {
+	Name        string
+	Description string // Text of the first "# " heading
+	Source      string // Path of the template file, or BuiltinSource
+	Content     string
+}
+
+// Variable is a variable used in a template.
+type Variable struct {
+	Name       string
+	Default    string
+	HasDefault bool
+}
+
+// Load returns the template with the given name. Templates in dirs, given
+// highest priority first, take priority over the built-in templates.
+func Load(name string, dirs []string) (*Template, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid template name %q", name)
+	}
+
+	for _, dir := range dirs {
+		for _, ext := range extensions {
+			path := filepath.Join(dir, name+ext)
+			data, err := os.ReadFile(path)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read template %s: %w", path, err)
+			}
+			return newTemplate(name, path, string(data)), nil
+		}
+	}
+
+	data, err := builtinFS.ReadFile("builtin/" + name + ".txt")
+	if err != nil {
+		return nil, fmt.Errorf("template %q not found (see sleepship template list)", name)
+	}
+	return newTemplate(name, BuiltinSource, string(data)), nil
+}
+
+// List returns the templates available from dirs and the built-in templates,
+// sorted by name. A template in a directory hides templates with the same
+// name in directories with a lower priority and built-in templates.
+func List(dirs []string) ([]Template, error) {
+	templates := make(map[string]Template)
+	add := func(name, source string, read func() ([]byte, error)) error {
+		if _, ok := templates[name]; ok {
+			return nil
+		}
+		data, err := read()
+		if err != nil {
+			return fmt.Errorf("failed to read template %s: %w", source, err)
+		}
+		templates[name] = *newTemplate(name, source, string(data))
+		return nil
+	}
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template directory %s: %w", dir, err)
+		}
+		// ReadDir sorts by file name, so a .txt file comes before the .md
+		// file with the same name
+		for _, entry := range entries {
+			name, ok := templateName(entry.Name())
+			if !ok || entry.IsDir() {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if err := add(name, path, func() ([]byte, error) { return os.ReadFile(path) }); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	entries, err := fs.ReadDir(builtinFS, "builtin")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name, _ := templateName(entry.Name())
+		path := "builtin/" + entry.Name()
+		if err := add(name, BuiltinSource, func() ([]byte, error) { return builtinFS.ReadFile(path) }); err != nil {
+			return nil, err
+		}
+	}
+
+	list := make([]Template, 0, len(templates))
+	for _, t := range templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// templateName returns the name of the template in a file, and whether the
+// file is a template.
+func templateName(file string) (string, bool) {
+	for _, ext := range extensions {
+		if name, ok := strings.CutSuffix(file, ext); ok && name != "" && !strings.HasPrefix(name, ".") {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func newTemplate(name, source, content string) *Template {
+	t := &Template{Name: name, Source: source, Content: content}
+	for _, line := range strings.Split(content, "\n") {
+		if heading, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+			t.Description = strings.TrimSpace(heading)
+			break
+		}
+	}
+	return t
+}
+
+// Variables returns the variables used in the template, in order of first
+// use. A variable used with a default in one place and without one in
+// another has a default. A ${name} in a verification command that is used
+// nowhere else without one is left to the shell and is not a variable.
+func (t *Template) Variables() []Variable {
+	var vars []Variable
+	index := make(map[string]int)
+	t.expand(func(name, def string, hasDefault, shell bool) (string, bool) {
+		if shell {
+			return "", false
+		}
+		i, ok := index[name]
+		if !ok {
+			index[name] = len(vars)
+			vars = append(vars, Variable{Name: name, Default: def, HasDefault: hasDefault})
+		} else if hasDefault && !vars[i].HasDefault {
+			vars[i].Default, vars[i].HasDefault = def, true
+		}
+		return "", true
+	})
+	return vars
+}
+
+// Render returns the content of the template with its variables replaced by
+// their values. It is an error to give a variable the template does not use
+// or to leave out a variable that has no default. A ${name} left to the
+// shell is replaced only if a value is given for it.
+func (t *Template) Render(values map[string]string) (string, error) {
+	vars := t.Variables()
+	defaults := make(map[string]Variable, len(vars))
+	names := make([]string, len(vars))
+	for i, v := range vars {
+		defaults[v.Name] = v
+		names[i] = v.Name
+	}
+	shellNames := make(map[string]bool)
+	t.expand(func(name, _ string, _, shell bool) (string, bool) {
+		shellNames[name] = shellNames[name] || shell
+		return "", false
+	})
+
+	for name := range values {
+		if _, ok := defaults[name]; !ok && !shellNames[name] {
+			if len(names) == 0 {
+				return "", fmt.Errorf("template %s has no variables, but %s was given", t.Name, name)
+			}
+			return "", fmt.Errorf("template %s has no variable %s (variables: %s)", t.Name, name, strings.Join(names, ", "))
+		}
+	}
+	var missing []string
+	for _, v := range vars {
+		if _, ok := values[v.Name]; !ok && !v.HasDefault {
+			missing = append(missing, v.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("template %s needs a value for %s (use --var name=value)", t.Name, strings.Join(missing, ", "))
+	}
+
+	return t.expand(func(name, _ string, _, _ bool) (string, bool) {
+		if value, ok := values[name]; ok {
+			return value, true
+		}
+		v, ok := defaults[name]
+		return v.Default, ok
+	}), nil
+}
+
+// expand replaces the variables in the template content with the result of
+// value, keeping a variable as is if value returns false. shell is true for
+// a ${name} without a default in a verification command (a "- `command`"
+// line) whose name is not used as a variable anywhere else.
+func (t *Template) expand(value func(name, def string, hasDefault, shell bool) (string, bool)) string {
+	lines := strings.Split(t.Content, "\n")
+	command := make([]bool, len(lines))
+	templateNames := make(map[string]bool)
+	for i, line := range lines {
+		command[i] = isCommandLine(line)
+		expand(line, func(name, _ string, hasDefault bool) (string, bool) {
+			if hasDefault || !command[i] {
+				templateNames[name] = true
+			}
+			return "", false
+		})
+	}
+
+	for i, line := range lines {
+		lines[i] = expand(line, func(name, def string, hasDefault bool) (string, bool) {
+			return value(name, def, hasDefault, command[i] && !templateNames[name])
+		})
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isCommandLine reports whether line is a verification command of a task.
+func isCommandLine(line string) bool {
+	return strings.HasPrefix(line, "- `") && strings.HasSuffix(line, "`")
+}
+
+// expand replaces the variables in s with the result of value, or keeps a
+// variable as is if value returns false. Text that looks like a variable but
+// is not one, such as ${1} or an unterminated ${, is kept as is.
+func expand(s string, value func(name, def string, hasDefault bool) (string, bool)) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 || i == len(s)-1 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		s = s[i:]
+
+		switch s[1] {
+		case '$':
+			b.WriteByte('$')
+			s = s[2:]
+			continue
+		case '{':
+			end := strings.IndexByte(s, '}')
+			if end > 0 {
+				name, def, hasDefault := strings.Cut(s[2:end], ":-")
+				if isVariableName(name) {
+					if v, ok := value(name, def, hasDefault); ok {
+						b.WriteString(v)
+					} else {
+						b.WriteString(s[:end+1])
+					}
+					s = s[end+1:]
+					continue
+				}
+			}
+		}
+		b.WriteByte('$')
+		s = s[1:]
+	}
+}
+
+// isVariableName reports whether name can be the name of a variable:
+// letters, digits and underscores, not starting with a digit.
+func isVariableName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
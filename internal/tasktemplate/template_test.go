@@ -0,0 +1,128 @@
+package tasktemplate
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	content := "# ${name:-demo}\n- `go test ${pkg:-./...}`\n- `echo $$HOME ${1} $PATH ${ref} ${HOME}`\nref: ${ref}\nend $"
+
+	tests := []struct {
+		name    string
+		values  map[string]string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "defaults",
+			values: map[string]string{"ref": "main"},
+			want:   "# demo\n- `go test ./...`\n- `echo $HOME ${1} $PATH main ${HOME}`\nref: main\nend $",
+		},
+		{
+			name:   "values",
+			values: map[string]string{"name": "api", "pkg": "./internal/...", "ref": ""},
+			want:   "# api\n- `go test ./internal/...`\n- `echo $HOME ${1} $PATH  ${HOME}`\nref: \nend $",
+		},
+		{
+			name:   "shell variable given",
+			values: map[string]string{"ref": "main", "HOME": "/home/me"},
+			want:   "# demo\n- `go test ./...`\n- `echo $HOME ${1} $PATH main /home/me`\nref: main\nend $",
+		},
+		{name: "missing required", values: map[string]string{}, wantErr: "needs a value for ref"},
+		{name: "unknown variable", values: map[string]string{"ref": "main", "nmae": "x"}, wantErr: "has no variable nmae (variables: name, pkg, ref)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := newTemplate("test", BuiltinSource, content)
+			got, err := tmpl.Render(tt.values)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Render() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariables(t *testing.T) {
+	tmpl := newTemplate("test", BuiltinSource, "${b} ${a:-1} ${b:-2} ${a:-3} $${c}")
+	want := []Variable{{Name: "b", Default: "2", HasDefault: true}, {Name: "a", Default: "1", HasDefault: true}}
+	if got := tmpl.Variables(); !slices.Equal(got, want) {
+		t.Errorf("Variables() = %+v, want %+v", got, want)
+	}
+
+	// Only ${a} is a variable in the commands: ${HOME} is used nowhere else,
+	// and ${b} is used with a default
+	tmpl = newTemplate("test", BuiltinSource, "${a}\n- `ls ${a} ${HOME} ${b:-.}`\n- `cd ${b}` no command")
+	want = []Variable{{Name: "a"}, {Name: "b", Default: ".", HasDefault: true}}
+	if got := tmpl.Variables(); !slices.Equal(got, want) {
+		t.Errorf("Variables() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadAndList(t *testing.T) {
+	project := t.TempDir()
+	home := t.TempDir()
+	files := map[string]string{
+		filepath.Join(project, "bug-fix.md"):      "# Our bug fix process\n\n## タスク1: ${bug}\n",
+		filepath.Join(project, "notes.txt~"):      "not a template",
+		filepath.Join(home, "bug-fix.txt"):        "# Hidden by the project template\n",
+		filepath.Join(home, "release.txt"):        "# Release ${version}\n",
+		filepath.Join(home, ".hidden.txt"):        "# Not listed\n",
+		filepath.Join(home, "nested", "deep.txt"): "# Not listed\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dirs := []string{project, home, filepath.Join(home, "missing")}
+
+	tmpl, err := Load("bug-fix", dirs)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if tmpl.Source != filepath.Join(project, "bug-fix.md") || tmpl.Description != "Our bug fix process" {
+		t.Errorf("Load(bug-fix) = %+v", tmpl)
+	}
+	if tmpl, err := Load(DefaultName, dirs); err != nil || tmpl.Source != BuiltinSource {
+		t.Errorf("Load(%s) = %+v, %v", DefaultName, tmpl, err)
+	}
+	for _, name := range []string{"missing", "../bug-fix", ".hidden"} {
+		if _, err := Load(name, dirs); err == nil {
+			t.Errorf("Load(%q) succeeded", name)
+		}
+	}
+
+	templates, err := List(dirs)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	sources := make(map[string]string)
+	var names []string
+	for _, tmpl := range templates {
+		names = append(names, tmpl.Name)
+		sources[tmpl.Name] = tmpl.Source
+	}
+	wantNames := []string{"bug-fix", "docs", "go-library", "go-service", "refactor", "release", "tdd"}
+	if !slices.Equal(names, wantNames) {
+		t.Errorf("List() names = %v, want %v", names, wantNames)
+	}
+	if sources["bug-fix"] != filepath.Join(project, "bug-fix.md") || sources["release"] != filepath.Join(home, "release.txt") || sources["tdd"] != BuiltinSource {
+		t.Errorf("List() sources = %v", sources)
+	}
+}